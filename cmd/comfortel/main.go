@@ -1,35 +1,93 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"io"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	application "github.com/srgklmv/comfortel/internal/app"
+	"github.com/srgklmv/comfortel/internal/config"
 	"github.com/srgklmv/comfortel/pkg/logger"
 )
 
 func main() {
 	logger.Init()
+
+	cfg, printOnly, err := config.Load(os.Args[1:], os.Stdout)
+	if errors.Is(err, config.ErrHelp) {
+		return
+	}
+	if err != nil {
+		logger.Error("Config error. Exiting Comfortel...", slog.String("error", err.Error()))
+		os.Exit(2)
+	}
+	if printOnly {
+		return
+	}
+	if err = application.ConfigureLogger(cfg.Log); err != nil {
+		logger.Error("Config error. Exiting Comfortel...", slog.String("error", err.Error()))
+		os.Exit(2)
+	}
+
 	logger.Info("Starting up Comfortel...")
 
 	shutdown := make(chan os.Signal, 1)
-	signal.Notify(shutdown, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	signal.Notify(shutdown, syscall.SIGTERM, syscall.SIGINT)
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
 
-	app := application.New()
+	app := application.New(cfg)
 
-	go func() {
-		if err := app.Run(); err != nil {
-			logger.Error("Startup error. Exiting Comfortel...", slog.String("error", err.Error()))
-			shutdown <- syscall.SIGTERM
-		}
-	}()
-	logger.Info("Comfortel is running.")
+	exitCode := 0
+	if err = app.Start(); err != nil {
+		logger.Error("Startup error. Exiting Comfortel...", slog.String("error", err.Error()))
+		exitCode = 1
+	} else {
+		logger.Info("Comfortel is running.")
+	}
 
-	<-shutdown
+wait:
+	for exitCode == 0 {
+		select {
+		case <-reload:
+			// Config is built from same file, env and flags, so file edits take effect.
+			cfg, _, err := config.Load(os.Args[1:], io.Discard)
+			if err != nil {
+				logger.Error("Config reload error. Keeping current config.", slog.String("error", err.Error()))
+				continue
+			}
+			if err = app.Reload(cfg); err != nil {
+				logger.Error("Config reload is incomplete.", slog.String("error", err.Error()))
+			}
+		case err := <-app.Err():
+			logger.Error("Serving error. Exiting Comfortel...", slog.String("error", err.Error()))
+			exitCode = 1
+		case <-shutdown:
+			break wait
+		}
+	}
 	logger.Info("Shutting down Comfortel...")
 
-	app.Shutdown()
+	// Second signal stops waiting for draining.
+	go func() {
+		<-shutdown
+		logger.Error("Forced shutdown.")
+		os.Exit(1)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.HTTP.ShutdownTimeout))
+	if err = app.Shutdown(ctx); err != nil {
+		logger.Error("Shutdown error.", slog.String("error", err.Error()))
+		exitCode = 1
+	}
+	cancel()
 	logger.Info("Comfortel shut down.")
+	_ = logger.Close()
+
+	os.Exit(exitCode)
 }
@@ -0,0 +1,150 @@
+// Package admin serves runtime diagnostics of app: pprof, log level control, build info and effective config.
+// It is meant for operators, so it is served on its own listener, not with API.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+	"strings"
+
+	"github.com/srgklmv/comfortel/internal/config"
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	"github.com/srgklmv/comfortel/internal/metrics"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+type handler struct {
+	config func() config.Config
+	tokens []string
+}
+
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+// New returns handler of admin endpoints. Config returns config app runs with, it is served with secrets
+// redacted. Requests must carry one of tokens as bearer token if any are set.
+func New(config func() config.Config, tokens []string) http.Handler {
+	h := handler{config: config, tokens: tokens}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("POST /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("GET /admin/loglevel", h.getLogLevel)
+	mux.HandleFunc("PUT /admin/loglevel", h.setLogLevel)
+	mux.HandleFunc("GET /admin/buildinfo", h.buildInfo)
+	mux.HandleFunc("GET /admin/config", h.effectiveConfig)
+
+	if len(tokens) == 0 {
+		return mux
+	}
+	return h.authenticate(mux)
+}
+
+func (h handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok {
+			for _, t := range h.tokens {
+				if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+
+		metrics.AuthFailed("admin")
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		writeJSON(w, http.StatusUnauthorized, apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.UnauthorizedErrorText,
+			Message: "Authorization failure.",
+		})
+	})
+}
+
+func (h handler) getLogLevel(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, LogLevel{Level: strings.ToLower(logger.Level().String())})
+}
+
+// setLogLevel changes level until config is reloaded, which sets log.level again.
+func (h handler) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var body LogLevel
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.BadRequestErrorText,
+			Message: "Request body invalid.",
+		})
+		return
+	}
+
+	if err := logger.SetLevel(body.Level); err != nil {
+		writeJSON(w, http.StatusBadRequest, apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.BadRequestErrorText,
+			Message: "Level must be one of debug, info, warn, error.",
+		})
+		return
+	}
+	logger.Warn("log level changed", slog.String("level", body.Level), slog.String("remote_addr", r.RemoteAddr))
+
+	h.getLogLevel(w, r)
+}
+
+func (h handler) buildInfo(w http.ResponseWriter, _ *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.InternalErrorText,
+			Message: "Build info is not available.",
+		})
+		return
+	}
+
+	response := BuildInfo{
+		Version:   info.Main.Version,
+		GoVersion: info.GoVersion,
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			response.Commit = s.Value
+		case "vcs.time":
+			response.Time = s.Value
+		case "vcs.modified":
+			response.Modified = s.Value == "true"
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h handler) effectiveConfig(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.config().Redacted())
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("admin response write error", slog.String("error", err.Error()))
+	}
+}
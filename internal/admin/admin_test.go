@@ -0,0 +1,119 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/srgklmv/comfortel/internal/config"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+func serve(t *testing.T, h http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	return w
+}
+
+func testConfig() config.Config {
+	cfg := config.Default()
+	cfg.Database.Password = "db-secret"
+	cfg.Admin.Tokens = []string{"admin-secret"}
+
+	return cfg
+}
+
+func TestAuthenticate(t *testing.T) {
+	logger.SetDefault(slog.New(slog.DiscardHandler))
+	h := New(testConfig, []string{"admin-secret"})
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{name: "missing token", want: http.StatusUnauthorized},
+		{name: "invalid token", token: "other", want: http.StatusUnauthorized},
+		{name: "valid token", token: "admin-secret", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, path := range []string{"/admin/loglevel", "/admin/config", "/debug/pprof/"} {
+				w := serve(t, h, http.MethodGet, path, tt.token, "")
+				if w.Code != tt.want {
+					t.Errorf("%s: status %d, want %d", path, w.Code, tt.want)
+				}
+				if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+					t.Errorf("%s: no WWW-Authenticate header", path)
+				}
+			}
+		})
+	}
+
+	// Without tokens, which config allows on loopback address only, requests are not authenticated.
+	if w := serve(t, New(testConfig, nil), http.MethodGet, "/admin/loglevel", "", ""); w.Code != http.StatusOK {
+		t.Errorf("status %d without tokens, want 200", w.Code)
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	logger.SetDefault(slog.New(slog.DiscardHandler))
+	t.Cleanup(func() { _ = logger.SetLevel("info") })
+	h := New(testConfig, []string{"admin-secret"})
+
+	if w := serve(t, h, http.MethodPut, "/admin/loglevel", "", `{"level":"debug"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("status %d without token, want 401", w.Code)
+	}
+	if logger.Level() == slog.LevelDebug {
+		t.Fatal("level changed by unauthenticated request")
+	}
+
+	w := serve(t, h, http.MethodPut, "/admin/loglevel", "admin-secret", `{"level":"debug"}`)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"level":"debug"}` {
+		t.Errorf("status %d, body %s", w.Code, w.Body)
+	}
+	if logger.Level() != slog.LevelDebug {
+		t.Errorf("level %s, want DEBUG", logger.Level())
+	}
+
+	for _, body := range []string{`{"level":"loud"}`, `level=debug`} {
+		if w = serve(t, h, http.MethodPut, "/admin/loglevel", "admin-secret", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", body, w.Code)
+		}
+	}
+	if logger.Level() != slog.LevelDebug {
+		t.Errorf("level %s after invalid requests, want DEBUG", logger.Level())
+	}
+}
+
+func TestEffectiveConfig(t *testing.T) {
+	logger.SetDefault(slog.New(slog.DiscardHandler))
+	h := New(testConfig, []string{"admin-secret"})
+
+	w := serve(t, h, http.MethodGet, "/admin/config", "admin-secret", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "db-secret") || strings.Contains(w.Body.String(), "admin-secret") {
+		t.Errorf("config has secrets: %s", w.Body)
+	}
+
+	var cfg config.Config
+	if err := json.Unmarshal(w.Body.Bytes(), &cfg); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	if cfg.Database.Password != "REDACTED" || cfg.HTTP.Address != config.Default().HTTP.Address {
+		t.Errorf("database.password %q, http.address %q", cfg.Database.Password, cfg.HTTP.Address)
+	}
+}
@@ -1,15 +1,18 @@
 package api
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/srgklmv/comfortel/internal/middleware"
+	"github.com/srgklmv/comfortel/pkg/openapi"
 )
 
 type controller interface {
 	userController
+	webhookController
 }
 
 type userController interface {
@@ -18,21 +21,124 @@ type userController interface {
 	GetUsers(*gin.Context)
 	UpdateUser(*gin.Context)
 	DeleteUser(*gin.Context)
+	BatchUsers(*gin.Context)
+	PrepareBatchUsers(*gin.Context)
 }
 
-func SetRoutes(engine *gin.Engine, conn *sql.DB, controller controller) {
-	engine.Use(cors.Default())
+type webhookController interface {
+	CreateSubscription(*gin.Context)
+	GetSubscriptions(*gin.Context)
+	GetSubscription(*gin.Context)
+	DeleteSubscription(*gin.Context)
+	GetDeliveries(*gin.Context)
+	GetDelivery(*gin.Context)
+	RedeliverDelivery(*gin.Context)
+}
+
+type transactor interface {
+	WithinTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error
+}
+
+type graphqlHandler interface {
+	Handle(*gin.Context)
+}
+
+type healthHandler interface {
+	Live(*gin.Context)
+	Ready(*gin.Context)
+}
+
+type eventsHandler interface {
+	Handle(*gin.Context)
+}
+
+type scimHandler interface {
+	Authenticate(*gin.Context)
+	ServiceProviderConfig(*gin.Context)
+	ResourceTypes(*gin.Context)
+	Schemas(*gin.Context)
+	GetUsers(*gin.Context)
+	GetUser(*gin.Context)
+	CreateUser(*gin.Context)
+	ReplaceUser(*gin.Context)
+	PatchUser(*gin.Context)
+	DeleteUser(*gin.Context)
+}
+
+// SetRoutes registers routes of API and its OpenAPI document with Swagger UI. Idempotency, replicaReads
+// and webhookAuth are middlewares, see middleware.Idempotency, middleware.ReplicaReads and middleware.BearerAuth.
+func SetRoutes(engine *gin.Engine, transactor transactor, controller controller, graphql graphqlHandler, scim scimHandler, events eventsHandler, health healthHandler, idempotency, replicaReads, webhookAuth gin.HandlerFunc) error {
+	setAPIRoutes(engine, transactor, controller, graphql, scim, events, health, idempotency, replicaReads, webhookAuth)
+
+	spec := newSpec()
+	if err := spec.Verify(engine.Routes()); err != nil {
+		return fmt.Errorf("spec.Verify: %w", err)
+	}
+	engine.GET(specPath, spec.Handler())
+	engine.GET(docsPath, openapi.UIHandler("Comfortel API", specPath, docsAssetsPath))
+	engine.GET(docsAssetsPath+"/*file", openapi.UIAssetsHandler())
+
+	return nil
+}
+
+// setAPIRoutes registers routes described by OpenAPI document, see newSpec.
+func setAPIRoutes(engine *gin.Engine, transactor transactor, controller controller, graphql graphqlHandler, scim scimHandler, events eventsHandler, health healthHandler, idempotency, replicaReads, webhookAuth gin.HandlerFunc) {
+	// Handlers pass gin context to usecases, so it has to fall back to request context,
+	// which carries transaction and cancellation.
+	engine.ContextWithFallback = true
 
 	engine.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, "pong")
 	})
+	engine.GET("/healthz", health.Live)
+	engine.GET("/readyz", health.Ready)
+
+	// Stream is long-lived, so it reads without transaction instead of holding one for whole connection.
+	engine.GET("/api/user/events", events.Handle)
 
-	api := engine.Group("api", middleware.Transaction(conn))
+	tx := middleware.Transaction(transactor, engine)
+	// Updates read user and write it back, so concurrent updates of same user must fail and be retried
+	// instead of silently overwriting each other.
+	updateTx := middleware.Transaction(transactor, engine, middleware.Isolation(sql.LevelRepeatableRead))
 
-	user := api.Group("/user")
+	api := engine.Group("api")
+
+	// User reads may go to replicas. Writes of all APIs changing users are routed through replicaReads too,
+	// so caller reads its writes from primary.
+	user := api.Group("/user", replicaReads, idempotency, tx)
 	user.POST("", controller.CreateUser)
 	user.GET("/:id", controller.GetUser)
 	user.GET("", controller.GetUsers)
-	user.PATCH("/:id", controller.UpdateUser)
 	user.DELETE("/:id", controller.DeleteUser)
+
+	api.Group("/user", replicaReads, idempotency, updateTx).PATCH("/:id", controller.UpdateUser)
+	// Passwords of batch are hashed before transaction is opened.
+	api.Group("/user", replicaReads, idempotency, controller.PrepareBatchUsers, tx).POST("/batch", controller.BatchUsers)
+
+	// Subscriptions expose delivery payloads and make app send requests, so they are for operators only.
+	webhook := api.Group("/webhook", webhookAuth, idempotency, tx)
+	webhook.POST("", controller.CreateSubscription)
+	webhook.GET("", controller.GetSubscriptions)
+	webhook.GET("/:id", controller.GetSubscription)
+	webhook.DELETE("/:id", controller.DeleteSubscription)
+	webhook.GET("/:id/delivery", controller.GetDeliveries)
+	webhook.GET("/:id/delivery/:deliveryID", controller.GetDelivery)
+	webhook.POST("/:id/delivery/:deliveryID/redeliver", controller.RedeliverDelivery)
+
+	engine.POST("/graphql", replicaReads, tx, graphql.Handle)
+
+	scimGroup := engine.Group("/scim/v2", scim.Authenticate)
+	scimGroup.GET("/ServiceProviderConfig", scim.ServiceProviderConfig)
+	scimGroup.GET("/ResourceTypes", scim.ResourceTypes)
+	scimGroup.GET("/Schemas", scim.Schemas)
+
+	scimUsers := scimGroup.Group("/Users", replicaReads, tx)
+	scimUsers.GET("", scim.GetUsers)
+	scimUsers.POST("", scim.CreateUser)
+	scimUsers.GET("/:id", scim.GetUser)
+	scimUsers.DELETE("/:id", scim.DeleteUser)
+
+	scimUpdates := scimGroup.Group("/Users", replicaReads, updateTx)
+	scimUpdates.PUT("/:id", scim.ReplaceUser)
+	scimUpdates.PATCH("/:id", scim.PatchUser)
 }
@@ -0,0 +1,589 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	"github.com/srgklmv/comfortel/internal/domain/idempotency"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	webhookDomain "github.com/srgklmv/comfortel/internal/domain/webhook"
+	"github.com/srgklmv/comfortel/internal/health"
+	"github.com/srgklmv/comfortel/internal/scim"
+	"github.com/srgklmv/comfortel/pkg/openapi"
+)
+
+const (
+	specPath       = "/openapi.json"
+	docsPath       = "/docs"
+	docsAssetsPath = "/docs/assets"
+)
+
+var userIDParam = openapi.Parameter{
+	Name:        "id",
+	In:          "path",
+	Required:    true,
+	Description: "User id.",
+	Schema:      &openapi.Schema{Type: "string", Format: "uuid"},
+}
+
+// idempotencyKeyParam and idempotencyMismatch are added to POST and PATCH operations of /api.
+var (
+	maxIdempotencyKeyLength = idempotency.MaxKeyLength
+	idempotencyKeyParam     = openapi.Parameter{
+		Name:        idempotency.Header,
+		In:          "header",
+		Description: "Makes request safe to retry. Retry with same key and request gets first response replayed, or 409 while first one is served.",
+		Schema:      &openapi.Schema{Type: "string", MaxLength: &maxIdempotencyKeyLength},
+	}
+	idempotencyMismatch = openapi.ResponseSpec{
+		Status:      http.StatusUnprocessableEntity,
+		Description: "Idempotency-Key is already used with different request.",
+		Body:        apperror.AppError{},
+	}
+)
+
+var (
+	badRequestExample = apperror.AppError{
+		Code:    apperror.AnyIntYouWantErrorCode,
+		Error:   apperror.BadRequestErrorText,
+		Message: "invalid login\ninvalid password",
+	}
+	notFoundExample = apperror.AppError{
+		Code:    apperror.AnyIntYouWantErrorCode,
+		Error:   apperror.BadRequestErrorText,
+		Message: "User not found.",
+	}
+	internalErrorExample = apperror.AppError{
+		Code:  apperror.AnyIntYouWantErrorCode,
+		Error: apperror.InternalErrorText,
+	}
+	userExample = userDomain.GetUserDTO{
+		ID:           "4654a401-8303-47b2-a757-88210009424c",
+		Login:        "ivan01",
+		FirstName:    "Ivan",
+		LastName:     "Ivanov",
+		Email:        "ivan01@example.com",
+		Sex:          userDomain.SexMale,
+		Age:          30,
+		IsActive:     true,
+		RegisterDate: "2025-01-01",
+	}
+)
+
+var readyExample = health.Report{
+	Status: health.StatusReady,
+	Components: map[string]health.ComponentReport{
+		"database":   {Status: health.StatusUp, Duration: "412µs"},
+		"migrations": {Status: health.StatusUp, Duration: "530µs"},
+	},
+}
+
+// graphqlRequest and graphqlResponse describe GraphQL over HTTP envelope for documentation only.
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+type graphqlResponse struct {
+	Data   map[string]any   `json:"data,omitempty"`
+	Errors []map[string]any `json:"errors,omitempty"`
+}
+
+// newSpec describes every route set in SetRoutes. Routes and spec are compared on startup,
+// so any new route must be described here.
+func newSpec() *openapi.Spec {
+	spec := openapi.New("Comfortel", "1.0.0", "User management API.")
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    "/ping",
+		ID:      "ping",
+		Summary: "Check that service is up",
+		Tags:    []string{"service"},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Body: "", Example: "pong"},
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    "/healthz",
+		ID:      "live",
+		Summary: "Liveness probe, responds while process serves requests",
+		Tags:    []string{"service"},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Body: health.Report{}, Example: health.Report{Status: health.StatusUp}},
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    "/readyz",
+		ID:      "ready",
+		Summary: "Readiness probe, checks database, migrations and publisher. Result is cached for a second",
+		Tags:    []string{"service"},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Body: health.Report{}, Example: readyExample},
+			{Status: http.StatusServiceUnavailable, Description: "Required component is down or app is shutting down.", Body: health.Report{}},
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodPost,
+		Path:    "/api/user",
+		ID:      "createUser",
+		Summary: "Create user",
+		Tags:    []string{"user"},
+		Params:  []openapi.Parameter{idempotencyKeyParam},
+		Request: userDomain.CreateUserRequestDTO{},
+		RequestExample: userDomain.CreateUserRequestDTO{
+			Login:     "ivan01",
+			FirstName: "Ivan",
+			LastName:  "Ivanov",
+			Email:     "ivan01@example.com",
+			Sex:       userDomain.SexMale,
+			Age:       30,
+			Password:  "s3cret!pass",
+		},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Description: "User created.", Body: userDomain.CreateUserResponseDTO{}, Example: userDomain.CreateUserResponseDTO{Created: userExample.ID}},
+			{Status: http.StatusBadRequest, Description: "Request body is invalid.", Body: apperror.AppError{}, Example: badRequestExample},
+			{Status: http.StatusConflict, Description: "Login or email is already taken.", Body: apperror.AppError{}, Example: apperror.AppError{Code: apperror.AnyIntYouWantErrorCode, Error: apperror.LoginTakenErrorText}},
+			idempotencyMismatch,
+			{Status: http.StatusInternalServerError, Body: apperror.AppError{}, Example: internalErrorExample},
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    "/api/user/:id",
+		ID:      "getUser",
+		Summary: "Get user by id",
+		Tags:    []string{"user"},
+		Params:  []openapi.Parameter{userIDParam},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Body: userDomain.GetUserDTO{}, Example: userExample},
+			{Status: http.StatusBadRequest, Description: "Invalid user id.", Body: apperror.AppError{}},
+			{Status: http.StatusNotFound, Description: "User not found.", Body: apperror.AppError{}, Example: notFoundExample},
+			{Status: http.StatusInternalServerError, Body: apperror.AppError{}, Example: internalErrorExample},
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    "/api/user",
+		ID:      "getUsers",
+		Summary: "List users",
+		Tags:    []string{"user"},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Body: []userDomain.GetUserDTO{}, Example: []userDomain.GetUserDTO{userExample}},
+			{Status: http.StatusInternalServerError, Body: apperror.AppError{}, Example: internalErrorExample},
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method: http.MethodPost,
+		Path:   "/api/user/batch",
+		ID:     "batchUsers",
+		Summary: "Run ordered list of create, update and delete operations. Atomic mode stops on first failure " +
+			"and saves nothing, best_effort mode saves every successful operation",
+		Tags:    []string{"user"},
+		Params:  []openapi.Parameter{idempotencyKeyParam},
+		Request: userDomain.BatchRequestDTO{},
+		RequestExample: map[string]any{
+			"mode": userDomain.BatchModeAtomic,
+			"operations": []map[string]any{
+				{"op": userDomain.BatchOpCreate, "data": map[string]any{"login": "petr01", "password": "s3cret!pass", "sex": userDomain.SexMale, "age": 25}},
+				{"op": userDomain.BatchOpUpdate, "id": userExample.ID, "data": map[string]any{"isActive": false}},
+				{"op": userDomain.BatchOpDelete, "id": userExample.ID},
+			},
+		},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Description: "All operations are executed, results are in order of operations.", Body: userDomain.BatchResponseDTO{}},
+			{Status: http.StatusBadRequest, Description: "Request body is invalid, or operation of atomic batch failed with this status.", Body: userDomain.BatchResponseDTO{}},
+			{Status: http.StatusNotFound, Description: "Operation of atomic batch failed with this status.", Body: userDomain.BatchResponseDTO{}},
+			{Status: http.StatusConflict, Description: "Operation of atomic batch failed with this status.", Body: userDomain.BatchResponseDTO{}},
+			idempotencyMismatch,
+			{Status: http.StatusInternalServerError, Body: apperror.AppError{}, Example: internalErrorExample},
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    "/api/user/events",
+		ID:      "streamUserEvents",
+		Summary: "Stream user events as Server-Sent Events",
+		Tags:    []string{"user"},
+		Params: []openapi.Parameter{
+			{
+				Name:        "Last-Event-ID",
+				In:          "header",
+				Description: "Id of last received event, stream resumes after it. Only new events are sent if it is absent.",
+				Schema:      &openapi.Schema{Type: "integer", Format: "int64"},
+			},
+			{
+				Name:        "lastEventId",
+				In:          "query",
+				Description: "Same as Last-Event-ID header, for clients unable to set headers.",
+				Schema:      &openapi.Schema{Type: "integer", Format: "int64"},
+			},
+			{
+				Name:        "user_id",
+				In:          "query",
+				Description: "Send only events of this user.",
+				Schema:      &openapi.Schema{Type: "string", Format: "uuid"},
+			},
+		},
+		Responses: []openapi.ResponseSpec{
+			{
+				Status:      http.StatusOK,
+				Description: "Event stream. Event id is sequence number, event name is event type and data is event JSON.",
+				ContentType: "text/event-stream",
+				Body:        "",
+				Example:     "id: 42\nevent: user.updated\ndata: {\"id\":\"...\",\"type\":\"user.updated\",\"aggregateId\":\"...\",\"createdAt\":\"...\",\"data\":{}}\n\n",
+			},
+			{Status: http.StatusBadRequest, Description: "Invalid Last-Event-ID or user_id.", Body: apperror.AppError{}, Example: badRequestExample},
+			{Status: http.StatusInternalServerError, Body: apperror.AppError{}, Example: internalErrorExample},
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:         http.MethodPatch,
+		Path:           "/api/user/:id",
+		ID:             "updateUser",
+		Summary:        "Update user",
+		Tags:           []string{"user"},
+		Params:         []openapi.Parameter{userIDParam, idempotencyKeyParam},
+		Request:        userDomain.UpdateUserRequestDTO{},
+		RequestExample: userDomain.UpdateUserRequestDTO{FirstName: "Petr"},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Description: "Updated user.", Body: userDomain.GetUserDTO{}, Example: userExample},
+			{Status: http.StatusBadRequest, Description: "Invalid user id or request body.", Body: apperror.AppError{}, Example: badRequestExample},
+			{Status: http.StatusNotFound, Description: "User not found.", Body: apperror.AppError{}, Example: notFoundExample},
+			{Status: http.StatusConflict, Description: "Email is already taken.", Body: apperror.AppError{}, Example: apperror.AppError{Code: apperror.AnyIntYouWantErrorCode, Error: apperror.EmailTakenErrorText}},
+			idempotencyMismatch,
+			{Status: http.StatusInternalServerError, Body: apperror.AppError{}, Example: internalErrorExample},
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodDelete,
+		Path:    "/api/user/:id",
+		ID:      "deleteUser",
+		Summary: "Delete user",
+		Tags:    []string{"user"},
+		Params:  []openapi.Parameter{userIDParam},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Description: "User deleted.", Body: userDomain.DeleteUserResponseDTO{}, Example: userDomain.DeleteUserResponseDTO{Deleted: userExample.ID}},
+			{Status: http.StatusBadRequest, Description: "Invalid user id.", Body: apperror.AppError{}},
+			{Status: http.StatusNotFound, Description: "User not found.", Body: apperror.AppError{}, Example: notFoundExample},
+			{Status: http.StatusInternalServerError, Body: apperror.AppError{}, Example: internalErrorExample},
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:         http.MethodPost,
+		Path:           "/graphql",
+		ID:             "graphql",
+		Summary:        "Execute GraphQL query or mutation over users",
+		Tags:           []string{"graphql"},
+		Request:        graphqlRequest{},
+		RequestExample: graphqlRequest{Query: `{ users(limit: 10, filter: {sex: "male"}) { total items { id login } } }`},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Description: "Result of execution, may contain field errors.", Body: graphqlResponse{}},
+			{Status: http.StatusBadRequest, Description: "Query is invalid or exceeds depth or complexity limits.", Body: graphqlResponse{}},
+		},
+	})
+
+	describeWebhooks(spec)
+	describeSCIM(spec)
+	describeUserConstraints(spec)
+
+	return spec
+}
+
+// describeWebhooks adds subscription management endpoints, which require bearer token of webhook.tokens.
+func describeWebhooks(spec *openapi.Spec) {
+	subscriptionIDParam := openapi.Parameter{
+		Name:     "id",
+		In:       "path",
+		Required: true,
+		Schema:   &openapi.Schema{Type: "string", Format: "uuid"},
+	}
+	deliveryIDParam := openapi.Parameter{
+		Name:     "deliveryID",
+		In:       "path",
+		Required: true,
+		Schema:   &openapi.Schema{Type: "string", Format: "uuid"},
+	}
+	notFound := openapi.ResponseSpec{Status: http.StatusNotFound, Body: apperror.AppError{}}
+	badRequest := openapi.ResponseSpec{Status: http.StatusBadRequest, Body: apperror.AppError{}}
+	internal := openapi.ResponseSpec{Status: http.StatusInternalServerError, Body: apperror.AppError{}, Example: internalErrorExample}
+	unauthorized := openapi.ResponseSpec{Status: http.StatusUnauthorized, Description: "Bearer token is missing or invalid.", Body: apperror.AppError{}}
+
+	spec.Add(openapi.Operation{
+		Method: http.MethodPost,
+		Path:   "/api/webhook",
+		ID:     "createSubscription",
+		Summary: "Subscribe URL to user events. Requests are signed with returned secret: " +
+			"Webhook-Signature header is v1=hex(HMAC-SHA256(secret, Webhook-Timestamp + \".\" + body))",
+		Tags:    []string{"webhook"},
+		Params:  []openapi.Parameter{idempotencyKeyParam},
+		Request: webhookDomain.CreateSubscriptionRequestDTO{},
+		RequestExample: webhookDomain.CreateSubscriptionRequestDTO{
+			URL:    "https://example.com/hooks/comfortel",
+			Events: []string{userDomain.EventCreated, userDomain.EventDeleted},
+		},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Description: "Subscription created. Secret is not shown again.", Body: webhookDomain.CreateSubscriptionResponseDTO{}},
+			badRequest,
+			idempotencyMismatch,
+			unauthorized,
+			internal,
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    "/api/webhook",
+		ID:      "getSubscriptions",
+		Summary: "List subscriptions",
+		Tags:    []string{"webhook"},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Body: []webhookDomain.GetSubscriptionDTO{}},
+			unauthorized,
+			internal,
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    "/api/webhook/:id",
+		ID:      "getSubscription",
+		Summary: "Get subscription",
+		Tags:    []string{"webhook"},
+		Params:  []openapi.Parameter{subscriptionIDParam},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Body: webhookDomain.GetSubscriptionDTO{}},
+			badRequest,
+			notFound,
+			unauthorized,
+			internal,
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodDelete,
+		Path:    "/api/webhook/:id",
+		ID:      "deleteSubscription",
+		Summary: "Delete subscription with its deliveries",
+		Tags:    []string{"webhook"},
+		Params:  []openapi.Parameter{subscriptionIDParam},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Body: webhookDomain.DeleteSubscriptionResponseDTO{}},
+			badRequest,
+			notFound,
+			unauthorized,
+			internal,
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    "/api/webhook/:id/delivery",
+		ID:      "getDeliveries",
+		Summary: "List latest deliveries of subscription, status=dead gives dead-letter list",
+		Tags:    []string{"webhook"},
+		Params: []openapi.Parameter{
+			subscriptionIDParam,
+			{
+				Name:   "status",
+				In:     "query",
+				Schema: &openapi.Schema{Type: "string", Enum: []any{webhookDomain.StatusPending, webhookDomain.StatusDelivered, webhookDomain.StatusDead}},
+			},
+		},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Body: []webhookDomain.GetDeliveryDTO{}},
+			badRequest,
+			notFound,
+			unauthorized,
+			internal,
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    "/api/webhook/:id/delivery/:deliveryID",
+		ID:      "getDelivery",
+		Summary: "Get delivery with log of attempts",
+		Tags:    []string{"webhook"},
+		Params:  []openapi.Parameter{subscriptionIDParam, deliveryIDParam},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Body: webhookDomain.GetDeliveryDTO{}},
+			badRequest,
+			notFound,
+			unauthorized,
+			internal,
+		},
+	})
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodPost,
+		Path:    "/api/webhook/:id/delivery/:deliveryID/redeliver",
+		ID:      "redeliverDelivery",
+		Summary: "Schedule delivery for immediate sending with fresh attempts budget",
+		Tags:    []string{"webhook"},
+		Params:  []openapi.Parameter{subscriptionIDParam, deliveryIDParam, idempotencyKeyParam},
+		Responses: []openapi.ResponseSpec{
+			{Status: http.StatusOK, Body: webhookDomain.RedeliverResponseDTO{}},
+			badRequest,
+			notFound,
+			idempotencyMismatch,
+			unauthorized,
+			internal,
+		},
+	})
+
+	spec.Component("CreateSubscriptionRequestDTO").Required = []string{"url", "events"}
+	spec.Property("CreateSubscriptionRequestDTO", "url").Format = "uri"
+	spec.Property("CreateSubscriptionRequestDTO", "events").Items = &openapi.Schema{
+		Type: "string",
+		Enum: []any{userDomain.EventCreated, userDomain.EventUpdated, userDomain.EventDeleted, userDomain.EventActivated},
+	}
+}
+
+// describeSCIM adds SCIM 2.0 provisioning endpoints. Their payloads are defined by RFC 7643 and RFC 7644.
+func describeSCIM(spec *openapi.Spec) {
+	errorResponses := []openapi.ResponseSpec{
+		{Status: http.StatusUnauthorized, Description: "Bearer token is missing or invalid.", Body: scim.Error{}},
+	}
+	discovery := func(path, id, summary string) {
+		spec.Add(openapi.Operation{
+			Method:  http.MethodGet,
+			Path:    "/scim/v2/" + path,
+			ID:      id,
+			Summary: summary,
+			Tags:    []string{"scim"},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Body: map[string]any{}},
+			}, errorResponses...),
+		})
+	}
+	discovery("ServiceProviderConfig", "scimServiceProviderConfig", "SCIM service provider configuration")
+	discovery("ResourceTypes", "scimResourceTypes", "SCIM resource types")
+	discovery("Schemas", "scimSchemas", "SCIM schemas")
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    "/scim/v2/Users",
+		ID:      "scimListUsers",
+		Summary: "List or filter provisioned users",
+		Tags:    []string{"scim"},
+		Params: []openapi.Parameter{
+			{Name: "filter", In: "query", Description: `Comparisons joined with "and", e.g. userName eq "ivan01".`, Schema: &openapi.Schema{Type: "string"}},
+			{Name: "startIndex", In: "query", Description: "1-based index of first result.", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "count", In: "query", Description: "Page size.", Schema: &openapi.Schema{Type: "integer"}},
+		},
+		Responses: append([]openapi.ResponseSpec{
+			{Status: http.StatusOK, Body: scim.ListResponse{}},
+			{Status: http.StatusBadRequest, Description: "Filter is invalid or not supported.", Body: scim.Error{}},
+		}, errorResponses...),
+	})
+
+	spec.Add(openapi.Operation{
+		Method:  http.MethodPost,
+		Path:    "/scim/v2/Users",
+		ID:      "scimCreateUser",
+		Summary: "Provision user",
+		Tags:    []string{"scim"},
+		Request: scim.User{},
+		Responses: append([]openapi.ResponseSpec{
+			{Status: http.StatusCreated, Body: scim.User{}},
+			{Status: http.StatusBadRequest, Body: scim.Error{}},
+			{Status: http.StatusConflict, Description: "userName or email is already taken.", Body: scim.Error{}},
+		}, errorResponses...),
+	})
+
+	for _, op := range []struct {
+		method  string
+		id      string
+		summary string
+		request any
+		status  int
+		body    any
+	}{
+		{method: http.MethodGet, id: "scimGetUser", summary: "Get provisioned user", status: http.StatusOK, body: scim.User{}},
+		{method: http.MethodPut, id: "scimReplaceUser", summary: "Replace provisioned user", request: scim.User{}, status: http.StatusOK, body: scim.User{}},
+		{method: http.MethodPatch, id: "scimPatchUser", summary: "Patch provisioned user", request: scim.PatchRequest{}, status: http.StatusOK, body: scim.User{}},
+		{method: http.MethodDelete, id: "scimDeleteUser", summary: "Deprovision user", status: http.StatusNoContent},
+	} {
+		spec.Add(openapi.Operation{
+			Method:  op.method,
+			Path:    "/scim/v2/Users/:id",
+			ID:      op.id,
+			Summary: op.summary,
+			Tags:    []string{"scim"},
+			Params:  []openapi.Parameter{userIDParam},
+			Request: op.request,
+			Responses: append([]openapi.ResponseSpec{
+				{Status: op.status, Body: op.body},
+				{Status: http.StatusBadRequest, Body: scim.Error{}},
+				{Status: http.StatusNotFound, Body: scim.Error{}},
+			}, errorResponses...),
+		})
+	}
+}
+
+// describeUserConstraints adds validation rules from user domain to DTO schemas.
+func describeUserConstraints(spec *openapi.Spec) {
+	maxName := userDomain.MaxNameLength
+	maxAge := float64(userDomain.MaxAge)
+	sexes := []any{userDomain.SexMale, userDomain.SexFemale}
+
+	create := "CreateUserRequestDTO"
+	spec.Component(create).Required = []string{"login", "password"}
+	spec.Property(create, "login").Pattern = userDomain.LoginPattern
+	spec.Property(create, "login").Description = "Case-insensitive, stored in lowercase."
+	spec.Property(create, "password").Pattern = userDomain.PasswordPattern
+	spec.Property(create, "password").Format = "password"
+	spec.Property(create, "email").Pattern = userDomain.EmailPattern
+	spec.Property(create, "email").Format = "email"
+	spec.Property(create, "sex").Enum = sexes
+	spec.Property(create, "age").Maximum = &maxAge
+	spec.Property(create, "avatarURL").Format = "uri"
+
+	update := "UpdateUserRequestDTO"
+	spec.Component(update).Required = nil
+	spec.Property(update, "email").Pattern = userDomain.EmailPattern
+	spec.Property(update, "email").Format = "email"
+	spec.Property(update, "avatarURL").Format = "uri"
+
+	for _, component := range []string{create, update} {
+		for _, name := range []string{"firstName", "lastName", "middleName"} {
+			spec.Property(component, name).MaxLength = &maxName
+		}
+	}
+
+	get := "GetUserDTO"
+	spec.Property(get, "id").Format = "uuid"
+	spec.Property(get, "registerDate").Format = "date"
+	spec.Property(get, "sex").Enum = sexes
+
+	batch := "BatchRequestDTO"
+	spec.Component(batch).Required = []string{"operations"}
+	spec.Property(batch, "mode").Enum = []any{userDomain.BatchModeAtomic, userDomain.BatchModeBestEffort}
+	spec.Property(batch, "mode").Description = "Atomic by default."
+	spec.Property(batch, "operations").Description = fmt.Sprintf("At most %d operations.", userDomain.MaxBatchSize)
+
+	operation := "BatchOperationDTO"
+	spec.Property(operation, "op").Enum = []any{userDomain.BatchOpCreate, userDomain.BatchOpUpdate, userDomain.BatchOpDelete}
+	spec.Property(operation, "id").Format = "uuid"
+	spec.Property(operation, "id").Description = "Required for update and delete."
+	*spec.Property(operation, "data") = openapi.Schema{
+		Type:        "object",
+		Description: "CreateUserRequestDTO for create, UpdateUserRequestDTO for update.",
+	}
+	spec.Property("BatchResultDTO", "body").Description = "Response of single operation endpoint."
+}
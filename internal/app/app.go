@@ -1,68 +1,653 @@
 package app
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/srgklmv/comfortel/internal/admin"
 	"github.com/srgklmv/comfortel/internal/api"
 	"github.com/srgklmv/comfortel/internal/config"
 	"github.com/srgklmv/comfortel/internal/controller"
+	ratelimitDomain "github.com/srgklmv/comfortel/internal/domain/ratelimit"
+	"github.com/srgklmv/comfortel/internal/eventstream"
+	"github.com/srgklmv/comfortel/internal/graphqlapi"
+	"github.com/srgklmv/comfortel/internal/grpcapi"
+	"github.com/srgklmv/comfortel/internal/health"
+	"github.com/srgklmv/comfortel/internal/idempotency"
+	"github.com/srgklmv/comfortel/internal/metrics"
+	"github.com/srgklmv/comfortel/internal/middleware"
+	"github.com/srgklmv/comfortel/internal/outbox"
+	"github.com/srgklmv/comfortel/internal/ratelimit"
 	"github.com/srgklmv/comfortel/internal/repository"
+	"github.com/srgklmv/comfortel/internal/scim"
 	"github.com/srgklmv/comfortel/internal/usecase"
+	"github.com/srgklmv/comfortel/internal/webhook"
+	"github.com/srgklmv/comfortel/migrations"
 	"github.com/srgklmv/comfortel/pkg/database"
+	"github.com/srgklmv/comfortel/pkg/logger"
+	"github.com/srgklmv/comfortel/pkg/publisher"
+	"github.com/srgklmv/comfortel/pkg/tracing"
+	"google.golang.org/grpc"
 )
 
+// checker is implemented by publishers which keep connection to broker.
+type checker interface {
+	Check(ctx context.Context) error
+}
+
+// rateLimitStore keeps buckets of rate limits, see middleware.RateLimit.
+type rateLimitStore interface {
+	TakeRateLimitTokens(ctx context.Context, key string, n int, interval time.Duration, limit int) (bool, time.Duration, error)
+}
+
+// liveSettings are settings which Reload applies to running app, by name or section prefix.
+var liveSettings = []string{
+	"log.",
+	"cors.",
+	"security.maxBodyBytes",
+	"database.maxOpenConns",
+	"database.maxIdleConns",
+	"database.connMaxLifetime",
+	"database.connMaxIdleTime",
+	"rateLimit.policies",
+}
+
 type app struct {
-	engine *gin.Engine
-	conn   *sql.DB
+	// mu guards cfg and what Reload changes from concurrent setup in Run.
+	mu              sync.Mutex
+	cfg             config.Config
+	engine          *gin.Engine
+	server          *http.Server
+	listener        net.Listener
+	ready           atomic.Bool
+	errs            chan error
+	cors            *middleware.Swappable
+	maxBodySize     *middleware.Swappable
+	rateLimit       *middleware.Swappable
+	rateLimitStore  rateLimitStore
+	replicaConns    []*sql.DB
+	grpcServer      *grpc.Server
+	metricsServer   *http.Server
+	metricsListener net.Listener
+	adminServer     *http.Server
+	adminListener   net.Listener
+	grpcListener    net.Listener
+	conn            *sql.DB
+	replicas        *repository.Replicas
+	publisher       publisher.Publisher
+	stopWorkers     context.CancelFunc
+	stopTracing     func(ctx context.Context) error
+	workers         sync.WaitGroup
+
+	// migrationVersion is schema version app works with, version of last embedded migration.
+	migrationVersion int
 }
 
-func New() *app {
+func New(cfg config.Config) *app {
+	// Debug mode of gin prints routes and warnings as plain text, GIN_MODE=debug brings it back.
+	if os.Getenv(gin.EnvGinMode) == "" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
 	return &app{
-		engine: gin.Default(),
+		cfg:    cfg,
+		engine: gin.New(),
+		// Each server sends at most one error.
+		errs: make(chan error, 4),
 	}
 }
 
-func (a *app) Run() error {
-	cfg, err := config.Init()
-	if err != nil {
-		return fmt.Errorf("config.Init: %w", err)
+// Start sets app up and starts serving in background. App is ready when Start returns without error,
+// errors of serving are sent to Err later. App must be shut down even if Start fails, to release what
+// was set up before failure.
+func (a *app) Start() error {
+	if err := a.setup(); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := a.server.Serve(a.listener); !errors.Is(err, http.ErrServerClosed) {
+			a.errs <- fmt.Errorf("server.Serve: %w", err)
+		}
+	}()
+	logger.Info("HTTP server is listening", slog.String("address", a.listener.Addr().String()))
+
+	if a.metricsServer != nil {
+		go func() {
+			if err := a.metricsServer.Serve(a.metricsListener); !errors.Is(err, http.ErrServerClosed) {
+				a.errs <- fmt.Errorf("metricsServer.Serve: %w", err)
+			}
+		}()
+		logger.Info("metrics server is listening", slog.String("address", a.metricsListener.Addr().String()))
+	}
+
+	if a.adminServer != nil {
+		go func() {
+			if err := a.adminServer.Serve(a.adminListener); !errors.Is(err, http.ErrServerClosed) {
+				a.errs <- fmt.Errorf("adminServer.Serve: %w", err)
+			}
+		}()
+		logger.Info("admin server is listening", slog.String("address", a.adminListener.Addr().String()))
+	}
+
+	if a.grpcServer != nil {
+		go func() {
+			if err := a.grpcServer.Serve(a.grpcListener); err != nil {
+				a.errs <- fmt.Errorf("grpcServer.Serve: %w", err)
+			}
+		}()
+		logger.Info("gRPC server is listening", slog.String("address", a.grpcListener.Addr().String()))
 	}
 
-	conn, err := database.New(
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.Name,
-		cfg.Database.User,
-		cfg.Database.Password,
+	a.ready.Store(true)
+
+	return nil
+}
+
+// Err returns channel of errors which stopped serving. App should be shut down after one.
+func (a *app) Err() <-chan error {
+	return a.errs
+}
+
+// config returns config app runs with, including settings changed by Reload.
+func (a *app) config() config.Config {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.cfg
+}
+
+// Ready reports whether app serves requests and is not shutting down.
+func (a *app) Ready() bool {
+	return a.ready.Load()
+}
+
+// setup connects to database, starts workers, builds servers and binds their listeners.
+// It holds mu, so Reload waits for it.
+func (a *app) setup() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cfg := a.cfg
+
+	stopTracing, err := tracing.New(
+		context.Background(),
+		cfg.Tracing.Exporter,
+		cfg.Tracing.Endpoint,
+		cfg.Tracing.ServiceName,
+		cfg.Tracing.SampleRatio,
 	)
 	if err != nil {
-		return fmt.Errorf("database.New: %w", err)
+		return fmt.Errorf("tracing.New: %w", err)
 	}
+	a.stopTracing = stopTracing
+
+	var conn *sql.DB
+	switch cfg.Database.Driver {
+	case database.DriverPostgres:
+		conn, err = database.New(
+			cfg.Database.Host,
+			cfg.Database.Port,
+			cfg.Database.Name,
+			cfg.Database.User,
+			cfg.Database.Password,
+		)
+		if err != nil {
+			return fmt.Errorf("database.New: %w", err)
+		}
+	case database.DriverSQLite:
+		conn, err = database.NewSQLite(cfg.Database.Path)
+		if err != nil {
+			return fmt.Errorf("database.NewSQLite: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown database driver %q", cfg.Database.Driver)
+	}
+	setPool(conn, cfg.Database)
 	a.conn = conn
+	if err = metrics.RegisterDB("primary", conn); err != nil {
+		return fmt.Errorf("metrics.RegisterDB: %w", err)
+	}
 
-	err = database.Migrate(conn, "file://migrations", 1)
+	a.migrationVersion, err = database.LatestVersion(migrations.FS, cfg.Database.Driver)
+	if err != nil {
+		return fmt.Errorf("database.LatestVersion: %w", err)
+	}
+	err = database.Migrate(conn, cfg.Database.Driver, migrations.FS, a.migrationVersion)
 	if err != nil {
 		return fmt.Errorf("database.Migrate: %w", err)
 	}
 
 	// cache. any need?
 
-	repo := repository.New(a.conn)
-	uc := usecase.New(repo)
+	var transactorOpts []repository.TransactorOption
+	if len(cfg.Database.Replicas) > 0 {
+		if cfg.Database.Driver != database.DriverPostgres {
+			return fmt.Errorf("replicas are not supported by %s driver", cfg.Database.Driver)
+		}
+
+		var replicaConns []*sql.DB
+		for i, dsn := range cfg.Database.Replicas {
+			replicaConn, err := database.NewReplica(dsn)
+			if err != nil {
+				return fmt.Errorf("database.NewReplica: %w", err)
+			}
+			setPool(replicaConn, cfg.Database)
+			replicaConns = append(replicaConns, replicaConn)
+			if err = metrics.RegisterDB(fmt.Sprintf("replica%d", i), replicaConn); err != nil {
+				return fmt.Errorf("metrics.RegisterDB: %w", err)
+			}
+		}
+		a.replicaConns = replicaConns
+
+		a.replicas = repository.NewReplicas(replicaConns, time.Duration(cfg.Database.ReplicaMaxLag))
+		transactorOpts = append(transactorOpts, repository.WithReplicas(a.replicas))
+	}
+
+	repo := repository.New(a.conn, cfg.Database.Driver, repository.WithQueryObserver(metrics.ObserveQuery))
+	transactor := repository.NewTransactor(a.conn, transactorOpts...)
+	uc := usecase.New(repo, transactor)
 	c := controller.New(uc)
 
+	a.publisher, err = publisher.New(cfg.Outbox.Publisher, cfg.Outbox.URL, cfg.Outbox.SubjectPrefix)
+	if err != nil {
+		return fmt.Errorf("publisher.New: %w", err)
+	}
+
+	events := eventstream.New(repo)
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	a.stopWorkers = stopWorkers
+	a.workers.Add(4)
+	go func() {
+		defer a.workers.Done()
+		webhook.NewWorker(transactor, repo, webhook.WithAllowedNetworks(config.Prefixes(cfg.Webhook.AllowedNetworks))).Run(workersCtx)
+	}()
+	go func() {
+		defer a.workers.Done()
+		outbox.NewRelay(transactor, repo, a.publisher).Run(workersCtx)
+	}()
+	go func() {
+		defer a.workers.Done()
+		events.Run(workersCtx)
+	}()
+	go func() {
+		defer a.workers.Done()
+		idempotency.NewCleaner(repo).Run(workersCtx)
+	}()
+	if a.replicas != nil {
+		a.workers.Add(1)
+		go func() {
+			defer a.workers.Done()
+			a.replicas.Run(workersCtx)
+		}()
+	}
+
+	a.rateLimitStore = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Store == "database" {
+		a.rateLimitStore = repo
+		a.workers.Add(1)
+		go func() {
+			defer a.workers.Done()
+			ratelimit.NewCleaner(repo).Run(workersCtx)
+		}()
+	}
+
+	if cfg.GRPC.Address != "" {
+		a.grpcListener, err = net.Listen("tcp", cfg.GRPC.Address)
+		if err != nil {
+			return fmt.Errorf("net.Listen: %w", err)
+		}
+		a.grpcServer = grpcapi.New(transactor, uc, cfg.GRPC.Tokens)
+	}
+
+	gql, err := graphqlapi.New(uc, transactor)
+	if err != nil {
+		return fmt.Errorf("graphqlapi.New: %w", err)
+	}
+
+	if err = a.engine.SetTrustedProxies(cfg.Security.TrustedProxies); err != nil {
+		return fmt.Errorf("engine.SetTrustedProxies: %w", err)
+	}
+	a.cors = middleware.NewSwappable(newCORS(cfg.CORS))
+	a.maxBodySize = middleware.NewSwappable(middleware.MaxBodySize(cfg.Security.MaxBodyBytes))
+	rateLimit, err := newRateLimit(a.rateLimitStore, cfg.RateLimit, apiKeys(cfg))
+	if err != nil {
+		return fmt.Errorf("newRateLimit: %w", err)
+	}
+	a.rateLimit = middleware.NewSwappable(rateLimit)
+	a.engine.Use(middleware.Tracing(), middleware.RequestLog(), middleware.Recovery(), metrics.HTTP(metrics.SkipRequests(middleware.Replayed)), a.cors.Handle, a.maxBodySize.Handle, middleware.Authenticate(realms(cfg)), a.rateLimit.Handle)
+
 	// routing
-	api.SetRoutes(a.engine, a.conn, c)
+	err = api.SetRoutes(
+		a.engine,
+		transactor,
+		c,
+		gql,
+		scim.New(uc, cfg.SCIM.Tokens, scim.WithTrustedProxies(config.Prefixes(cfg.Security.TrustedProxies))),
+		events,
+		health.New(a.Ready, a.healthComponents()...),
+		middleware.Idempotency(repo, time.Duration(cfg.Idempotency.TTL)),
+		middleware.ReplicaReads(time.Duration(cfg.Database.StickyWindow)),
+		middleware.BearerAuth("webhook", cfg.Webhook.Tokens),
+	)
+	if err != nil {
+		return fmt.Errorf("api.SetRoutes: %w", err)
+	}
 
-	if err = a.engine.Run("0.0.0.0:3000"); err != nil {
-		return fmt.Errorf("engine.Run: %w", err)
+	a.server = &http.Server{
+		Addr:              cfg.HTTP.Address,
+		Handler:           a.engine,
+		ReadTimeout:       time.Duration(cfg.HTTP.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(cfg.HTTP.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(cfg.HTTP.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.HTTP.IdleTimeout),
+	}
+	// Streams never become idle, so they are ended for server to drain.
+	a.server.RegisterOnShutdown(events.Close)
+	a.listener, err = net.Listen("tcp", cfg.HTTP.Address)
+	if err != nil {
+		return fmt.Errorf("net.Listen: %w", err)
+	}
+
+	if cfg.Metrics.Address != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		a.metricsServer = &http.Server{
+			Addr:              cfg.Metrics.Address,
+			Handler:           mux,
+			ReadHeaderTimeout: time.Duration(cfg.HTTP.ReadHeaderTimeout),
+		}
+		a.metricsListener, err = net.Listen("tcp", cfg.Metrics.Address)
+		if err != nil {
+			return fmt.Errorf("net.Listen: %w", err)
+		}
+	}
+
+	if cfg.Admin.Address != "" {
+		// Profiles take as long as requested, so there is no write timeout.
+		a.adminServer = &http.Server{
+			Addr:              cfg.Admin.Address,
+			Handler:           admin.New(a.config, cfg.Admin.Tokens),
+			ReadHeaderTimeout: time.Duration(cfg.HTTP.ReadHeaderTimeout),
+		}
+		a.adminListener, err = net.Listen("tcp", cfg.Admin.Address)
+		if err != nil {
+			return fmt.Errorf("net.Listen: %w", err)
+		}
 	}
 
 	return nil
 }
 
-func (a *app) Shutdown() error {
-	return database.Shutdown(a.conn)
+// Reload applies settings of cfg listed in liveSettings to running app. Other changed settings are kept
+// as they are and reported in returned error, as they need restart.
+func (a *app) Reload(cfg config.Config) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.server == nil {
+		return errors.New("app is not started")
+	}
+
+	var live, rejected []string
+	for _, name := range a.cfg.Changed(cfg) {
+		if slices.ContainsFunc(liveSettings, func(prefix string) bool { return strings.HasPrefix(name, prefix) }) {
+			live = append(live, name)
+		} else {
+			rejected = append(rejected, name)
+		}
+	}
+
+	if err := ConfigureLogger(cfg.Log); err != nil {
+		return fmt.Errorf("ConfigureLogger: %w", err)
+	}
+	a.cfg.Log = cfg.Log
+
+	a.cors.Swap(newCORS(cfg.CORS))
+	a.cfg.CORS = cfg.CORS
+
+	a.maxBodySize.Swap(middleware.MaxBodySize(cfg.Security.MaxBodyBytes))
+	a.cfg.Security.MaxBodyBytes = cfg.Security.MaxBodyBytes
+
+	// Tokens need restart to change, so keys are taken from running config.
+	rateLimit, err := newRateLimit(a.rateLimitStore, cfg.RateLimit, apiKeys(a.cfg))
+	if err != nil {
+		return fmt.Errorf("newRateLimit: %w", err)
+	}
+	a.rateLimit.Swap(rateLimit)
+	a.cfg.RateLimit.Policies = cfg.RateLimit.Policies
+
+	a.cfg.Database.MaxOpenConns = cfg.Database.MaxOpenConns
+	a.cfg.Database.MaxIdleConns = cfg.Database.MaxIdleConns
+	a.cfg.Database.ConnMaxLifetime = cfg.Database.ConnMaxLifetime
+	a.cfg.Database.ConnMaxIdleTime = cfg.Database.ConnMaxIdleTime
+	setPool(a.conn, a.cfg.Database)
+	for _, conn := range a.replicaConns {
+		setPool(conn, a.cfg.Database)
+	}
+
+	if len(live) > 0 {
+		logger.Info("config reloaded", slog.Any("settings", live))
+	}
+	if len(rejected) > 0 {
+		return fmt.Errorf("settings %s need restart to change, they are kept as they were", strings.Join(rejected, ", "))
+	}
+
+	return nil
+}
+
+// Shutdown stops app in order: it reports not ready and waits http.shutdownDelay for load balancers to notice,
+// drains HTTP and gRPC servers, stops workers and metrics server and closes publisher and databases.
+// Servers are stopped forcibly when ctx is done.
+func (a *app) Shutdown(ctx context.Context) error {
+	wasReady := a.ready.Swap(false)
+
+	var errs []error
+
+	if wasReady {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Duration(a.config().HTTP.ShutdownDelay)):
+		}
+	}
+
+	// Listeners are closed by servers which serve them, and here if Start failed before that.
+	if a.server != nil {
+		if err := a.server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("server.Shutdown: %w", err))
+			_ = a.server.Close()
+		}
+	}
+	if a.listener != nil {
+		_ = a.listener.Close()
+	}
+
+	if a.grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			a.grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("grpcServer.GracefulStop: %w", ctx.Err()))
+			a.grpcServer.Stop()
+		}
+	}
+	if a.grpcListener != nil {
+		_ = a.grpcListener.Close()
+	}
+
+	// Workers go after servers, so outbox relay and webhooks pick up what last requests wrote.
+	if a.stopWorkers != nil {
+		a.stopWorkers()
+		a.workers.Wait()
+	}
+
+	// Metrics are served until the end, so last scrape sees drained requests.
+	if a.metricsServer != nil {
+		if err := a.metricsServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("metricsServer.Shutdown: %w", err))
+			_ = a.metricsServer.Close()
+		}
+	}
+	if a.metricsListener != nil {
+		_ = a.metricsListener.Close()
+	}
+	if a.adminServer != nil {
+		if err := a.adminServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("adminServer.Shutdown: %w", err))
+			_ = a.adminServer.Close()
+		}
+	}
+	if a.adminListener != nil {
+		_ = a.adminListener.Close()
+	}
+
+	if a.publisher != nil {
+		if err := a.publisher.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("publisher.Close: %w", err))
+		}
+	}
+	if a.replicas != nil {
+		if err := a.replicas.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("replicas.Close: %w", err))
+		}
+	}
+
+	// Spans are flushed after everything which makes them has stopped.
+	if a.stopTracing != nil {
+		if err := a.stopTracing(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stopTracing: %w", err))
+		}
+	}
+
+	if a.conn != nil {
+		if err := database.Shutdown(a.conn); err != nil {
+			errs = append(errs, fmt.Errorf("database.Shutdown: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ConfigureLogger replaces default logger with one configured by cfg.
+func ConfigureLogger(cfg config.Log) error {
+	return logger.Configure(
+		cfg.Level,
+		logger.WithFormat(cfg.Format),
+		logger.WithOutput(cfg.Output),
+		logger.WithFile(cfg.File, cfg.MaxSizeMB, cfg.MaxBackups),
+		logger.WithSyslog(cfg.SyslogAddress),
+		logger.WithSampling(cfg.SampleFirst, cfg.SampleThereafter, time.Duration(cfg.SampleInterval)),
+		logger.WithRedaction(cfg.Redact...),
+	)
+}
+
+// healthComponents returns dependencies checked by readiness probe.
+func (a *app) healthComponents() []health.Component {
+	components := []health.Component{
+		{
+			Name: "database",
+			Check: func(ctx context.Context) error {
+				return a.conn.PingContext(ctx)
+			},
+		},
+		{
+			Name: "migrations",
+			Check: func(ctx context.Context) error {
+				version, dirty, err := database.MigrationVersion(ctx, a.conn)
+				if err != nil {
+					return fmt.Errorf("database.MigrationVersion: %w", err)
+				}
+				if dirty {
+					return fmt.Errorf("migration %d failed halfway", version)
+				}
+				if version != a.migrationVersion {
+					return fmt.Errorf("schema version is %d, expected %d", version, a.migrationVersion)
+				}
+				return nil
+			},
+		},
+	}
+
+	if checker, ok := a.publisher.(checker); ok {
+		components = append(components, health.Component{Name: "publisher", Check: checker.Check})
+	}
+
+	// Reads fall back to primary, so replicas being down does not make app not ready.
+	if a.replicas != nil {
+		components = append(components, health.Component{
+			Name:     "replicas",
+			Optional: true,
+			Check: func(ctx context.Context) error {
+				if a.replicas.Healthy() == 0 {
+					return errors.New("no healthy replicas")
+				}
+				return nil
+			},
+		})
+	}
+
+	return components
+}
+
+func newCORS(cfg config.CORS) gin.HandlerFunc {
+	return cors.New(cors.Config{
+		AllowOrigins:     cfg.AllowOrigins,
+		AllowMethods:     cfg.AllowMethods,
+		AllowHeaders:     cfg.AllowHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           time.Duration(cfg.MaxAge),
+	})
+}
+
+// newRateLimit returns middleware limiting requests by policies of cfg, see ratelimit.ParsePolicy.
+func newRateLimit(store rateLimitStore, cfg config.RateLimit, apiKeys []string) (gin.HandlerFunc, error) {
+	policies := make([]ratelimitDomain.Policy, 0, len(cfg.Policies))
+	for _, s := range cfg.Policies {
+		p, err := ratelimitDomain.ParsePolicy(s)
+		if err != nil {
+			return nil, fmt.Errorf("ratelimit.ParsePolicy %q: %w", s, err)
+		}
+		policies = append(policies, p)
+	}
+
+	return middleware.RateLimit(
+		store,
+		policies,
+		middleware.RouteCost(http.MethodPost, "/api/user/batch", controller.BatchCost),
+		middleware.APIKeys(apiKeys...),
+	), nil
+}
+
+// apiKeys returns tokens app authenticates callers with, which rate limit counts requests by.
+func apiKeys(cfg config.Config) []string {
+	return slices.Concat(cfg.SCIM.Tokens, cfg.Webhook.Tokens, cfg.GRPC.Tokens)
+}
+
+// realms returns tokens of HTTP APIs by API, whose callers rate limit counts requests of user policies by.
+func realms(cfg config.Config) map[string][]string {
+	return map[string][]string{"scim": cfg.SCIM.Tokens, "webhook": cfg.Webhook.Tokens}
+}
+
+func setPool(conn *sql.DB, cfg config.Database) {
+	conn.SetMaxOpenConns(cfg.MaxOpenConns)
+	conn.SetMaxIdleConns(cfg.MaxIdleConns)
+	conn.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime))
+	conn.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTime))
 }
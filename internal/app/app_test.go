@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srgklmv/comfortel/internal/config"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+// TestReload starts app with sqlite database. Metrics of database pool can be registered once per process,
+// so this is the only test starting app.
+func TestReload(t *testing.T) {
+	cfg := config.Default()
+	cfg.HTTP.Address = "127.0.0.1:0"
+	cfg.HTTP.ShutdownDelay = config.Duration(50 * time.Millisecond)
+	cfg.Metrics.Address = ""
+	cfg.Admin.Address = ""
+	cfg.Log.Level = "error"
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = filepath.Join(t.TempDir(), "app.db")
+	if err := ConfigureLogger(cfg.Log); err != nil {
+		t.Fatalf("ConfigureLogger: %v", err)
+	}
+
+	a := New(cfg)
+	t.Cleanup(func() { _ = a.Shutdown(context.Background()) })
+	if err := a.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	url := "http://" + a.listener.Addr().String()
+
+	if got := allowedOrigin(t, url, "https://example.com"); got != "*" {
+		t.Fatalf("allowed origin %q before reload, want *", got)
+	}
+
+	reloaded := cfg
+	reloaded.Log.Level = "warn"
+	reloaded.CORS.AllowOrigins = []string{"https://example.com"}
+	reloaded.Database.MaxOpenConns = 3
+	reloaded.HTTP.Address = "127.0.0.1:1"
+	reloaded.SCIM.Tokens = []string{"new"}
+
+	err := a.Reload(reloaded)
+	if err == nil || !strings.Contains(err.Error(), "http.address, scim.tokens") {
+		t.Fatalf("Reload error %v, want http.address and scim.tokens rejected", err)
+	}
+	for _, live := range []string{"log.level", "cors.allowOrigins", "database.maxOpenConns"} {
+		if strings.Contains(err.Error(), live) {
+			t.Errorf("Reload rejected live setting %s: %v", live, err)
+		}
+	}
+
+	// Live settings are applied, others are kept as they were.
+	running := a.config()
+	if running.HTTP.Address != cfg.HTTP.Address || len(running.SCIM.Tokens) != 0 {
+		t.Errorf("running http.address %q, scim.tokens %v, want them kept", running.HTTP.Address, running.SCIM.Tokens)
+	}
+	if running.Log.Level != "warn" || running.Database.MaxOpenConns != 3 {
+		t.Errorf("running log.level %q, database.maxOpenConns %d, want reloaded", running.Log.Level, running.Database.MaxOpenConns)
+	}
+	if logger.Level() != slog.LevelWarn {
+		t.Errorf("log level %s, want WARN", logger.Level())
+	}
+	if got := a.conn.Stats().MaxOpenConnections; got != 3 {
+		t.Errorf("max open conns of pool %d, want 3", got)
+	}
+	if got := allowedOrigin(t, url, "https://example.com"); got != "https://example.com" {
+		t.Errorf("allowed origin %q after reload, want https://example.com", got)
+	}
+	if got := allowedOrigin(t, url, "https://other.example.com"); got != "" {
+		t.Errorf("allowed origin %q of other origin after reload, want none", got)
+	}
+
+	// Reload may come during shutdown, which reads config under same lock.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = a.Reload(reloaded)
+	}()
+	if err = a.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+	wg.Wait()
+}
+
+// allowedOrigin returns Access-Control-Allow-Origin of preflight request from origin.
+func allowedOrigin(t *testing.T, url, origin string) string {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodOptions, url+"/api/user", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("client.Do: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	return resp.Header.Get("Access-Control-Allow-Origin")
+}
@@ -1,48 +1,387 @@
 package config
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/srgklmv/comfortel/internal/domain/ratelimit"
 )
 
 type Config struct {
-	Database Database `json:"database"`
+	HTTP        HTTP        `json:"http"`
+	Metrics     Metrics     `json:"metrics"`
+	Admin       Admin       `json:"admin"`
+	Tracing     Tracing     `json:"tracing"`
+	Log         Log         `json:"log"`
+	CORS        CORS        `json:"cors"`
+	Security    Security    `json:"security"`
+	Database    Database    `json:"database"`
+	GRPC        GRPC        `json:"grpc"`
+	SCIM        SCIM        `json:"scim"`
+	Webhook     Webhook     `json:"webhook"`
+	Outbox      Outbox      `json:"outbox"`
+	Idempotency Idempotency `json:"idempotency"`
+	RateLimit   RateLimit   `json:"rateLimit"`
+}
+
+// HTTP configures HTTP server. Zero timeout means no timeout. On shutdown app reports not ready for
+// ShutdownDelay before it stops accepting requests, and ShutdownTimeout bounds whole shutdown.
+type HTTP struct {
+	Address           string   `json:"address"`
+	ReadTimeout       Duration `json:"readTimeout"`
+	ReadHeaderTimeout Duration `json:"readHeaderTimeout"`
+	WriteTimeout      Duration `json:"writeTimeout"`
+	IdleTimeout       Duration `json:"idleTimeout"`
+	ShutdownDelay     Duration `json:"shutdownDelay"`
+	ShutdownTimeout   Duration `json:"shutdownTimeout"`
+}
+
+// Metrics configures Prometheus endpoint /metrics, which is served on its own Address, not with API.
+// Metrics are not served if Address is empty.
+type Metrics struct {
+	Address string `json:"address"`
+}
+
+// Admin configures diagnostics endpoints: pprof, log level, build info and config. They are served on
+// Address, which is local by default, and are not served if it is empty. Requests must carry one of Tokens
+// as bearer token if any are set, tokens are required if Address is not loopback.
+type Admin struct {
+	Address string   `json:"address"`
+	Tokens  []string `json:"tokens" secret:"true"`
+}
+
+// Tracing configures export of traces. Exporter is "none" (default) or "otlp", which sends spans over
+// OTLP/HTTP to collector at Endpoint, e.g. "http://localhost:4318". SampleRatio is share of traces started
+// by app which are recorded, traces continued from caller follow its decision.
+type Tracing struct {
+	Exporter    string  `json:"exporter"`
+	Endpoint    string  `json:"endpoint"`
+	ServiceName string  `json:"serviceName"`
+	SampleRatio float64 `json:"sampleRatio"`
+}
+
+// Log configures logger. Level is one of "debug", "info", "warn" or "error", Format is "json" or "text".
+// Output is "stdout", "stderr", "file" or "syslog". File is rotated when it grows over MaxSizeMB, MaxBackups
+// rotated files are kept. SyslogAddress is like "udp://localhost:514", local syslog is used if it is empty.
+// Of records with same level and message within SampleInterval SampleFirst are logged, then every
+// SampleThereafter-th, zero SampleFirst disables sampling. Values of attributes with key containing
+// one of Redact are replaced.
+type Log struct {
+	Level            string   `json:"level"`
+	Format           string   `json:"format"`
+	Output           string   `json:"output"`
+	File             string   `json:"file"`
+	MaxSizeMB        int      `json:"maxSizeMB"`
+	MaxBackups       int      `json:"maxBackups"`
+	SyslogAddress    string   `json:"syslogAddress"`
+	SampleFirst      int      `json:"sampleFirst"`
+	SampleThereafter int      `json:"sampleThereafter"`
+	SampleInterval   Duration `json:"sampleInterval"`
+	Redact           []string `json:"redact"`
+}
+
+// CORS configures cross-origin requests. "*" in AllowOrigins allows any origin.
+type CORS struct {
+	AllowOrigins     []string `json:"allowOrigins"`
+	AllowMethods     []string `json:"allowMethods"`
+	AllowHeaders     []string `json:"allowHeaders"`
+	AllowCredentials bool     `json:"allowCredentials"`
+	MaxAge           Duration `json:"maxAge"`
 }
 
+// Security configures client facing limits. Client IP is taken from X-Forwarded-For only behind
+// TrustedProxies (IPs or CIDRs), requests with body larger than MaxBodyBytes are rejected.
+type Security struct {
+	TrustedProxies []string `json:"trustedProxies"`
+	MaxBodyBytes   int64    `json:"maxBodyBytes"`
+}
+
+// Database configures storage. Driver is "postgres" (default) or "sqlite", which uses database file at Path
+// and ignores connection fields.
 type Database struct {
+	Driver   string `json:"driver"`
+	Path     string `json:"path"`
 	User     string `json:"user"`
-	Password string `json:"password"`
+	Password string `json:"password" secret:"true"`
 	Host     string `json:"host"`
 	Port     string `json:"port"`
 	Name     string `json:"name"`
+	// Pool limits apply to primary and to each replica, zero MaxOpenConns means no limit.
+	MaxOpenConns    int      `json:"maxOpenConns"`
+	MaxIdleConns    int      `json:"maxIdleConns"`
+	ConnMaxLifetime Duration `json:"connMaxLifetime"`
+	ConnMaxIdleTime Duration `json:"connMaxIdleTime"`
+	// Replicas are DSNs of postgres read replicas, which serve user reads round-robin.
+	// ReplicaMaxLag is replication lag after which replica is not used.
+	// Caller reads from primary for StickyWindow after its write.
+	Replicas      []string `json:"replicas" secret:"true"`
+	ReplicaMaxLag Duration `json:"replicaMaxLag"`
+	StickyWindow  Duration `json:"stickyWindow"`
+}
+
+// GRPC configures gRPC API. Server is not started if Address is empty.
+// Requests must carry one of Tokens as bearer token if any are set.
+type GRPC struct {
+	Address string   `json:"address"`
+	Tokens  []string `json:"tokens" secret:"true"`
+}
+
+// SCIM configures provisioning API. Requests are rejected if there are no Tokens.
+type SCIM struct {
+	Tokens []string `json:"tokens" secret:"true"`
+}
+
+// Webhook configures outgoing webhooks. Subscription API requests must carry one of Tokens as bearer token,
+// all of them are rejected if there are no tokens. Webhooks are not sent to loopback, link-local and private
+// addresses unless they are in AllowedNetworks (IPs or CIDRs).
+type Webhook struct {
+	Tokens          []string `json:"tokens" secret:"true"`
+	AllowedNetworks []string `json:"allowedNetworks"`
+}
+
+// Outbox configures publisher of domain events. Publisher is one of "log" (default), "http" or "nats".
+// URL is endpoint for "http" and server for "nats", SubjectPrefix is prepended to NATS subjects.
+type Outbox struct {
+	Publisher     string `json:"publisher"`
+	URL           string `json:"url" secret:"url"`
+	SubjectPrefix string `json:"subjectPrefix"`
+}
+
+// Idempotency configures storage of idempotency keys, which are kept for TTL.
+type Idempotency struct {
+	TTL Duration `json:"ttl"`
+}
+
+// RateLimit configures limits of HTTP requests. Store is "memory", which limits each instance on its own,
+// or "database", which shares limits across instances. Policies are like "POST /api/user ip 10/1m",
+// see ratelimit.ParsePolicy, requests are not limited if there are none.
+type RateLimit struct {
+	Store    string   `json:"store"`
+	Policies []string `json:"policies"`
+}
+
+// Default returns config used for settings which are not set in file, env or flags.
+func Default() Config {
+	return Config{
+		HTTP: HTTP{
+			Address:           "0.0.0.0:3000",
+			ReadTimeout:       Duration(15 * time.Second),
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			WriteTimeout:      Duration(30 * time.Second),
+			IdleTimeout:       Duration(time.Minute),
+			ShutdownTimeout:   Duration(30 * time.Second),
+		},
+		Metrics: Metrics{
+			Address: "0.0.0.0:9090",
+		},
+		Admin: Admin{
+			Address: "127.0.0.1:6060",
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			ServiceName: "comfortel",
+			SampleRatio: 1,
+		},
+		Log: Log{
+			Level:            "info",
+			Format:           "json",
+			Output:           "stdout",
+			MaxSizeMB:        100,
+			MaxBackups:       5,
+			SampleThereafter: 100,
+			SampleInterval:   Duration(time.Second),
+			Redact:           []string{"password", "token", "secret", "authorization", "email"},
+		},
+		CORS: CORS{
+			AllowOrigins: []string{"*"},
+			AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+			AllowHeaders: []string{"Origin", "Content-Length", "Content-Type"},
+			MaxAge:       Duration(12 * time.Hour),
+		},
+		Security: Security{
+			MaxBodyBytes: 4 << 20,
+		},
+		Database: Database{
+			Driver:          "postgres",
+			Port:            "5432",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: Duration(30 * time.Minute),
+			ConnMaxIdleTime: Duration(5 * time.Minute),
+			ReplicaMaxLag:   Duration(10 * time.Second),
+			StickyWindow:    Duration(5 * time.Second),
+		},
+		Outbox: Outbox{
+			Publisher: "log",
+		},
+		Idempotency: Idempotency{
+			TTL: Duration(24 * time.Hour),
+		},
+		RateLimit: RateLimit{
+			Store: "memory",
+			Policies: []string{
+				"POST /api/user ip 10/1m",
+				"POST /api/user/batch ip 200/1m",
+				"POST /graphql ip 60/1m",
+			},
+		},
+	}
 }
 
-// Init parses config file named "config.json" from same directory as executable binary
-// and return Config struct instance.
-func Init() (Config, error) {
-	exec, err := os.Executable()
-	if err != nil {
-		return Config{}, fmt.Errorf("os.Executable: %w", err)
+// Prefixes returns IPs and CIDRs of list as prefixes, IP is prefix of single address. Invalid items,
+// which Validate reports, are skipped.
+func Prefixes(list []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, item := range list {
+		if prefix, err := netip.ParsePrefix(item); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(item); err == nil {
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+
+	return prefixes
+}
+
+// Validate returns all problems of config joined, each naming the setting as in config file.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, name, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", name, fmt.Sprintf(format, args...)))
+		}
+	}
+	nonNegative := func(name string, d Duration) {
+		check(d >= 0, name, "must not be negative, got %s", d)
+	}
+
+	_, _, err := net.SplitHostPort(c.HTTP.Address)
+	check(err == nil, "http.address", "must be host:port, got %q", c.HTTP.Address)
+	nonNegative("http.readTimeout", c.HTTP.ReadTimeout)
+	nonNegative("http.readHeaderTimeout", c.HTTP.ReadHeaderTimeout)
+	nonNegative("http.writeTimeout", c.HTTP.WriteTimeout)
+	nonNegative("http.idleTimeout", c.HTTP.IdleTimeout)
+	nonNegative("http.shutdownDelay", c.HTTP.ShutdownDelay)
+	check(c.HTTP.ShutdownTimeout > c.HTTP.ShutdownDelay, "http.shutdownTimeout",
+		"must be greater than http.shutdownDelay %s, got %s", c.HTTP.ShutdownDelay, c.HTTP.ShutdownTimeout)
+
+	if c.Metrics.Address != "" {
+		_, _, err = net.SplitHostPort(c.Metrics.Address)
+		check(err == nil, "metrics.address", "must be host:port, got %q", c.Metrics.Address)
+		check(c.Metrics.Address != c.HTTP.Address, "metrics.address", "must differ from http.address")
+	}
+
+	if c.Admin.Address != "" {
+		host, _, err := net.SplitHostPort(c.Admin.Address)
+		check(err == nil, "admin.address", "must be host:port, got %q", c.Admin.Address)
+		ip, _ := netip.ParseAddr(host)
+		check(err != nil || host == "localhost" || ip.IsLoopback() || len(c.Admin.Tokens) > 0, "admin.tokens",
+			"must be set if admin.address %q is not loopback", c.Admin.Address)
+		check(c.Admin.Address != c.HTTP.Address && c.Admin.Address != c.Metrics.Address, "admin.address",
+			"must differ from http.address and metrics.address")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none":
+	case "otlp":
+		u, err := url.Parse(c.Tracing.Endpoint)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "tracing.endpoint",
+			"must be http(s) URL for otlp exporter, got %q", c.Tracing.Endpoint)
+	default:
+		check(false, "tracing.exporter", "must be none or otlp, got %q", c.Tracing.Exporter)
 	}
+	check(c.Tracing.ServiceName != "", "tracing.serviceName", "must not be empty")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sampleRatio",
+		"must be between 0 and 1, got %g", c.Tracing.SampleRatio)
 
-	dir, _ := filepath.Split(exec)
-	configPath := filepath.Join(dir, "config.json")
-	file, err := os.Open(configPath)
-	if err != nil {
-		return Config{}, fmt.Errorf("os.Open: %w", err)
+	check(slices.Contains([]string{"debug", "info", "warn", "error"}, c.Log.Level),
+		"log.level", "must be one of debug, info, warn, error, got %q", c.Log.Level)
+	check(slices.Contains([]string{"json", "text"}, c.Log.Format),
+		"log.format", "must be json or text, got %q", c.Log.Format)
+	switch c.Log.Output {
+	case "stdout", "stderr":
+	case "file":
+		check(c.Log.File != "", "log.file", "must be set for file output")
+	case "syslog":
+		if c.Log.SyslogAddress != "" {
+			u, err := url.Parse(c.Log.SyslogAddress)
+			check(err == nil && slices.Contains([]string{"udp", "tcp", "unix", "unixgram"}, u.Scheme),
+				"log.syslogAddress", "must be like udp://host:514, got %q", c.Log.SyslogAddress)
+		}
+	default:
+		check(false, "log.output", "must be stdout, stderr, file or syslog, got %q", c.Log.Output)
+	}
+	check(c.Log.MaxSizeMB >= 0, "log.maxSizeMB", "must not be negative, got %d", c.Log.MaxSizeMB)
+	check(c.Log.MaxBackups >= 0, "log.maxBackups", "must not be negative, got %d", c.Log.MaxBackups)
+	check(c.Log.SampleFirst >= 0, "log.sampleFirst", "must not be negative, got %d", c.Log.SampleFirst)
+	check(c.Log.SampleThereafter >= 0, "log.sampleThereafter", "must not be negative, got %d", c.Log.SampleThereafter)
+	check(c.Log.SampleFirst == 0 || c.Log.SampleInterval > 0, "log.sampleInterval",
+		"must be positive if sampling is enabled, got %s", c.Log.SampleInterval)
+
+	check(len(c.CORS.AllowOrigins) > 0, "cors.allowOrigins", "must not be empty")
+	check(!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowOrigins, "*"),
+		"cors.allowCredentials", "cannot be used with any origin allowed")
+	nonNegative("cors.maxAge", c.CORS.MaxAge)
+
+	for _, proxy := range c.Security.TrustedProxies {
+		_, prefixErr := netip.ParsePrefix(proxy)
+		_, addrErr := netip.ParseAddr(proxy)
+		check(prefixErr == nil || addrErr == nil, "security.trustedProxies", "%q is neither IP nor CIDR", proxy)
+	}
+	check(c.Security.MaxBodyBytes > 0, "security.maxBodyBytes", "must be positive, got %d", c.Security.MaxBodyBytes)
+
+	switch c.Database.Driver {
+	case "postgres":
+		check(c.Database.Host != "", "database.host", "must be set for postgres")
+		check(c.Database.Name != "", "database.name", "must be set for postgres")
+		check(c.Database.User != "", "database.user", "must be set for postgres")
+		check(c.Database.Port != "", "database.port", "must be set for postgres")
+	case "sqlite":
+		check(c.Database.Path != "", "database.path", "must be set for sqlite")
+		check(len(c.Database.Replicas) == 0, "database.replicas", "are not supported by sqlite")
+	default:
+		check(false, "database.driver", "must be postgres or sqlite, got %q", c.Database.Driver)
+	}
+	check(c.Database.MaxOpenConns >= 0, "database.maxOpenConns", "must not be negative, got %d", c.Database.MaxOpenConns)
+	check(c.Database.MaxIdleConns >= 0, "database.maxIdleConns", "must not be negative, got %d", c.Database.MaxIdleConns)
+	nonNegative("database.connMaxLifetime", c.Database.ConnMaxLifetime)
+	nonNegative("database.connMaxIdleTime", c.Database.ConnMaxIdleTime)
+	check(c.Database.ReplicaMaxLag > 0, "database.replicaMaxLag", "must be positive, got %s", c.Database.ReplicaMaxLag)
+	nonNegative("database.stickyWindow", c.Database.StickyWindow)
+
+	if c.GRPC.Address != "" {
+		_, _, err = net.SplitHostPort(c.GRPC.Address)
+		check(err == nil, "grpc.address", "must be host:port, got %q", c.GRPC.Address)
+	}
+
+	for _, network := range c.Webhook.AllowedNetworks {
+		_, prefixErr := netip.ParsePrefix(network)
+		_, addrErr := netip.ParseAddr(network)
+		check(prefixErr == nil || addrErr == nil, "webhook.allowedNetworks", "%q is neither IP nor CIDR", network)
+	}
 
+	switch c.Outbox.Publisher {
+	case "log":
+	case "http", "nats":
+		check(c.Outbox.URL != "", "outbox.url", "must be set for %s publisher", c.Outbox.Publisher)
+	default:
+		check(false, "outbox.publisher", "must be log, http or nats, got %q", c.Outbox.Publisher)
 	}
-	defer file.Close()
 
-	var cfg Config
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&cfg)
-	if err != nil {
-		return Config{}, fmt.Errorf("decoder.Decode: %w", err)
+	check(c.Idempotency.TTL > 0, "idempotency.ttl", "must be positive, got %s", c.Idempotency.TTL)
 
+	check(slices.Contains([]string{"memory", "database"}, c.RateLimit.Store),
+		"rateLimit.store", "must be memory or database, got %q", c.RateLimit.Store)
+	for _, policy := range c.RateLimit.Policies {
+		_, err := ratelimit.ParsePolicy(policy)
+		check(err == nil, "rateLimit.policies", "%q: %v", policy, err)
 	}
 
-	return cfg, err
+	return errors.Join(errs...)
 }
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAdminTokens(t *testing.T) {
+	tests := []struct {
+		address   string
+		tokens    []string
+		wantError bool
+	}{
+		{address: "127.0.0.1:6060"},
+		{address: "[::1]:6060"},
+		{address: "localhost:6060"},
+		{address: ""},
+		{address: "0.0.0.0:6060", wantError: true},
+		{address: "10.0.0.5:6060", wantError: true},
+		{address: "admin.internal:6060", wantError: true},
+		{address: "0.0.0.0:6060", tokens: []string{"secret"}},
+	}
+
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.Database.Driver = "sqlite"
+		cfg.Database.Path = "x.db"
+		cfg.Admin.Address = tt.address
+		cfg.Admin.Tokens = tt.tokens
+
+		err := cfg.Validate()
+		if got := err != nil && strings.Contains(err.Error(), "admin.tokens"); got != tt.wantError {
+			t.Errorf("address %q, tokens %v: error %v, want admin.tokens error %t", tt.address, tt.tokens, err, tt.wantError)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const envPrefix = "COMFORTEL_"
+
+// envAliases are env variables of postgres image, so app and database can share env file.
+// They are overridden by env variables of app.
+var envAliases = map[string]string{
+	"POSTGRES_HOST":     "database.host",
+	"POSTGRES_PORT":     "database.port",
+	"POSTGRES_DB":       "database.name",
+	"POSTGRES_USER":     "database.user",
+	"POSTGRES_PASSWORD": "database.password",
+}
+
+// ErrHelp is returned by Load if help was requested with -h flag.
+var ErrHelp = flag.ErrHelp
+
+// Load returns config built in layers, each overriding settings of previous one:
+// Default, config file, env variables and command line flags.
+//
+// Config file is taken from -config flag or COMFORTEL_CONFIG env variable, otherwise config.json from directory
+// of executable is used if it exists. Format is chosen by extension: .json, .yaml/.yml or .toml.
+// Each setting has env variable like COMFORTEL_DATABASE_MAX_OPEN_CONNS and flag like -database.maxOpenConns,
+// lists are comma separated.
+//
+// If -print-config flag is passed, Load writes config with secrets redacted to output and returns printOnly.
+func Load(args []string, output io.Writer) (cfg Config, printOnly bool, err error) {
+	cfg = Default()
+
+	fs := flag.NewFlagSet("comfortel", flag.ContinueOnError)
+	fs.SetOutput(output)
+	configPath := fs.String("config", os.Getenv(envPrefix+"CONFIG"), "config file (.json, .yaml, .yml or .toml)")
+	fs.BoolVar(&printOnly, "print-config", false, "print effective config with secrets redacted and exit")
+
+	// Flags are applied after file and env, so their values are kept until then.
+	type flagValue struct {
+		name  string
+		value string
+	}
+	var flagValues []flagValue
+	settings := settingsOf(&cfg)
+	for _, s := range settings {
+		usage := fmt.Sprintf("%s, env %s", s.name, s.env())
+		set := func(value string) error {
+			flagValues = append(flagValues, flagValue{name: s.name, value: value})
+			return nil
+		}
+		if s.value.Kind() == reflect.Bool {
+			fs.BoolFunc(s.name, usage, set)
+		} else {
+			fs.Func(s.name, usage, set)
+		}
+	}
+
+	if err = fs.Parse(args); err != nil {
+		return Config{}, false, err
+	}
+
+	if err = loadFile(&cfg, *configPath); err != nil {
+		return Config{}, false, fmt.Errorf("loadFile: %w", err)
+	}
+
+	// Settings are looked up again, because file decoding may have replaced slices.
+	settings = settingsOf(&cfg)
+	byName := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byName[s.name] = s
+	}
+
+	var errs []error
+	for env, name := range envAliases {
+		if value, ok := os.LookupEnv(env); ok {
+			if _, overridden := os.LookupEnv(byName[name].env()); !overridden {
+				errs = append(errs, byName[name].set(value, env))
+			}
+		}
+	}
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env()); ok {
+			errs = append(errs, s.set(value, s.env()))
+		}
+	}
+	for _, f := range flagValues {
+		errs = append(errs, byName[f.name].set(f.value, "-"+f.name))
+	}
+	if err = errors.Join(errs...); err != nil {
+		return Config{}, false, err
+	}
+
+	if printOnly {
+		if err = cfg.Print(output); err != nil {
+			return Config{}, false, fmt.Errorf("cfg.Print: %w", err)
+		}
+		return cfg, true, nil
+	}
+
+	if err = cfg.Validate(); err != nil {
+		return Config{}, false, fmt.Errorf("invalid config:\n%w", err)
+	}
+
+	return cfg, false, nil
+}
+
+// loadFile decodes config file over cfg. Missing default file is not an error.
+func loadFile(cfg *Config, path string) error {
+	explicit := path != ""
+	if !explicit {
+		exec, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("os.Executable: %w", err)
+		}
+		path = filepath.Join(filepath.Dir(exec), "config.json")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	// YAML and TOML are converted to JSON, so struct tags and Duration parsing are shared by all formats.
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+	case ".yaml", ".yml":
+		var raw map[string]any
+		if err = yaml.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("yaml.Unmarshal %s: %w", path, err)
+		}
+		if data, err = json.Marshal(raw); err != nil {
+			return fmt.Errorf("json.Marshal: %w", err)
+		}
+	case ".toml":
+		var raw map[string]any
+		if err = toml.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("toml.Unmarshal %s: %w", path, err)
+		}
+		if data, err = json.Marshal(raw); err != nil {
+			return fmt.Errorf("json.Marshal: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown config file format %q", ext)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("decode %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// setting is leaf field of Config named by json tags, like "database.maxOpenConns".
+type setting struct {
+	name  string
+	field reflect.StructField
+	value reflect.Value
+}
+
+func settingsOf(cfg *Config) []setting {
+	var settings []setting
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			name := prefix + strings.Split(field.Tag.Get("json"), ",")[0]
+			if field.Type.Kind() == reflect.Struct {
+				walk(name+".", v.Field(i))
+				continue
+			}
+			settings = append(settings, setting{name: name, field: field, value: v.Field(i)})
+		}
+	}
+	walk("", reflect.ValueOf(cfg).Elem())
+
+	return settings
+}
+
+// env returns name of env variable of setting: "database.maxOpenConns" is COMFORTEL_DATABASE_MAX_OPEN_CONNS.
+func (s setting) env() string {
+	var b strings.Builder
+	b.WriteString(envPrefix)
+	prev := '.'
+	for _, r := range s.name {
+		switch {
+		case r == '.':
+			b.WriteRune('_')
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			b.WriteRune('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		prev = r
+	}
+
+	return b.String()
+}
+
+// set parses value into setting, source names where value came from for error.
+func (s setting) set(value, source string) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("%s: invalid value %q from %s: %w", s.name, value, source, err)
+	}
+
+	if u, ok := s.value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(value)); err != nil {
+			return wrap(err)
+		}
+		return nil
+	}
+
+	switch s.value.Kind() {
+	case reflect.String:
+		s.value.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return wrap(err)
+		}
+		s.value.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return wrap(err)
+		}
+		s.value.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return wrap(err)
+		}
+		s.value.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		s.value.Set(reflect.ValueOf(items))
+	default:
+		return wrap(fmt.Errorf("unsupported kind %s", s.value.Kind()))
+	}
+
+	return nil
+}
+
+// Changed returns names of settings, like "database.maxOpenConns", which differ in c and other.
+func (c Config) Changed(other Config) []string {
+	otherSettings := settingsOf(&other)
+
+	var changed []string
+	for i, s := range settingsOf(&c) {
+		if !reflect.DeepEqual(s.value.Interface(), otherSettings[i].value.Interface()) {
+			changed = append(changed, s.name)
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+
+	return path
+}
+
+func TestLoadLayers(t *testing.T) {
+	path := writeFile(t, "config.json", `{
+		"http": {"address": "127.0.0.1:8080"},
+		"log": {"level": "debug", "format": "text"},
+		"database": {"name": "file", "user": "file"},
+		"scim": {"tokens": ["file"]}
+	}`)
+	t.Setenv("COMFORTEL_CONFIG", path)
+	t.Setenv("COMFORTEL_LOG_LEVEL", "warn")
+	t.Setenv("COMFORTEL_DATABASE_NAME", "env")
+	t.Setenv("COMFORTEL_DATABASE_MAX_OPEN_CONNS", "7")
+	t.Setenv("COMFORTEL_SCIM_TOKENS", "a, b")
+	t.Setenv("POSTGRES_HOST", "alias")
+	t.Setenv("POSTGRES_USER", "alias")
+	t.Setenv("POSTGRES_PASSWORD", "alias")
+	t.Setenv("COMFORTEL_DATABASE_PASSWORD", "env")
+
+	cfg, printOnly, err := Load([]string{"-database.name", "flag", "-cors.allowOrigins", "https://example.com", "-cors.allowCredentials"}, io.Discard)
+	if err != nil || printOnly {
+		t.Fatalf("Load: %v, print only %t", err, printOnly)
+	}
+
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{name: "default", got: cfg.HTTP.ReadTimeout, want: Duration(15 * time.Second)},
+		{name: "file over default", got: cfg.HTTP.Address, want: "127.0.0.1:8080"},
+		{name: "file over default", got: cfg.Log.Format, want: "text"},
+		{name: "env over file", got: cfg.Log.Level, want: "warn"},
+		{name: "env over default", got: cfg.Database.MaxOpenConns, want: 7},
+		{name: "env list", got: cfg.SCIM.Tokens, want: []string{"a", "b"}},
+		{name: "flag over env and file", got: cfg.Database.Name, want: "flag"},
+		{name: "bool flag", got: cfg.CORS.AllowCredentials, want: true},
+		{name: "alias over file", got: cfg.Database.User, want: "alias"},
+		{name: "env over alias", got: cfg.Database.Password, want: "env"},
+	}
+	for _, tt := range tests {
+		if got, ok := tt.got.([]string); ok {
+			if !slices.Equal(got, tt.want.([]string)) {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			}
+			continue
+		}
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadFormats(t *testing.T) {
+	for name, content := range map[string]string{
+		"config.yaml": "log:\n  level: error\ndatabase:\n  driver: sqlite\n  path: x.db\n  connMaxLifetime: 1m\n",
+		"config.toml": "[log]\nlevel = \"error\"\n[database]\ndriver = \"sqlite\"\npath = \"x.db\"\nconnMaxLifetime = \"1m\"\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg, _, err := Load([]string{"-config", writeFile(t, name, content)}, io.Discard)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Log.Level != "error" || cfg.Database.ConnMaxLifetime != Duration(time.Minute) {
+				t.Errorf("log.level %q, database.connMaxLifetime %s", cfg.Log.Level, cfg.Database.ConnMaxLifetime)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want []string
+	}{
+		{
+			name: "unknown setting in file",
+			file: `{"log": {"levle": "debug"}}`,
+			want: []string{"levle"},
+		},
+		{
+			name: "invalid env and flag values name their source",
+			env:  map[string]string{"COMFORTEL_DATABASE_MAX_OPEN_CONNS": "many"},
+			args: []string{"-http.idleTimeout", "long"},
+			want: []string{"from COMFORTEL_DATABASE_MAX_OPEN_CONNS", "from -http.idleTimeout"},
+		},
+		{
+			name: "invalid config",
+			args: []string{"-log.level", "loud", "-admin.address", "0.0.0.0:6060"},
+			want: []string{"log.level", "admin.tokens"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, "config.json", tt.file)}, args...)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, _, err := Load(args, io.Discard)
+			if err == nil {
+				t.Fatal("Load returned no error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+)
+
+const redacted = "REDACTED"
+
+// Redacted returns copy of config with settings tagged secret replaced. Secret "url" keeps URL but its user info.
+func (c Config) Redacted() Config {
+	// Slices are shared with c, so they are replaced instead of modified.
+	for _, s := range settingsOf(&c) {
+		switch s.field.Tag.Get("secret") {
+		case "true":
+			switch s.value.Kind() {
+			case reflect.String:
+				if s.value.String() != "" {
+					s.value.SetString(redacted)
+				}
+			case reflect.Slice:
+				if s.value.Len() == 0 {
+					continue
+				}
+				items := make([]string, s.value.Len())
+				for i := range items {
+					items[i] = redacted
+				}
+				s.value.Set(reflect.ValueOf(items))
+			}
+		case "url":
+			u, err := url.Parse(s.value.String())
+			if err != nil {
+				s.value.SetString(redacted)
+			} else if u.User != nil {
+				u.User = url.User(redacted)
+				s.value.SetString(u.String())
+			}
+		}
+	}
+
+	return c
+}
+
+// Print writes config with secrets redacted as JSON, which can be used as config file.
+func (c Config) Print(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return fmt.Errorf("encoder.Encode: %w", err)
+	}
+
+	return nil
+}
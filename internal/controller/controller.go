@@ -4,14 +4,17 @@ import (
 	"context"
 
 	"github.com/srgklmv/comfortel/internal/domain/user"
+	"github.com/srgklmv/comfortel/internal/domain/webhook"
 )
 
 type controller struct {
-	userUsecase userUsecase
+	userUsecase    userUsecase
+	webhookUsecase webhookUsecase
 }
 
 type usecase interface {
 	userUsecase
+	webhookUsecase
 }
 
 type userUsecase interface {
@@ -20,10 +23,23 @@ type userUsecase interface {
 	GetUsers(ctx context.Context) (any, int)
 	UpdateUser(ctx context.Context, id string, data user.UpdateUserRequestDTO) (any, int)
 	DeleteUser(ctx context.Context, id string) (any, int)
+	BatchUsers(ctx context.Context, data user.BatchRequestDTO) (any, int)
+	HashBatchPasswords(ctx context.Context, data user.BatchRequestDTO) context.Context
+}
+
+type webhookUsecase interface {
+	CreateSubscription(ctx context.Context, data webhook.CreateSubscriptionRequestDTO) (any, int)
+	GetSubscriptions(ctx context.Context) (any, int)
+	GetSubscription(ctx context.Context, id string) (any, int)
+	DeleteSubscription(ctx context.Context, id string) (any, int)
+	GetDeliveries(ctx context.Context, subscriptionID string, status string) (any, int)
+	GetDelivery(ctx context.Context, subscriptionID, deliveryID string) (any, int)
+	RedeliverDelivery(ctx context.Context, subscriptionID, deliveryID string) (any, int)
 }
 
 func New(uc usecase) *controller {
 	return &controller{
-		userUsecase: uc,
+		userUsecase:    uc,
+		webhookUsecase: uc,
 	}
 }
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	"github.com/srgklmv/comfortel/internal/middleware"
+	"github.com/srgklmv/comfortel/internal/repository"
+	usecaseImpl "github.com/srgklmv/comfortel/internal/usecase"
+	"github.com/srgklmv/comfortel/migrations"
+	"github.com/srgklmv/comfortel/pkg/database"
+	"github.com/srgklmv/comfortel/pkg/logger"
+	"github.com/srgklmv/comfortel/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// TestTracing serves request with sqlite repository and checks spans it leaves.
+// Global tracer provider can be set for packages only once, so this is the only test setting it.
+func TestTracing(t *testing.T) {
+	logger.SetDefault(slog.New(slog.DiscardHandler))
+	gin.SetMode(gin.TestMode)
+
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), "comfortel-test", 1))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	conn, err := database.NewSQLite(filepath.Join(t.TempDir(), "tracing.db"))
+	if err != nil {
+		t.Fatalf("database.NewSQLite: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Shutdown(conn) })
+	version, err := database.LatestVersion(migrations.FS, database.DriverSQLite)
+	if err != nil {
+		t.Fatalf("database.LatestVersion: %v", err)
+	}
+	if err = database.Migrate(conn, database.DriverSQLite, migrations.FS, version); err != nil {
+		t.Fatalf("database.Migrate: %v", err)
+	}
+
+	repo, transactor := repository.New(conn, database.DriverSQLite), repository.NewTransactor(conn)
+	id, err := repo.CreateUser(context.Background(), userDomain.User{Login: "alice"}, "hashed")
+	if err != nil {
+		t.Fatalf("repo.CreateUser: %v", err)
+	}
+
+	engine := gin.New()
+	engine.ContextWithFallback = true
+	engine.Use(middleware.Tracing())
+	engine.GET("/api/user/:id", middleware.Transaction(transactor, engine), New(usecaseImpl.New(repo, transactor)).GetUser)
+
+	const (
+		traceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentSpanID = "00f067aa0ba902b7"
+	)
+	req := httptest.NewRequest(http.MethodGet, "/api/user/"+id.String(), nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentSpanID+"-01")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, s := range exporter.GetSpans() {
+		spans[s.Name] = s
+	}
+	server, ok := spans["GET /api/user/:id"]
+	if !ok {
+		t.Fatalf("no server span among %v", spanNames(exporter))
+	}
+	uc, ok := spans["usecase.GetUserByID"]
+	if !ok {
+		t.Fatalf("no usecase span among %v", spanNames(exporter))
+	}
+	query, ok := spans["repository.GetUserByID"]
+	if !ok {
+		t.Fatalf("no query span among %v", spanNames(exporter))
+	}
+
+	// Trace of caller is continued.
+	if got := server.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("trace id %s, want %s of traceparent", got, traceID)
+	}
+	if got := server.Parent.SpanID().String(); got != parentSpanID || !server.Parent.IsRemote() {
+		t.Errorf("server span parent %s, want remote %s", got, parentSpanID)
+	}
+
+	if uc.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("usecase span is not child of server span")
+	}
+	if query.Parent.SpanID() != uc.SpanContext.SpanID() {
+		t.Errorf("query span is not child of usecase span")
+	}
+
+	// Query span has statement, but not its arguments.
+	var statement string
+	for _, attr := range query.Attributes {
+		if attr.Key == semconv.DBQueryTextKey {
+			statement = attr.Value.AsString()
+		}
+		if strings.Contains(attr.Value.Emit(), id.String()) {
+			t.Errorf("attribute %s has argument of query: %s", attr.Key, attr.Value.Emit())
+		}
+	}
+	if !strings.Contains(strings.ToLower(statement), "select") {
+		t.Errorf("statement %q is not text of query", statement)
+	}
+}
+
+func spanNames(exporter *tracetest.InMemoryExporter) []string {
+	var names []string
+	for _, s := range exporter.GetSpans() {
+		names = append(names, s.Name)
+	}
+
+	return names
+}
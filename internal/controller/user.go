@@ -1,11 +1,15 @@
 package controller
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/srgklmv/comfortel/internal/domain/apperror"
 	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	"github.com/srgklmv/comfortel/internal/middleware"
 )
 
 func (c controller) CreateUser(gc *gin.Context) {
@@ -21,12 +25,16 @@ func (c controller) CreateUser(gc *gin.Context) {
 	}
 
 	response, status := c.userUsecase.CreateUser(gc, body)
+	if created, ok := response.(userDomain.CreateUserResponseDTO); ok {
+		middleware.SetUserID(gc, created.Created)
+	}
 
 	gc.JSON(status, response)
 }
 
 func (c controller) GetUser(gc *gin.Context) {
 	id := gc.Param("id")
+	middleware.SetUserID(gc, id)
 
 	response, status := c.userUsecase.GetUserByID(gc, id)
 
@@ -41,6 +49,7 @@ func (c controller) GetUsers(gc *gin.Context) {
 
 func (c controller) UpdateUser(gc *gin.Context) {
 	id := gc.Param("id")
+	middleware.SetUserID(gc, id)
 
 	var dto userDomain.UpdateUserRequestDTO
 	err := gc.ShouldBindJSON(&dto)
@@ -60,8 +69,63 @@ func (c controller) UpdateUser(gc *gin.Context) {
 
 func (c controller) DeleteUser(gc *gin.Context) {
 	id := gc.Param("id")
+	middleware.SetUserID(gc, id)
 
 	response, status := c.userUsecase.DeleteUser(gc, id)
 
 	gc.JSON(status, response)
 }
+
+func (c controller) BatchUsers(gc *gin.Context) {
+	var body userDomain.BatchRequestDTO
+	err := gc.ShouldBindJSON(&body)
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.BadRequestErrorText,
+			Message: "Request body invalid.",
+		})
+		return
+	}
+
+	response, status := c.userUsecase.BatchUsers(gc, body)
+
+	gc.JSON(status, response)
+}
+
+// PrepareBatchUsers hashes passwords of batch before transaction is opened, as hashing is slow and
+// transaction would hold locks meanwhile, so it goes before Transaction middleware. Invalid body is
+// left to BatchUsers to reject.
+func (c controller) PrepareBatchUsers(gc *gin.Context) {
+	if body, ok := peekBatch(gc); ok {
+		gc.Request = gc.Request.WithContext(c.userUsecase.HashBatchPasswords(gc.Request.Context(), body))
+	}
+
+	gc.Next()
+}
+
+// BatchCost returns number of operations of batch request, so rate limit counts each of them.
+func BatchCost(gc *gin.Context) int {
+	body, ok := peekBatch(gc)
+	if !ok {
+		return 1
+	}
+
+	return len(body.Operations)
+}
+
+// peekBatch decodes batch from body and puts body back for handlers after.
+func peekBatch(gc *gin.Context) (userDomain.BatchRequestDTO, bool) {
+	raw, err := io.ReadAll(gc.Request.Body)
+	gc.Request.Body = io.NopCloser(bytes.NewReader(raw))
+	if err != nil {
+		return userDomain.BatchRequestDTO{}, false
+	}
+
+	var body userDomain.BatchRequestDTO
+	if err = json.Unmarshal(raw, &body); err != nil {
+		return userDomain.BatchRequestDTO{}, false
+	}
+
+	return body, true
+}
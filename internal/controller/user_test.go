@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	"github.com/srgklmv/comfortel/internal/middleware"
+	"github.com/srgklmv/comfortel/internal/repository/memory"
+	usecaseImpl "github.com/srgklmv/comfortel/internal/usecase"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+type server struct {
+	engine *gin.Engine
+	repo   interface {
+		CreateUser(ctx context.Context, data userDomain.User, hashedPassword string) (uuid.UUID, error)
+	}
+}
+
+// newServer serves user routes like api does, with usecases over in-memory repository.
+func newServer(t *testing.T) *server {
+	t.Helper()
+	logger.SetDefault(slog.New(slog.DiscardHandler))
+	gin.SetMode(gin.TestMode)
+
+	mem := memory.New()
+	c := New(usecaseImpl.New(mem, mem))
+
+	engine := gin.New()
+	// Handlers pass gin context to usecases, which find transaction of request through it.
+	engine.ContextWithFallback = true
+	tx := middleware.Transaction(mem, engine)
+	user := engine.Group("/api/user", tx)
+	user.POST("", c.CreateUser)
+	user.GET("/:id", c.GetUser)
+	user.DELETE("/:id", c.DeleteUser)
+	user.PATCH("/:id", c.UpdateUser)
+	engine.Group("/api/user", c.PrepareBatchUsers, tx).POST("/batch", c.BatchUsers)
+
+	return &server{engine: engine, repo: mem}
+}
+
+// seed stores user directly, as creating one through API hashes password for about a second.
+func (s *server) seed(t *testing.T, login string) uuid.UUID {
+	t.Helper()
+
+	id, err := s.repo.CreateUser(context.Background(), userDomain.User{Login: login, FirstName: "First"}, "hashed")
+	if err != nil {
+		t.Fatalf("repo.CreateUser: %v", err)
+	}
+
+	return id
+}
+
+func (s *server) do(method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+
+	return w
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("json.Unmarshal %q: %v", w.Body, err)
+	}
+
+	return v
+}
+
+func TestCreateUserRejectsBadRequests(t *testing.T) {
+	s := newServer(t)
+	s.seed(t, "alice")
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "malformed body", body: `{"login":`, status: http.StatusBadRequest},
+		{name: "invalid login", body: `{"login":"a","password":"password1"}`, status: http.StatusBadRequest},
+		{name: "taken login", body: `{"login":"ALICE","password":"password1"}`, status: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := s.do(http.MethodPost, "/api/user", tt.body); w.Code != tt.status {
+				t.Errorf("status %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	s := newServer(t)
+	id := s.seed(t, "alice")
+
+	w := s.do(http.MethodGet, "/api/user/"+id.String(), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	if got := decode[userDomain.GetUserDTO](t, w); got.ID != id.String() || got.Login != "alice" {
+		t.Errorf("got %+v", got)
+	}
+
+	if w = s.do(http.MethodGet, "/api/user/"+uuid.NewString(), ""); w.Code != http.StatusNotFound {
+		t.Errorf("missing user: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w = s.do(http.MethodGet, "/api/user/not-uuid", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid id: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestUpdateAndDeleteUser(t *testing.T) {
+	s := newServer(t)
+	id := s.seed(t, "alice")
+	path := "/api/user/" + id.String()
+
+	w := s.do(http.MethodPatch, path, `{"firstName":"Changed","isActive":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH: status %d, body %s", w.Code, w.Body)
+	}
+	if got := decode[userDomain.GetUserDTO](t, s.do(http.MethodGet, path, "")); got.FirstName != "Changed" {
+		t.Errorf("update is not saved: %+v", got)
+	}
+
+	if w = s.do(http.MethodDelete, path, ""); w.Code != http.StatusOK {
+		t.Fatalf("DELETE: status %d, body %s", w.Code, w.Body)
+	}
+	if w = s.do(http.MethodGet, path, ""); w.Code != http.StatusNotFound {
+		t.Errorf("deleted user: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestBatchUsersRollsBackAtomicBatch(t *testing.T) {
+	s := newServer(t)
+	id := s.seed(t, "alice")
+
+	body := `{"operations":[
+		{"op":"update","id":"` + id.String() + `","data":{"firstName":"Changed","isActive":true}},
+		{"op":"delete","id":"` + uuid.NewString() + `"}
+	]}`
+	w := s.do(http.MethodPost, "/api/user/batch", body)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status %d, want %d, body %s", w.Code, http.StatusNotFound, w.Body)
+	}
+	if got := decode[userDomain.BatchResponseDTO](t, w); got.Committed || len(got.Results) != 2 {
+		t.Errorf("got %+v, want 2 results of uncommitted batch", got)
+	}
+
+	if got := decode[userDomain.GetUserDTO](t, s.do(http.MethodGet, "/api/user/"+id.String(), "")); got.FirstName != "First" {
+		t.Errorf("update of rolled back batch is saved: %+v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	webhookDomain "github.com/srgklmv/comfortel/internal/domain/webhook"
+)
+
+func (c controller) CreateSubscription(gc *gin.Context) {
+	var body webhookDomain.CreateSubscriptionRequestDTO
+	err := gc.ShouldBindJSON(&body)
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.BadRequestErrorText,
+			Message: "Request body invalid.",
+		})
+		return
+	}
+
+	response, status := c.webhookUsecase.CreateSubscription(gc, body)
+
+	gc.JSON(status, response)
+}
+
+func (c controller) GetSubscriptions(gc *gin.Context) {
+	response, status := c.webhookUsecase.GetSubscriptions(gc)
+
+	gc.JSON(status, response)
+}
+
+func (c controller) GetSubscription(gc *gin.Context) {
+	response, status := c.webhookUsecase.GetSubscription(gc, gc.Param("id"))
+
+	gc.JSON(status, response)
+}
+
+func (c controller) DeleteSubscription(gc *gin.Context) {
+	response, status := c.webhookUsecase.DeleteSubscription(gc, gc.Param("id"))
+
+	gc.JSON(status, response)
+}
+
+func (c controller) GetDeliveries(gc *gin.Context) {
+	response, status := c.webhookUsecase.GetDeliveries(gc, gc.Param("id"), gc.Query("status"))
+
+	gc.JSON(status, response)
+}
+
+func (c controller) GetDelivery(gc *gin.Context) {
+	response, status := c.webhookUsecase.GetDelivery(gc, gc.Param("id"), gc.Param("deliveryID"))
+
+	gc.JSON(status, response)
+}
+
+func (c controller) RedeliverDelivery(gc *gin.Context) {
+	response, status := c.webhookUsecase.RedeliverDelivery(gc, gc.Param("id"), gc.Param("deliveryID"))
+
+	gc.JSON(status, response)
+}
@@ -4,11 +4,14 @@ type errorText string
 
 // Common errors.
 const (
-	InternalErrorText   errorText = "Internal error."
-	BadRequestErrorText errorText = "Bad request."
+	InternalErrorText        errorText = "Internal error."
+	BadRequestErrorText      errorText = "Bad request."
+	UnauthorizedErrorText    errorText = "Unauthorized."
+	TooManyRequestsErrorText errorText = "Too many requests."
 )
 
 // User errors.
 const (
 	LoginTakenErrorText errorText = "Login is already taken."
+	EmailTakenErrorText errorText = "Email is already taken."
 )
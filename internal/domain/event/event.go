@@ -0,0 +1,40 @@
+package event
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event describes change of domain entity. Data is serialized to JSON as is.
+type Event struct {
+	ID          uuid.UUID `json:"id"`
+	Type        string    `json:"type"`
+	AggregateID uuid.UUID `json:"aggregateId"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Data        any       `json:"data"`
+}
+
+func New(eventType string, aggregateID uuid.UUID, data any) Event {
+	return Event{
+		ID:          uuid.New(),
+		Type:        eventType,
+		AggregateID: aggregateID,
+		CreatedAt:   time.Now().UTC(),
+		Data:        data,
+	}
+}
+
+// Record is event stored in outbox. Seq orders events by insertion, Position orders them by commit.
+// Position is assigned by outbox relay once event is committed, so readers going by it never skip
+// event committed late.
+type Record struct {
+	Seq         int64
+	Position    int64
+	ID          uuid.UUID
+	Type        string
+	AggregateID uuid.UUID
+	Payload     []byte
+	Attempts    int
+	CreatedAt   time.Time
+}
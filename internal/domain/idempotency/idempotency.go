@@ -0,0 +1,25 @@
+package idempotency
+
+import "time"
+
+// Header is request header carrying idempotency key.
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed from stored ones.
+const ReplayedHeader = "Idempotent-Replayed"
+
+// MaxKeyLength is limit of key length in storage.
+const MaxKeyLength = 255
+
+// Key is idempotency key stored with first response to request. Completed is false while
+// first request is still processed.
+type Key struct {
+	Caller      string
+	Key         string
+	RequestHash string
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+}
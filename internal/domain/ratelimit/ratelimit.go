@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Response headers, see draft-ietf-httpapi-ratelimit-headers.
+const (
+	HeaderLimit      = "RateLimit-Limit"
+	HeaderRemaining  = "RateLimit-Remaining"
+	HeaderReset      = "RateLimit-Reset"
+	HeaderPolicy     = "RateLimit-Policy"
+	HeaderRetryAfter = "Retry-After"
+	// HeaderAPIKey carries API key of client, bearer token is used as API key too.
+	HeaderAPIKey = "X-API-Key"
+)
+
+// Keys requests are counted by.
+const (
+	// KeyIP counts requests by client IP, which is taken from X-Forwarded-For only behind trusted proxies.
+	KeyIP = "ip"
+	// KeyUser counts requests by principal, which is caller authenticated by bearer token of API like SCIM.
+	// Requests without principal are counted by IP.
+	KeyUser = "user"
+	// KeyAPIKey counts requests by API key or bearer token if it is one app knows. Requests with unknown
+	// key or without one are counted by IP.
+	KeyAPIKey = "apikey"
+)
+
+// Any matches any method or route of policy.
+const Any = "*"
+
+// Policy is token bucket of Limit tokens, which is refilled at rate of Limit tokens per Period.
+// Each request matching Method and Route takes token from bucket of its key, and is rejected if there is none.
+type Policy struct {
+	Method string
+	Route  string
+	Key    string
+	Limit  int
+	Period time.Duration
+}
+
+// ParsePolicy parses policy written as "<method> <route> <key> <limit>/<period>", e.g. "POST /api/user ip 10/1m".
+// Route is template of route like "/api/user/:id". Method and route may be "*" to match any, period may omit 1,
+// like in "10/m".
+func ParsePolicy(s string) (Policy, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 4 {
+		return Policy{}, errors.New(`must be "<method> <route> <key> <limit>/<period>"`)
+	}
+
+	p := Policy{
+		Method: strings.ToUpper(fields[0]),
+		Route:  fields[1],
+		Key:    fields[2],
+	}
+	if p.Route != Any && !strings.HasPrefix(p.Route, "/") {
+		return Policy{}, fmt.Errorf("route must start with / or be *, got %q", p.Route)
+	}
+	if p.Key != KeyIP && p.Key != KeyUser && p.Key != KeyAPIKey {
+		return Policy{}, fmt.Errorf("key must be ip, user or apikey, got %q", p.Key)
+	}
+
+	limit, period, ok := strings.Cut(fields[3], "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate must be <limit>/<period>, got %q", fields[3])
+	}
+	var err error
+	if p.Limit, err = strconv.Atoi(limit); err != nil || p.Limit <= 0 {
+		return Policy{}, fmt.Errorf("limit must be positive integer, got %q", limit)
+	}
+	if p.Period, err = time.ParseDuration(period); err != nil {
+		if p.Period, err = time.ParseDuration("1" + period); err != nil {
+			return Policy{}, fmt.Errorf("period must be duration like 1m, got %q", period)
+		}
+	}
+	if p.Period <= 0 {
+		return Policy{}, fmt.Errorf("period must be positive, got %q", period)
+	}
+
+	return p, nil
+}
+
+func (p Policy) String() string {
+	return fmt.Sprintf("%s %s %s %d/%s", p.Method, p.Route, p.Key, p.Limit, p.Period)
+}
+
+// Interval returns time in which one token is refilled.
+func (p Policy) Interval() time.Duration {
+	return p.Period / time.Duration(p.Limit)
+}
+
+// Matches reports whether policy applies to request of method to route template.
+func (p Policy) Matches(method, route string) bool {
+	return (p.Method == Any || p.Method == method) && (p.Route == Any || p.Route == route)
+}
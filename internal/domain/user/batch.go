@@ -0,0 +1,95 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Batch modes. In atomic mode batch stops on first failed operation and nothing is saved,
+// in best-effort mode failed operations are rolled back one by one and the rest is saved.
+const (
+	BatchModeAtomic     = "atomic"
+	BatchModeBestEffort = "best_effort"
+)
+
+// Batch operations.
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+// MaxBatchSize bounds batch, which hashes password of every created user.
+const MaxBatchSize = 100
+
+type BatchRequestDTO struct {
+	Mode       string              `json:"mode"`
+	Operations []BatchOperationDTO `json:"operations"`
+}
+
+// BatchOperationDTO is single operation of batch. Data is CreateUserRequestDTO for create
+// and UpdateUserRequestDTO for update, ID is required for update and delete.
+type BatchOperationDTO struct {
+	Op   string          `json:"op"`
+	ID   string          `json:"id,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// BatchResultDTO is result of operation with same index. Body is what single operation endpoint returns.
+// Status is 424 for operations not executed because of previous failure in atomic mode.
+type BatchResultDTO struct {
+	Status int `json:"status"`
+	Body   any `json:"body,omitempty"`
+}
+
+type BatchResponseDTO struct {
+	Committed bool             `json:"committed"`
+	Results   []BatchResultDTO `json:"results"`
+}
+
+func (dto BatchRequestDTO) Validate() (validationError error) {
+	if dto.Mode != "" && dto.Mode != BatchModeAtomic && dto.Mode != BatchModeBestEffort {
+		validationError = errors.Join(validationError, errors.New("invalid mode"))
+	}
+
+	if len(dto.Operations) == 0 || len(dto.Operations) > MaxBatchSize {
+		validationError = errors.Join(validationError, fmt.Errorf("number of operations must be between 1 and %d", MaxBatchSize))
+	}
+
+	for i, op := range dto.Operations {
+		switch op.Op {
+		case BatchOpCreate:
+		case BatchOpUpdate, BatchOpDelete:
+			if op.ID == "" {
+				validationError = errors.Join(validationError, fmt.Errorf("operation %d: id is required", i))
+			}
+		default:
+			validationError = errors.Join(validationError, fmt.Errorf("operation %d: invalid op", i))
+		}
+	}
+
+	return validationError
+}
+
+func (dto BatchRequestDTO) IsAtomic() bool {
+	return dto.Mode != BatchModeBestEffort
+}
+
+func (dto BatchOperationDTO) CreateData() (CreateUserRequestDTO, error) {
+	var data CreateUserRequestDTO
+	if err := json.Unmarshal(dto.Data, &data); err != nil {
+		return CreateUserRequestDTO{}, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return data, nil
+}
+
+func (dto BatchOperationDTO) UpdateData() (UpdateUserRequestDTO, error) {
+	var data UpdateUserRequestDTO
+	if err := json.Unmarshal(dto.Data, &data); err != nil {
+		return UpdateUserRequestDTO{}, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return data, nil
+}
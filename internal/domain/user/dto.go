@@ -8,10 +8,24 @@ import (
 	"time"
 )
 
+// Validation constraints. Exported to be reused in API documentation.
 const (
-	loginRegex    = "^[a-zA-Z0-9]{5,20}$"
-	passwordRegex = "^[a-zA-Z0-9!&*.,#@$]{8,20}$"
-	emailRegex    = `^[\w-\.]+@([\w-]+\.)+[\w-]{2,4}$`
+	LoginPattern    = "^[a-zA-Z0-9]{5,20}$"
+	PasswordPattern = "^[a-zA-Z0-9!&*.,#@$]{8,20}$"
+	EmailPattern    = `^[\w-\.]+@([\w-]+\.)+[\w-]{2,4}$`
+
+	// ProvisionedLoginPattern is looser pattern of logins of provisioned users, which are userNames
+	// of identity provider, typically emails.
+	ProvisionedLoginPattern = "^[a-zA-Z0-9][a-zA-Z0-9._%+@-]{0,254}$"
+
+	MaxNameLength = 20
+	MaxAge        = 150
+
+	SexMale   = "male"
+	SexFemale = "female"
+
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
 )
 
 type CreateUserRequestDTO struct {
@@ -24,10 +38,17 @@ type CreateUserRequestDTO struct {
 	Age        uint8  `json:"age"`
 	Password   string `json:"password"`
 	AvatarURL  string `json:"avatarURL"`
+	// Provisioned is set for users created by identity provider through SCIM, their login is checked
+	// with ProvisionedLoginPattern.
+	Provisioned bool `json:"-"`
 }
 
 func (dto CreateUserRequestDTO) Validate() (validationError error, err error) {
-	matched, err := regexp.MatchString(loginRegex, dto.Login)
+	loginPattern := LoginPattern
+	if dto.Provisioned {
+		loginPattern = ProvisionedLoginPattern
+	}
+	matched, err := regexp.MatchString(loginPattern, dto.Login)
 	if err != nil {
 		return nil, fmt.Errorf("regexp.MatchString: %w", err)
 	}
@@ -35,7 +56,7 @@ func (dto CreateUserRequestDTO) Validate() (validationError error, err error) {
 		validationError = errors.Join(validationError, errors.New("invalid login"))
 	}
 
-	matched, err = regexp.MatchString(passwordRegex, dto.Password)
+	matched, err = regexp.MatchString(PasswordPattern, dto.Password)
 	if err != nil {
 		return nil, fmt.Errorf("regexp.MatchString: %w", err)
 	}
@@ -43,7 +64,7 @@ func (dto CreateUserRequestDTO) Validate() (validationError error, err error) {
 		validationError = errors.Join(validationError, errors.New("invalid password"))
 	}
 
-	matched, err = regexp.MatchString(emailRegex, dto.Email)
+	matched, err = regexp.MatchString(EmailPattern, dto.Email)
 	if err != nil {
 		return nil, fmt.Errorf("regexp.MatchString: %w", err)
 	}
@@ -51,15 +72,15 @@ func (dto CreateUserRequestDTO) Validate() (validationError error, err error) {
 		validationError = errors.Join(validationError, errors.New("invalid email"))
 	}
 
-	if dto.Sex != "" && dto.Sex != "male" && dto.Sex != "female" {
+	if dto.Sex != "" && dto.Sex != SexMale && dto.Sex != SexFemale {
 		validationError = errors.Join(validationError, errors.New("invalid sex"))
 	}
 
-	if dto.Age > 150 {
+	if dto.Age > MaxAge {
 		validationError = errors.Join(validationError, errors.New("age may be exaggerated a little bit"))
 	}
 
-	if len([]byte(dto.FirstName)) > 20 || len([]byte(dto.LastName)) > 20 || len([]byte(dto.MiddleName)) > 20 {
+	if len([]byte(dto.FirstName)) > MaxNameLength || len([]byte(dto.LastName)) > MaxNameLength || len([]byte(dto.MiddleName)) > MaxNameLength {
 		validationError = errors.Join(validationError, errors.New("too long name"))
 	}
 
@@ -75,7 +96,8 @@ func (dto CreateUserRequestDTO) Validate() (validationError error, err error) {
 
 func (dto CreateUserRequestDTO) ToDomain() User {
 	return User{
-		Login:      dto.Login,
+		Login:      NormalizeLogin(dto.Login),
+		Email:      NormalizeEmail(dto.Email),
 		FirstName:  dto.FirstName,
 		LastName:   dto.LastName,
 		MiddleName: dto.MiddleName,
@@ -95,6 +117,7 @@ type GetUserDTO struct {
 	Sex          string `json:"sex,omitempty"`
 	Age          uint8  `json:"age,omitempty"`
 	AvatarURL    string `json:"avatarURL,omitempty"`
+	IsActive     bool   `json:"isActive"`
 	RegisterDate string `json:"registerDate"`
 }
 
@@ -109,6 +132,7 @@ func (dto GetUserDTO) FromDomain(u User) GetUserDTO {
 		Sex:          u.Sex,
 		Age:          u.Age,
 		AvatarURL:    u.AvatarURL,
+		IsActive:     u.IsActive,
 		RegisterDate: u.CreatedAt.Format(time.DateOnly),
 	}
 }
@@ -123,10 +147,11 @@ type UpdateUserRequestDTO struct {
 	MiddleName string `json:"middleName"`
 	Email      string `json:"email"`
 	AvatarURL  string `json:"avatarURL"`
+	IsActive   *bool  `json:"isActive"`
 }
 
 func (dto UpdateUserRequestDTO) Validate() (validationError error, err error) {
-	matched, err := regexp.MatchString(emailRegex, dto.Email)
+	matched, err := regexp.MatchString(EmailPattern, dto.Email)
 	if err != nil {
 		return nil, fmt.Errorf("regexp.MatchString: %w", err)
 	}
@@ -134,7 +159,7 @@ func (dto UpdateUserRequestDTO) Validate() (validationError error, err error) {
 		validationError = errors.Join(validationError, errors.New("invalid email"))
 	}
 
-	if len([]byte(dto.FirstName)) > 20 || len([]byte(dto.LastName)) > 20 || len([]byte(dto.MiddleName)) > 20 {
+	if len([]byte(dto.FirstName)) > MaxNameLength || len([]byte(dto.LastName)) > MaxNameLength || len([]byte(dto.MiddleName)) > MaxNameLength {
 		validationError = errors.Join(validationError, errors.New("too long name"))
 	}
 
@@ -151,3 +176,47 @@ func (dto UpdateUserRequestDTO) Validate() (validationError error, err error) {
 type DeleteUserResponseDTO struct {
 	Deleted string `json:"deleted"`
 }
+
+type SearchUsersRequestDTO struct {
+	Login  string `json:"login"`
+	Email  string `json:"email"`
+	Sex    string `json:"sex"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+func (dto SearchUsersRequestDTO) Validate() (validationError error) {
+	if dto.Sex != "" && dto.Sex != SexMale && dto.Sex != SexFemale {
+		validationError = errors.Join(validationError, errors.New("invalid sex"))
+	}
+
+	if dto.Limit < 0 || dto.Limit > MaxSearchLimit {
+		validationError = errors.Join(validationError, fmt.Errorf("limit must be between 0 and %d", MaxSearchLimit))
+	}
+
+	if dto.Offset < 0 {
+		validationError = errors.Join(validationError, errors.New("negative offset"))
+	}
+
+	return validationError
+}
+
+func (dto SearchUsersRequestDTO) ToDomain() Filter {
+	limit := dto.Limit
+	if limit == 0 {
+		limit = DefaultSearchLimit
+	}
+
+	return Filter{
+		Login:  NormalizeLogin(dto.Login),
+		Email:  NormalizeEmail(dto.Email),
+		Sex:    dto.Sex,
+		Limit:  limit,
+		Offset: dto.Offset,
+	}
+}
+
+type UsersPageDTO struct {
+	Users []GetUserDTO `json:"users"`
+	Total int          `json:"total"`
+}
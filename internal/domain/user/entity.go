@@ -52,6 +52,7 @@ func EntityFromDomain(u User) Entity {
 	if u.AvatarURL != "" {
 		e.AvatarURL = &u.AvatarURL
 	}
+	e.IsActive = &u.IsActive
 
 	return e
 }
@@ -67,6 +68,7 @@ func (e Entity) ToDomain() User {
 		Age:        pointer.ParsePointer(e.Age),
 		Email:      pointer.ParsePointer(e.Email),
 		AvatarURL:  pointer.ParsePointer(e.AvatarURL),
+		IsActive:   pointer.ParsePointer(e.IsActive),
 		CreatedAt:  pointer.ParsePointer(e.CreatedAt),
 		UpdatedAt:  pointer.ParsePointer(e.UpdatedAt),
 	}
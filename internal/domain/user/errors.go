@@ -0,0 +1,8 @@
+package user
+
+import "errors"
+
+var (
+	ErrLoginTaken = errors.New("login is already taken")
+	ErrEmailTaken = errors.New("email is already taken")
+)
@@ -0,0 +1,11 @@
+package user
+
+// Event types of user lifecycle.
+const (
+	EventCreated   = "user.created"
+	EventUpdated   = "user.updated"
+	EventDeleted   = "user.deleted"
+	EventActivated = "user.activated"
+)
+
+var EventTypes = []string{EventCreated, EventUpdated, EventDeleted, EventActivated}
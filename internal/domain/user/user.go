@@ -1,6 +1,7 @@
 package user
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,10 +17,21 @@ type User struct {
 	Age        uint8
 	Email      string
 	AvatarURL  string
+	IsActive   bool
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 }
 
+// Filter narrows users search. Empty fields are ignored.
+type Filter struct {
+	// Login is matched as case-insensitive substring.
+	Login  string
+	Email  string
+	Sex    string
+	Limit  int
+	Offset int
+}
+
 func (u *User) Update(dto UpdateUserRequestDTO) {
 	if dto.FirstName != "" {
 		u.FirstName = dto.FirstName
@@ -31,9 +43,22 @@ func (u *User) Update(dto UpdateUserRequestDTO) {
 		u.MiddleName = dto.MiddleName
 	}
 	if dto.Email != "" {
-		u.Email = dto.Email
+		u.Email = NormalizeEmail(dto.Email)
 	}
 	if dto.AvatarURL != "" {
 		u.AvatarURL = dto.AvatarURL
 	}
+	if dto.IsActive != nil {
+		u.IsActive = *dto.IsActive
+	}
+}
+
+// NormalizeLogin returns canonical form of login used for storage and lookups.
+func NormalizeLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
+
+// NormalizeEmail returns canonical form of email used for storage and lookups.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
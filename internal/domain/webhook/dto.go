@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/srgklmv/comfortel/internal/domain/user"
+)
+
+type CreateSubscriptionRequestDTO struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret is generated if empty.
+	Secret string `json:"secret"`
+}
+
+func (dto CreateSubscriptionRequestDTO) Validate() (validationError error) {
+	u, err := url.Parse(dto.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		validationError = errors.Join(validationError, errors.New("invalid url"))
+	}
+
+	if len(dto.Events) == 0 {
+		validationError = errors.Join(validationError, errors.New("no events passed"))
+	}
+	for _, e := range dto.Events {
+		if !slices.Contains(user.EventTypes, e) {
+			validationError = errors.Join(validationError, fmt.Errorf("unknown event %q", e))
+		}
+	}
+
+	if dto.Secret != "" && len(dto.Secret) < 16 {
+		validationError = errors.Join(validationError, errors.New("secret is too short"))
+	}
+
+	return validationError
+}
+
+func (dto CreateSubscriptionRequestDTO) ToDomain() Subscription {
+	return Subscription{
+		URL:      dto.URL,
+		Secret:   dto.Secret,
+		Events:   dto.Events,
+		IsActive: true,
+	}
+}
+
+// CreateSubscriptionResponseDTO is the only response containing secret.
+type CreateSubscriptionResponseDTO struct {
+	ID     string `json:"id"`
+	Secret string `json:"secret"`
+}
+
+type GetSubscriptionDTO struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	IsActive  bool     `json:"isActive"`
+	CreatedAt string   `json:"createdAt"`
+}
+
+func (dto GetSubscriptionDTO) FromDomain(s Subscription) GetSubscriptionDTO {
+	return GetSubscriptionDTO{
+		ID:        s.ID.String(),
+		URL:       s.URL,
+		Events:    s.Events,
+		IsActive:  s.IsActive,
+		CreatedAt: s.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+type DeleteSubscriptionResponseDTO struct {
+	Deleted string `json:"deleted"`
+}
+
+type GetDeliveryDTO struct {
+	ID            string          `json:"id"`
+	EventID       string          `json:"eventId"`
+	EventType     string          `json:"eventType"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt string          `json:"nextAttemptAt,omitempty"`
+	LastError     string          `json:"lastError,omitempty"`
+	CreatedAt     string          `json:"createdAt"`
+	DeliveredAt   string          `json:"deliveredAt,omitempty"`
+	Log           []GetAttemptDTO `json:"log,omitempty"`
+}
+
+func (dto GetDeliveryDTO) FromDomain(d Delivery, attempts []Attempt) GetDeliveryDTO {
+	result := GetDeliveryDTO{
+		ID:        d.ID.String(),
+		EventID:   d.EventID.String(),
+		EventType: d.EventType,
+		Status:    d.Status,
+		Attempts:  d.Attempts,
+		LastError: d.LastError,
+		CreatedAt: d.CreatedAt.Format(time.RFC3339),
+	}
+	if d.Status == StatusPending {
+		result.NextAttemptAt = d.NextAttemptAt.Format(time.RFC3339)
+	}
+	if d.DeliveredAt != nil {
+		result.DeliveredAt = d.DeliveredAt.Format(time.RFC3339)
+	}
+	for _, a := range attempts {
+		result.Log = append(result.Log, GetAttemptDTO{}.FromDomain(a))
+	}
+
+	return result
+}
+
+type GetAttemptDTO struct {
+	StatusCode  int    `json:"statusCode,omitempty"`
+	Error       string `json:"error,omitempty"`
+	DurationMS  int64  `json:"durationMs"`
+	AttemptedAt string `json:"attemptedAt"`
+}
+
+func (dto GetAttemptDTO) FromDomain(a Attempt) GetAttemptDTO {
+	return GetAttemptDTO{
+		StatusCode:  a.StatusCode,
+		Error:       a.Error,
+		DurationMS:  a.Duration.Milliseconds(),
+		AttemptedAt: a.AttemptedAt.Format(time.RFC3339),
+	}
+}
+
+type RedeliverResponseDTO struct {
+	Redelivered string `json:"redelivered"`
+}
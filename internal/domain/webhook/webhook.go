@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Delivery statuses. Dead deliveries exhausted their attempts and wait for manual redelivery.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+type Subscription struct {
+	ID        uuid.UUID
+	URL       string
+	Secret    string
+	Events    []string
+	IsActive  bool
+	CreatedAt time.Time
+}
+
+type Delivery struct {
+	ID             uuid.UUID
+	SubscriptionID uuid.UUID
+	EventID        uuid.UUID
+	EventType      string
+	Payload        []byte
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
+
+type Attempt struct {
+	DeliveryID  uuid.UUID
+	StatusCode  int
+	Error       string
+	Duration    time.Duration
+	AttemptedAt time.Time
+}
+
+// Job is a claimed delivery together with subscription data required to send it.
+type Job struct {
+	Delivery Delivery
+	URL      string
+	Secret   string
+}
@@ -0,0 +1,238 @@
+package eventstream
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	"github.com/srgklmv/comfortel/internal/domain/event"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+const (
+	pollInterval      = 500 * time.Millisecond
+	heartbeatInterval = 15 * time.Second
+	batchSize         = 100
+	// retryDelay is reconnection delay suggested to clients, in milliseconds.
+	retryDelay = 3000
+)
+
+type eventRepository interface {
+	GetLastEventPosition(ctx context.Context) (int64, error)
+	GetEventsAfter(ctx context.Context, afterPosition int64, aggregateID uuid.UUID, limit int) ([]event.Record, error)
+}
+
+// stream sends user events from outbox as Server-Sent Events. Event id is outbox position, which follows
+// order of commit, so clients resume from Last-Event-ID after reconnect without losing events.
+// Events get position from outbox relay, so they are streamed after relay picks them up.
+// Run watches latest position and wakes connected clients, which then read new events themselves.
+type stream struct {
+	repo eventRepository
+
+	mu          sync.Mutex
+	head        int64
+	changed     chan struct{}
+	subscribers int
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func New(repo eventRepository) *stream {
+	return &stream{
+		repo:    repo,
+		changed: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Close ends connected streams, clients reconnect to other instance with Last-Event-ID.
+func (s *stream) Close() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+}
+
+// Run polls latest event position while there are connected clients, until ctx is cancelled.
+func (s *stream) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		idle := s.subscribers == 0
+		s.mu.Unlock()
+		if idle {
+			continue
+		}
+
+		head, err := s.lastPosition(ctx)
+		if err != nil {
+			logger.Error("event stream poll error", slog.String("error", err.Error()))
+			continue
+		}
+
+		s.mu.Lock()
+		if head > s.head {
+			s.head = head
+			close(s.changed)
+			s.changed = make(chan struct{})
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Handle streams events after Last-Event-ID header or lastEventId query parameter, which is useful for
+// clients unable to set headers. Without them only new events are sent. Optional user_id query parameter
+// limits stream to events of one user.
+func (s *stream) Handle(gc *gin.Context) {
+	var userID uuid.UUID
+	if raw := gc.Query("user_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			gc.JSON(http.StatusBadRequest, apperror.AppError{
+				Code:    apperror.AnyIntYouWantErrorCode,
+				Error:   apperror.BadRequestErrorText,
+				Message: "Invalid user_id.",
+			})
+			return
+		}
+		userID = id
+	}
+
+	lastEventID := gc.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = gc.Query("lastEventId")
+	}
+
+	var after int64
+	if lastEventID != "" {
+		position, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || position < 0 {
+			gc.JSON(http.StatusBadRequest, apperror.AppError{
+				Code:    apperror.AnyIntYouWantErrorCode,
+				Error:   apperror.BadRequestErrorText,
+				Message: "Invalid Last-Event-ID.",
+			})
+			return
+		}
+		after = position
+	} else {
+		position, err := s.lastPosition(gc)
+		if err != nil {
+			logger.ErrorContext(gc, "event stream head read error", slog.String("error", err.Error()))
+			gc.JSON(http.StatusInternalServerError, apperror.AppError{
+				Code:  apperror.AnyIntYouWantErrorCode,
+				Error: apperror.InternalErrorText,
+			})
+			return
+		}
+		after = position
+	}
+
+	s.subscribe()
+	defer s.unsubscribe()
+
+	// Stream outlives write timeout of server.
+	_ = http.NewResponseController(gc.Writer).SetWriteDeadline(time.Time{})
+
+	gc.Header("Content-Type", sse.ContentType)
+	gc.Header("Cache-Control", "no-cache")
+	gc.Header("Connection", "keep-alive")
+	gc.Header("X-Accel-Buffering", "no")
+	gc.Status(http.StatusOK)
+	// Encoder of sse.Event always writes data field, which would make retry message event.
+	_, _ = gc.Writer.WriteString("retry:" + strconv.Itoa(retryDelay) + "\n\n")
+	gc.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	ctx := gc.Request.Context()
+	for {
+		// Channel is taken before reading, so event recorded during read wakes stream again.
+		changed := s.wait()
+
+		records, err := s.eventsAfter(ctx, after, userID)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.ErrorContext(ctx, "event stream read error", slog.String("error", err.Error()))
+			}
+			// Client reconnects and resumes from last sent event.
+			return
+		}
+
+		for _, rec := range records {
+			gc.Render(-1, sse.Event{
+				Id:    strconv.FormatInt(rec.Position, 10),
+				Event: rec.Type,
+				Data:  string(rec.Payload),
+			})
+			after = rec.Position
+		}
+		if len(records) > 0 {
+			gc.Writer.Flush()
+		}
+		if len(records) == batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.closed:
+			return
+		case <-changed:
+		case <-heartbeat.C:
+			if _, err = gc.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+			gc.Writer.Flush()
+		}
+	}
+}
+
+func (s *stream) subscribe() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscribers++
+}
+
+func (s *stream) unsubscribe() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscribers--
+}
+
+// wait returns channel closed on next change of latest position.
+func (s *stream) wait() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.changed
+}
+
+// lastPosition and eventsAfter read without transaction, because transaction middleware would hold one
+// for whole connection.
+func (s *stream) lastPosition(ctx context.Context) (int64, error) {
+	return s.repo.GetLastEventPosition(ctx)
+}
+
+func (s *stream) eventsAfter(ctx context.Context, after int64, userID uuid.UUID) ([]event.Record, error) {
+	return s.repo.GetEventsAfter(ctx, after, userID, batchSize)
+}
@@ -0,0 +1,221 @@
+package eventstream
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/srgklmv/comfortel/internal/domain/event"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+// fakeRepository holds events positioned by relay.
+type fakeRepository struct {
+	mu      sync.Mutex
+	records []event.Record
+}
+
+func (r *fakeRepository) add(aggregateID uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	position := int64(len(r.records) + 1)
+	r.records = append(r.records, event.Record{
+		Position:    position,
+		Type:        "user.updated",
+		AggregateID: aggregateID,
+		Payload:     []byte(fmt.Sprintf(`{"n":%d}`, position)),
+	})
+}
+
+func (r *fakeRepository) GetLastEventPosition(context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return int64(len(r.records)), nil
+}
+
+func (r *fakeRepository) GetEventsAfter(_ context.Context, afterPosition int64, aggregateID uuid.UUID, limit int) ([]event.Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var records []event.Record
+	for _, rec := range r.records[afterPosition:] {
+		if aggregateID != uuid.Nil && rec.AggregateID != aggregateID {
+			continue
+		}
+		records = append(records, rec)
+		if len(records) == limit {
+			break
+		}
+	}
+
+	return records, nil
+}
+
+type message struct {
+	id, event, data, retry string
+}
+
+// client reads messages of stream, skipping comments.
+type client struct {
+	t      *testing.T
+	reader *bufio.Reader
+}
+
+func (c client) next() message {
+	c.t.Helper()
+
+	var m message
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			c.t.Fatalf("reader.ReadString: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if m != (message{}) {
+				return m
+			}
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		switch field {
+		case "id":
+			m.id = value
+		case "event":
+			m.event = value
+		case "data":
+			m.data = value
+		case "retry":
+			m.retry = value
+		}
+	}
+}
+
+// newStream serves stream of repo and runs its polling until test ends.
+func newStream(t *testing.T, repo *fakeRepository) *httptest.Server {
+	t.Helper()
+	logger.SetDefault(slog.New(slog.DiscardHandler))
+	gin.SetMode(gin.TestMode)
+
+	s := New(repo)
+	ctx, cancel := context.WithCancel(context.Background())
+	go s.Run(ctx)
+
+	engine := gin.New()
+	engine.GET("/events", s.Handle)
+	server := httptest.NewServer(engine)
+	t.Cleanup(func() {
+		s.Close()
+		cancel()
+		server.Close()
+	})
+
+	return server
+}
+
+func connect(t *testing.T, server *httptest.Server, query, lastEventID string) client {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events"+query, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("client.Do: %v", err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	c := client{t: t, reader: bufio.NewReader(resp.Body)}
+	if m := c.next(); m.retry != "3000" {
+		t.Fatalf("first message %+v, want retry hint", m)
+	}
+
+	return c
+}
+
+func expectPositions(t *testing.T, c client, from, to int) {
+	t.Helper()
+
+	for position := from; position <= to; position++ {
+		m := c.next()
+		want := message{id: fmt.Sprint(position), event: "user.updated", data: fmt.Sprintf(`{"n":%d}`, position)}
+		if m != want {
+			t.Fatalf("message %+v, want %+v", m, want)
+		}
+	}
+}
+
+func TestHandleResumesAfterLastEventID(t *testing.T) {
+	repo := &fakeRepository{}
+	for range batchSize + 50 {
+		repo.add(uuid.New())
+	}
+	server := newStream(t, repo)
+
+	// Events are sent in order of position past batch size, then new ones as they come.
+	c := connect(t, server, "", "2")
+	expectPositions(t, c, 3, batchSize+50)
+	repo.add(uuid.New())
+	expectPositions(t, c, batchSize+51, batchSize+51)
+
+	// Query parameter works like header.
+	c = connect(t, server, "?lastEventId=149", "")
+	expectPositions(t, c, 150, batchSize+51)
+}
+
+func TestHandleSendsOnlyNewEventsWithoutLastEventID(t *testing.T) {
+	repo := &fakeRepository{}
+	repo.add(uuid.New())
+	repo.add(uuid.New())
+	server := newStream(t, repo)
+
+	c := connect(t, server, "", "")
+	repo.add(uuid.New())
+	expectPositions(t, c, 3, 3)
+}
+
+func TestHandleFiltersByUser(t *testing.T) {
+	repo := &fakeRepository{}
+	alice, bob := uuid.New(), uuid.New()
+	repo.add(alice)
+	repo.add(bob)
+	repo.add(alice)
+	server := newStream(t, repo)
+
+	c := connect(t, server, "?user_id="+alice.String(), "0")
+	expectPositions(t, c, 1, 1)
+	expectPositions(t, c, 3, 3)
+}
+
+func TestHandleRejectsInvalidLastEventID(t *testing.T) {
+	server := newStream(t, &fakeRepository{})
+
+	for _, query := range []string{"?lastEventId=-1", "?lastEventId=x", "?user_id=x"} {
+		resp, err := server.Client().Get(server.URL + "/events" + query)
+		if err != nil {
+			t.Fatalf("client.Get: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", query, resp.StatusCode)
+		}
+	}
+}
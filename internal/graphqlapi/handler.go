@@ -0,0 +1,129 @@
+package graphqlapi
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+)
+
+type usecase interface {
+	CreateUser(ctx context.Context, data userDomain.CreateUserRequestDTO) (any, int)
+	GetUserByID(ctx context.Context, id string) (any, int)
+	GetUsersByIDs(ctx context.Context, ids []string) (any, int)
+	GetUsersByLogins(ctx context.Context, logins []string) (any, int)
+	SearchUsers(ctx context.Context, data userDomain.SearchUsersRequestDTO) (any, int)
+	UpdateUser(ctx context.Context, id string, data userDomain.UpdateUserRequestDTO) (any, int)
+	DeleteUser(ctx context.Context, id string) (any, int)
+}
+
+// transactor runs fn in transaction, or in savepoint if ctx already carries one.
+type transactor interface {
+	WithinTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error
+}
+
+type loaders struct {
+	userByID    *loader[string, userDomain.GetUserDTO]
+	userByLogin *loader[string, userDomain.GetUserDTO]
+}
+
+type handler struct {
+	schema      graphql.Schema
+	userUsecase usecase
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// New returns handler of GraphQL requests. Each mutation runs in its own unit of work of transactor,
+// so failed one is rolled back while mutations before it are kept.
+func New(uc usecase, transactor transactor) (*handler, error) {
+	schema, err := newSchema(uc, transactor)
+	if err != nil {
+		return nil, fmt.Errorf("newSchema: %w", err)
+	}
+
+	return &handler{
+		schema:      schema,
+		userUsecase: uc,
+	}, nil
+}
+
+func (h handler) Handle(gc *gin.Context) {
+	var req request
+	if err := gc.ShouldBindJSON(&req); err != nil {
+		gc.JSON(http.StatusBadRequest, graphql.Result{
+			Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError("Request body invalid.")},
+		})
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+
+	validation := graphql.ValidateDocument(&h.schema, doc, nil)
+	if !validation.IsValid {
+		gc.JSON(http.StatusBadRequest, graphql.Result{Errors: validation.Errors})
+		return
+	}
+
+	if err = checkLimits(doc, req.OperationName, req.Variables); err != nil {
+		gc.JSON(http.StatusBadRequest, graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+
+	ctx := withLoaders(gc, h.newLoaders())
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+
+	gc.JSON(http.StatusOK, result)
+}
+
+// newLoaders creates request scoped loaders, so every user is loaded once per request
+// and lookups of the same kind are made by single repository query.
+func (h handler) newLoaders() *loaders {
+	return &loaders{
+		userByID: newLoader(func(ctx context.Context, ids []string) (map[string]userDomain.GetUserDTO, error) {
+			response, status := h.userUsecase.GetUsersByIDs(ctx, ids)
+			if err := toError(response, status); err != nil {
+				return nil, err
+			}
+
+			result := map[string]userDomain.GetUserDTO{}
+			for _, u := range response.([]userDomain.GetUserDTO) {
+				result[u.ID] = u
+			}
+			return result, nil
+		}),
+		userByLogin: newLoader(func(ctx context.Context, logins []string) (map[string]userDomain.GetUserDTO, error) {
+			response, status := h.userUsecase.GetUsersByLogins(ctx, logins)
+			if err := toError(response, status); err != nil {
+				return nil, err
+			}
+
+			result := map[string]userDomain.GetUserDTO{}
+			for _, u := range response.([]userDomain.GetUserDTO) {
+				result[u.Login] = u
+			}
+			return result, nil
+		}),
+	}
+}
@@ -0,0 +1,166 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	"github.com/srgklmv/comfortel/internal/middleware"
+	"github.com/srgklmv/comfortel/internal/repository/memory"
+	usecaseImpl "github.com/srgklmv/comfortel/internal/usecase"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+// failName is first name which makes update fail after it is written.
+const failName = "Fail"
+
+// recordingUsecase counts batch lookups and fails updates to failName after they are written,
+// like usecase failing after its first write.
+type recordingUsecase struct {
+	usecase
+	byIDs    [][]string
+	byLogins [][]string
+}
+
+func (uc *recordingUsecase) GetUsersByIDs(ctx context.Context, ids []string) (any, int) {
+	uc.byIDs = append(uc.byIDs, ids)
+	return uc.usecase.GetUsersByIDs(ctx, ids)
+}
+
+func (uc *recordingUsecase) GetUsersByLogins(ctx context.Context, logins []string) (any, int) {
+	uc.byLogins = append(uc.byLogins, logins)
+	return uc.usecase.GetUsersByLogins(ctx, logins)
+}
+
+func (uc *recordingUsecase) UpdateUser(ctx context.Context, id string, data userDomain.UpdateUserRequestDTO) (any, int) {
+	response, status := uc.usecase.UpdateUser(ctx, id, data)
+	if status < http.StatusBadRequest && data.FirstName == failName {
+		return apperror.AppError{Code: apperror.AnyIntYouWantErrorCode, Error: apperror.InternalErrorText}, http.StatusInternalServerError
+	}
+
+	return response, status
+}
+
+type server struct {
+	engine *gin.Engine
+	uc     *recordingUsecase
+	repo   interface {
+		CreateUser(ctx context.Context, data userDomain.User, hashedPassword string) (uuid.UUID, error)
+	}
+}
+
+// newServer serves GraphQL like api does, in transaction of in-memory repository.
+func newServer(t *testing.T) *server {
+	t.Helper()
+	logger.SetDefault(slog.New(slog.DiscardHandler))
+	gin.SetMode(gin.TestMode)
+
+	mem := memory.New()
+	uc := &recordingUsecase{usecase: usecaseImpl.New(mem, mem)}
+	h, err := New(uc, mem)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	engine := gin.New()
+	engine.ContextWithFallback = true
+	engine.POST("/graphql", middleware.Transaction(mem, engine), h.Handle)
+
+	return &server{engine: engine, uc: uc, repo: mem}
+}
+
+func (s *server) seed(t *testing.T, login string) string {
+	t.Helper()
+
+	id, err := s.repo.CreateUser(context.Background(), userDomain.User{Login: login, FirstName: "First"}, "hashed")
+	if err != nil {
+		t.Fatalf("repo.CreateUser: %v", err)
+	}
+
+	return id.String()
+}
+
+type result struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string   `json:"message"`
+		Path    []string `json:"path"`
+	} `json:"errors"`
+}
+
+func (s *server) do(t *testing.T, query string) (int, result) {
+	t.Helper()
+
+	body, _ := json.Marshal(map[string]string{"query": query})
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+
+	var r result
+	if err := json.Unmarshal(w.Body.Bytes(), &r); err != nil {
+		t.Fatalf("json.Unmarshal %q: %v", w.Body, err)
+	}
+
+	return w.Code, r
+}
+
+func TestHandleRejectsTooComplexQuery(t *testing.T) {
+	s := newServer(t)
+
+	status, r := s.do(t, `{ users(limit: 100) { items { id login email firstName lastName middleName sex age avatarURL isActive registerDate } total } }`)
+	if status != http.StatusBadRequest || len(r.Errors) != 1 || !strings.Contains(r.Errors[0].Message, "complexity") {
+		t.Errorf("status %d, errors %+v, want 400 with complexity error", status, r.Errors)
+	}
+}
+
+func TestHandleBatchesLookups(t *testing.T) {
+	s := newServer(t)
+	alice, bob := s.seed(t, "alice"), s.seed(t, "bobby")
+
+	status, r := s.do(t, `{
+		a: user(id: "`+alice+`") { login }
+		b: user(id: "`+bob+`") { login }
+		again: user(id: "`+alice+`") { login }
+		missing: user(id: "`+uuid.NewString()+`") { login }
+		c: userByLogin(login: "ALICE") { id }
+		d: userByLogin(login: "bobby") { id }
+	}`)
+	if status != http.StatusOK || len(r.Errors) > 0 {
+		t.Fatalf("status %d, errors %+v", status, r.Errors)
+	}
+	if string(r.Data["a"]) != `{"login":"alice"}` || string(r.Data["b"]) != `{"login":"bobby"}` || string(r.Data["missing"]) != "null" {
+		t.Errorf("data %s", r.Data)
+	}
+
+	if len(s.uc.byIDs) != 1 || len(s.uc.byIDs[0]) != 3 {
+		t.Errorf("lookups by ids %v, want one of 3 ids", s.uc.byIDs)
+	}
+	if len(s.uc.byLogins) != 1 || len(s.uc.byLogins[0]) != 2 {
+		t.Errorf("lookups by logins %v, want one of 2 logins", s.uc.byLogins)
+	}
+}
+
+func TestHandleRollsBackFailedMutationOnly(t *testing.T) {
+	s := newServer(t)
+	alice, bob := s.seed(t, "alice"), s.seed(t, "bobby")
+
+	status, r := s.do(t, `mutation {
+		a: updateUser(id: "`+alice+`", input: {firstName: "Changed"}) { firstName }
+		b: updateUser(id: "`+bob+`", input: {firstName: "`+failName+`"}) { firstName }
+	}`)
+	if status != http.StatusOK || len(r.Errors) != 1 || r.Errors[0].Path[0] != "b" {
+		t.Fatalf("status %d, errors %+v, want error of b", status, r.Errors)
+	}
+
+	_, r = s.do(t, `{ a: user(id: "`+alice+`") { firstName } b: user(id: "`+bob+`") { firstName } }`)
+	if string(r.Data["a"]) != `{"firstName":"Changed"}` || string(r.Data["b"]) != `{"firstName":"First"}` {
+		t.Errorf("data %s, want update of a kept and of b rolled back", r.Data)
+	}
+}
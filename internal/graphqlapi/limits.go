@@ -0,0 +1,128 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	maxDepth      = 8
+	maxComplexity = 1000
+)
+
+// listMultipliers maps list fields to argument which limits amount of returned items.
+// Complexity of their selections is multiplied by this argument value.
+var listMultipliers = map[string]struct {
+	arg      string
+	fallback int
+}{
+	"users": {arg: "limit", fallback: defaultListLimit},
+}
+
+type limitsChecker struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+// checkLimits calculates depth and complexity of operation and returns error if any of them is too big.
+// Every field costs 1, selections of list fields cost as much as list may contain.
+func checkLimits(doc *ast.Document, operationName string, variables map[string]any) error {
+	c := limitsChecker{
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: variables,
+	}
+
+	var operations []*ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.FragmentDefinition:
+			c.fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				operations = append(operations, d)
+			}
+		}
+	}
+
+	for _, op := range operations {
+		depth, complexity := c.selectionSet(op.SelectionSet, map[string]bool{})
+		if depth > maxDepth {
+			return fmt.Errorf("query depth %d exceeds limit of %d", depth, maxDepth)
+		}
+		if complexity > maxComplexity {
+			return fmt.Errorf("query complexity %d exceeds limit of %d", complexity, maxComplexity)
+		}
+	}
+
+	return nil
+}
+
+// selectionSet returns depth and complexity of selection set.
+// visited contains fragments on current path to stop on cyclic spreads, which are rejected by validation anyway.
+func (c limitsChecker) selectionSet(set *ast.SelectionSet, visited map[string]bool) (int, int) {
+	if set == nil {
+		return 0, 0
+	}
+
+	var depth, complexity int
+	for _, selection := range set.Selections {
+		var d, cx int
+		switch s := selection.(type) {
+		case *ast.Field:
+			d, cx = c.selectionSet(s.SelectionSet, visited)
+			d++
+			cx = 1 + cx*c.multiplier(s)
+		case *ast.InlineFragment:
+			d, cx = c.selectionSet(s.SelectionSet, visited)
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			fragment, ok := c.fragments[name]
+			if !ok || visited[name] {
+				continue
+			}
+			visited[name] = true
+			d, cx = c.selectionSet(fragment.SelectionSet, visited)
+			delete(visited, name)
+		}
+
+		depth = max(depth, d)
+		complexity += cx
+	}
+
+	return depth, complexity
+}
+
+func (c limitsChecker) multiplier(field *ast.Field) int {
+	m, ok := listMultipliers[field.Name.Value]
+	if !ok {
+		return 1
+	}
+
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != m.arg {
+			continue
+		}
+
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil && n > 0 {
+				return n
+			}
+		case *ast.Variable:
+			switch n := c.variables[v.Name.Value].(type) {
+			case float64:
+				if n > 0 {
+					return int(n)
+				}
+			case int:
+				if n > 0 {
+					return n
+				}
+			}
+		}
+	}
+
+	return m.fallback
+}
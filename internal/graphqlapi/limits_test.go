@@ -0,0 +1,63 @@
+package graphqlapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// nested returns query of fields nested depth times, e.g. "{ f { f { id } } }" for depth 3.
+func nested(depth int) string {
+	return strings.Repeat("{ f ", depth-1) + "{ id }" + strings.Repeat(" }", depth-1)
+}
+
+func TestCheckLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		err       string
+	}{
+		{name: "depth at limit", query: nested(maxDepth)},
+		{name: "depth over limit", query: nested(maxDepth + 1), err: "depth 9"},
+		{
+			name:  "depth over limit through fragment",
+			query: "query { f { ...deep } } fragment deep on T " + nested(maxDepth),
+			err:   "depth 9",
+		},
+		{name: "complexity at limit", query: "{ users(limit: 333) { items { id } total } }"},
+		{name: "complexity over limit", query: "{ users(limit: 500) { items { id } total } }", err: "complexity 1501"},
+		{
+			name:  "complexity of aliases is summed",
+			query: "{ a: users(limit: 300) { items { id } } b: users(limit: 300) { items { id } } }",
+			err:   "complexity 1202",
+		},
+		{
+			name:      "list size from variable",
+			query:     "query($n: Int) { users(limit: $n) { items { id login email } } }",
+			variables: map[string]any{"n": float64(400)},
+			err:       "complexity 1601",
+		},
+		{
+			name:  "default list size",
+			query: "{ users { items { id login email firstName lastName middleName sex age avatarURL isActive registerDate } } }",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			if err != nil {
+				t.Fatalf("parser.Parse: %v", err)
+			}
+
+			err = checkLimits(doc, "", tt.variables)
+			if tt.err == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("error %v, want one with %q", err, tt.err)
+			}
+		})
+	}
+}
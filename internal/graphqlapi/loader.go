@@ -0,0 +1,89 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+)
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFromContext(ctx context.Context) *loaders {
+	l, _ := ctx.Value(loadersKey{}).(*loaders)
+	return l
+}
+
+// loader collects keys requested by resolvers and loads them with single batch call
+// when first of returned thunks is resolved. Results are cached for request lifetime.
+type loader[K comparable, V any] struct {
+	batch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	results map[K]V
+	errs    map[K]error
+}
+
+func newLoader[K comparable, V any](batch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		batch:   batch,
+		queued:  map[K]bool{},
+		results: map[K]V{},
+		errs:    map[K]error{},
+	}
+}
+
+// Load schedules key for loading and returns thunk in format expected by graphql executor.
+// Thunk resolves to nil if there is no value for key.
+func (l *loader[K, V]) Load(ctx context.Context, key K) func() (any, error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (any, error) {
+		l.dispatch(ctx)
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if err := l.errs[key]; err != nil {
+			return nil, err
+		}
+		v, ok := l.results[key]
+		if !ok {
+			return nil, nil
+		}
+
+		return v, nil
+	}
+}
+
+func (l *loader[K, V]) dispatch(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.pending) == 0 {
+		return
+	}
+
+	keys := l.pending
+	l.pending = nil
+
+	results, err := l.batch(ctx, keys)
+	for _, key := range keys {
+		if err != nil {
+			l.errs[key] = err
+			continue
+		}
+		if v, ok := results[key]; ok {
+			l.results[key] = v
+		}
+	}
+}
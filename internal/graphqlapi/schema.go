@@ -0,0 +1,335 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+const defaultListLimit = userDomain.DefaultSearchLimit
+
+// resolveError carries usecase error to client with its code and HTTP status in extensions.
+type resolveError struct {
+	status int
+	appErr apperror.AppError
+}
+
+func (e resolveError) Error() string {
+	if e.appErr.Message != "" {
+		return fmt.Sprintf("%s %s", e.appErr.Error, e.appErr.Message)
+	}
+	return string(e.appErr.Error)
+}
+
+func (e resolveError) Extensions() map[string]any {
+	return map[string]any{
+		"code":   e.appErr.Code,
+		"status": e.status,
+	}
+}
+
+// toError converts usecase response with HTTP status into resolver error. Returns nil on success.
+func toError(response any, status int) error {
+	if status < http.StatusBadRequest {
+		return nil
+	}
+
+	appErr, ok := response.(apperror.AppError)
+	if !ok {
+		appErr = apperror.AppError{Code: apperror.AnyIntYouWantErrorCode, Error: apperror.InternalErrorText}
+	}
+
+	return resolveError{status: status, appErr: appErr}
+}
+
+var userType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "User",
+	Fields: graphql.Fields{
+		"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: userField(func(u userDomain.GetUserDTO) any { return u.ID })},
+		"login":        &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: userField(func(u userDomain.GetUserDTO) any { return u.Login })},
+		"firstName":    &graphql.Field{Type: graphql.String, Resolve: userField(func(u userDomain.GetUserDTO) any { return optional(u.FirstName) })},
+		"lastName":     &graphql.Field{Type: graphql.String, Resolve: userField(func(u userDomain.GetUserDTO) any { return optional(u.LastName) })},
+		"middleName":   &graphql.Field{Type: graphql.String, Resolve: userField(func(u userDomain.GetUserDTO) any { return optional(u.MiddleName) })},
+		"email":        &graphql.Field{Type: graphql.String, Resolve: userField(func(u userDomain.GetUserDTO) any { return optional(u.Email) })},
+		"sex":          &graphql.Field{Type: graphql.String, Resolve: userField(func(u userDomain.GetUserDTO) any { return optional(u.Sex) })},
+		"age":          &graphql.Field{Type: graphql.Int, Resolve: userField(func(u userDomain.GetUserDTO) any { return optional(int(u.Age)) })},
+		"avatarURL":    &graphql.Field{Type: graphql.String, Resolve: userField(func(u userDomain.GetUserDTO) any { return optional(u.AvatarURL) })},
+		"isActive":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Resolve: userField(func(u userDomain.GetUserDTO) any { return u.IsActive })},
+		"registerDate": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: userField(func(u userDomain.GetUserDTO) any { return u.RegisterDate })},
+	},
+})
+
+var userPageType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "UserPage",
+	Fields: graphql.Fields{
+		"items": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType)))},
+		"total": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+var userFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "UserFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"login": &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Case-insensitive substring of login."},
+		"email": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"sex":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+	},
+})
+
+var createUserInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CreateUserInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"login":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"password":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"firstName":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"lastName":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"middleName": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"email":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"sex":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"age":        &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"avatarURL":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+	},
+})
+
+var updateUserInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "UpdateUserInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"firstName":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"lastName":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"middleName": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"email":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"avatarURL":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"isActive":   &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+	},
+})
+
+func newSchema(uc usecase, transactor transactor) (graphql.Schema, error) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, _ := p.Args["id"].(string)
+					if _, err := uuid.Parse(id); err != nil {
+						return nil, toError(apperror.AppError{
+							Code:    apperror.AnyIntYouWantErrorCode,
+							Error:   apperror.BadRequestErrorText,
+							Message: "Invalid user id.",
+						}, http.StatusBadRequest)
+					}
+
+					return loadersFromContext(p.Context).userByID.Load(p.Context, id), nil
+				},
+			},
+			"userByLogin": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"login": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					login, _ := p.Args["login"].(string)
+					return loadersFromContext(p.Context).userByLogin.Load(p.Context, userDomain.NormalizeLogin(login)), nil
+				},
+			},
+			"users": &graphql.Field{
+				Type: graphql.NewNonNull(userPageType),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: userFilterType},
+					"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultListLimit},
+					"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					filter, _ := p.Args["filter"].(map[string]any)
+					dto := userDomain.SearchUsersRequestDTO{
+						Login: stringArg(filter, "login"),
+						Email: stringArg(filter, "email"),
+						Sex:   stringArg(filter, "sex"),
+					}
+					dto.Limit, _ = p.Args["limit"].(int)
+					dto.Offset, _ = p.Args["offset"].(int)
+
+					response, status := uc.SearchUsers(p.Context, dto)
+					if err := toError(response, status); err != nil {
+						return nil, err
+					}
+
+					page := response.(userDomain.UsersPageDTO)
+					return map[string]any{"items": page.Users, "total": page.Total}, nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createUser": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createUserInputType)},
+				},
+				Resolve: withinTx(transactor, func(p graphql.ResolveParams) (any, error) {
+					input, _ := p.Args["input"].(map[string]any)
+					dto := userDomain.CreateUserRequestDTO{
+						Login:      stringArg(input, "login"),
+						Password:   stringArg(input, "password"),
+						FirstName:  stringArg(input, "firstName"),
+						LastName:   stringArg(input, "lastName"),
+						MiddleName: stringArg(input, "middleName"),
+						Email:      stringArg(input, "email"),
+						Sex:        stringArg(input, "sex"),
+						AvatarURL:  stringArg(input, "avatarURL"),
+					}
+					if age, ok := input["age"].(int); ok {
+						if age < 0 || age > userDomain.MaxAge {
+							return nil, toError(apperror.AppError{
+								Code:    apperror.AnyIntYouWantErrorCode,
+								Error:   apperror.BadRequestErrorText,
+								Message: "age may be exaggerated a little bit",
+							}, http.StatusBadRequest)
+						}
+						dto.Age = uint8(age)
+					}
+
+					response, status := uc.CreateUser(p.Context, dto)
+					if err := toError(response, status); err != nil {
+						return nil, err
+					}
+
+					return getUser(p.Context, uc, response.(userDomain.CreateUserResponseDTO).Created)
+				}),
+			},
+			"updateUser": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateUserInputType)},
+				},
+				Resolve: withinTx(transactor, func(p graphql.ResolveParams) (any, error) {
+					id, _ := p.Args["id"].(string)
+					input, _ := p.Args["input"].(map[string]any)
+					dto := userDomain.UpdateUserRequestDTO{
+						FirstName:  stringArg(input, "firstName"),
+						LastName:   stringArg(input, "lastName"),
+						MiddleName: stringArg(input, "middleName"),
+						Email:      stringArg(input, "email"),
+						AvatarURL:  stringArg(input, "avatarURL"),
+					}
+					if active, ok := input["isActive"].(bool); ok {
+						dto.IsActive = &active
+					}
+
+					response, status := uc.UpdateUser(p.Context, id, dto)
+					if err := toError(response, status); err != nil {
+						return nil, err
+					}
+
+					return response, nil
+				}),
+			},
+			"deleteUser": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: withinTx(transactor, func(p graphql.ResolveParams) (any, error) {
+					id, _ := p.Args["id"].(string)
+
+					response, status := uc.DeleteUser(p.Context, id)
+					if err := toError(response, status); err != nil {
+						return nil, err
+					}
+
+					return response.(userDomain.DeleteUserResponseDTO).Deleted, nil
+				}),
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+// errMutationFailed makes transactor roll back mutation which resolved with error.
+var errMutationFailed = errors.New("mutation failed")
+
+// withinTx runs mutation resolver in unit of work of transactor, which is savepoint of request transaction,
+// so writes of failed mutation are rolled back and do not get committed with request.
+func withinTx(transactor transactor, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		var (
+			result     any
+			resolveErr error
+		)
+		err := transactor.WithinTx(p.Context, nil, func(ctx context.Context) error {
+			p.Context = ctx
+			result, resolveErr = resolve(p)
+			if resolveErr != nil {
+				return errMutationFailed
+			}
+			return nil
+		})
+		if resolveErr != nil {
+			return nil, resolveErr
+		}
+		if err != nil {
+			logger.ErrorContext(p.Context, "transactor.WithinTx error", slog.String("error", err.Error()))
+			return nil, toError(apperror.AppError{
+				Code:  apperror.AnyIntYouWantErrorCode,
+				Error: apperror.InternalErrorText,
+			}, http.StatusInternalServerError)
+		}
+
+		return result, nil
+	}
+}
+
+func getUser(ctx context.Context, uc usecase, id string) (any, error) {
+	response, status := uc.GetUserByID(ctx, id)
+	if err := toError(response, status); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// userField adapts getter of GetUserDTO to field resolver.
+func userField(get func(userDomain.GetUserDTO) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		u, ok := p.Source.(userDomain.GetUserDTO)
+		if !ok {
+			return nil, fmt.Errorf("unexpected user source %T", p.Source)
+		}
+
+		return get(u), nil
+	}
+}
+
+// optional turns zero value into null.
+func optional[T comparable](v T) any {
+	var zero T
+	if v == zero {
+		return nil
+	}
+
+	return v
+}
+
+func stringArg(args map[string]any, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
@@ -0,0 +1,116 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/srgklmv/comfortel/internal/metrics"
+	"github.com/srgklmv/comfortel/internal/repository"
+	"github.com/srgklmv/comfortel/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func logging() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		args := []any{
+			slog.String("method", info.FullMethod),
+			slog.String("code", code.String()),
+			slog.Duration("latency", time.Since(start)),
+		}
+		if code == codes.Internal || code == codes.Unknown {
+			logger.Error("grpc request", append(args, slog.String("error", err.Error()))...)
+		} else {
+			logger.Info("grpc request", args...)
+		}
+
+		return resp, err
+	}
+}
+
+// auth checks bearer tokens from "authorization" metadata against configured tokens, scheme is case-insensitive
+// and any of values may carry valid token. Does nothing if there are no tokens.
+func auth(services map[string]bool, tokens []string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if len(tokens) == 0 || !services[serviceName(info.FullMethod)] {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			metrics.AuthFailed("grpc")
+			return nil, status.Error(codes.Unauthenticated, "missing authorization token")
+		}
+
+		bearer := false
+		for _, value := range values {
+			scheme, token, ok := strings.Cut(value, " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") {
+				continue
+			}
+			bearer = true
+
+			for _, t := range tokens {
+				if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(t)) == 1 {
+					return handler(ctx, req)
+				}
+			}
+		}
+
+		if !bearer {
+			metrics.AuthFailed("grpc")
+			return nil, status.Error(codes.Unauthenticated, "invalid authorization scheme")
+		}
+		metrics.AuthFailed("grpc")
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization token")
+	}
+}
+
+// transaction is gRPC counterpart of middleware.Transaction.
+// Transaction is rolled back if handler returns error. Methods of reads run in read-only transaction,
+// which goes to replica if there is healthy one. Unlike HTTP, there is no window after write of caller.
+func transaction(services, reads map[string]bool, transactor transactor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !services[serviceName(info.FullMethod)] {
+			return handler(ctx, req)
+		}
+
+		var resp any
+		var handlerErr error
+		opts := &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+		if reads[info.FullMethod] {
+			opts.ReadOnly = true
+			ctx = repository.PreferReplica(ctx)
+		}
+		err := transactor.WithinTx(ctx, opts, func(ctx context.Context) error {
+			resp, handlerErr = handler(ctx, req)
+			return handlerErr
+		})
+		if handlerErr != nil {
+			return nil, handlerErr
+		}
+		if err != nil {
+			logger.Error("transaction error", slog.String("error", err.Error()))
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+
+		return resp, nil
+	}
+}
+
+// serviceName extracts service name from full method name in "/package.Service/Method" format.
+func serviceName(fullMethod string) string {
+	name, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return name
+}
@@ -0,0 +1,63 @@
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/srgklmv/comfortel/internal/domain/user"
+	userv1 "github.com/srgklmv/comfortel/internal/grpcapi/user/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+type usecase interface {
+	userUsecase
+}
+
+type userUsecase interface {
+	CreateUser(ctx context.Context, data user.CreateUserRequestDTO) (any, int)
+	GetUserByID(ctx context.Context, id string) (any, int)
+	GetUsers(ctx context.Context) (any, int)
+	UpdateUser(ctx context.Context, id string, data user.UpdateUserRequestDTO) (any, int)
+	DeleteUser(ctx context.Context, id string) (any, int)
+}
+
+type transactor interface {
+	WithinTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error
+}
+
+type server struct {
+	userv1.UnimplementedUserServiceServer
+
+	userUsecase userUsecase
+}
+
+// New returns gRPC server with user service, health checking and reflection registered.
+// Interceptors are applied to user service only, so health probes need neither token nor transaction.
+func New(transactor transactor, uc usecase, tokens []string) *grpc.Server {
+	services := map[string]bool{
+		userv1.UserService_ServiceDesc.ServiceName: true,
+	}
+	reads := map[string]bool{
+		userv1.UserService_GetUser_FullMethodName:   true,
+		userv1.UserService_ListUsers_FullMethodName: true,
+	}
+
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		logging(),
+		auth(services, tokens),
+		transaction(services, reads, transactor),
+	))
+
+	userv1.RegisterUserServiceServer(s, &server{userUsecase: uc})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(userv1.UserService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, healthServer)
+
+	reflection.Register(s)
+
+	return s
+}
@@ -0,0 +1,202 @@
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	userv1 "github.com/srgklmv/comfortel/internal/grpcapi/user/v1"
+	"github.com/srgklmv/comfortel/internal/repository/memory"
+	usecaseImpl "github.com/srgklmv/comfortel/internal/usecase"
+	"github.com/srgklmv/comfortel/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const token = "secret"
+
+// recordingTransactor records options of transactions it starts.
+type recordingTransactor struct {
+	transactor
+
+	mu   sync.Mutex
+	opts []sql.TxOptions
+}
+
+func (t *recordingTransactor) WithinTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error {
+	t.mu.Lock()
+	t.opts = append(t.opts, *opts)
+	t.mu.Unlock()
+
+	return t.transactor.WithinTx(ctx, opts, fn)
+}
+
+// failingUsecase fails deletes after they are made, like usecase failing after its first write.
+type failingUsecase struct {
+	usecase
+}
+
+func (uc failingUsecase) DeleteUser(ctx context.Context, id string) (any, int) {
+	response, code := uc.usecase.DeleteUser(ctx, id)
+	if code >= http.StatusBadRequest {
+		return response, code
+	}
+
+	return apperror.AppError{Code: apperror.AnyIntYouWantErrorCode, Error: apperror.InternalErrorText}, http.StatusInternalServerError
+}
+
+type testServer struct {
+	conn       *grpc.ClientConn
+	transactor *recordingTransactor
+	ids        map[string]string
+}
+
+// newTestServer serves gRPC over in-memory listener with users alice and bobby in memory repository.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	logger.SetDefault(slog.New(slog.DiscardHandler))
+
+	mem := memory.New()
+	ids := map[string]string{}
+	for _, login := range []string{"alice", "bobby"} {
+		id, err := mem.CreateUser(context.Background(), userDomain.User{Login: login, Email: login + "@example.com"}, "hashed")
+		if err != nil {
+			t.Fatalf("mem.CreateUser: %v", err)
+		}
+		ids[login] = id.String()
+	}
+
+	tr := &recordingTransactor{transactor: mem}
+	s := New(tr, failingUsecase{usecase: usecaseImpl.New(mem, mem)}, []string{token})
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = s.Serve(listener) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return &testServer{conn: conn, transactor: tr, ids: ids}
+}
+
+func authorized(values ...string) context.Context {
+	md := metadata.MD{}
+	for _, v := range values {
+		md.Append("authorization", v)
+	}
+
+	return metadata.NewOutgoingContext(context.Background(), md)
+}
+
+func TestAuth(t *testing.T) {
+	s := newTestServer(t)
+	client := userv1.NewUserServiceClient(s.conn)
+
+	tests := []struct {
+		name   string
+		values []string
+		want   codes.Code
+	}{
+		{name: "missing", want: codes.Unauthenticated},
+		{name: "other scheme", values: []string{"Basic " + token}, want: codes.Unauthenticated},
+		{name: "invalid token", values: []string{"Bearer other"}, want: codes.Unauthenticated},
+		{name: "valid", values: []string{"Bearer " + token}, want: codes.OK},
+		{name: "lower case scheme", values: []string{"bearer " + token}, want: codes.OK},
+		{name: "valid among values", values: []string{"Basic dXNlcjpwYXNz", "BEARER " + token}, want: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.ListUsers(authorized(tt.values...), &userv1.ListUsersRequest{})
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("code %s, want %s (%v)", got, tt.want, err)
+			}
+		})
+	}
+
+	// Health probes need no token.
+	resp, err := healthpb.NewHealthClient(s.conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("health check %v, %v", resp, err)
+	}
+}
+
+func TestStatusCodes(t *testing.T) {
+	s := newTestServer(t)
+	client := userv1.NewUserServiceClient(s.conn)
+	ctx := authorized("Bearer " + token)
+
+	resp, err := client.GetUser(ctx, &userv1.GetUserRequest{Id: s.ids["alice"]})
+	if err != nil || resp.GetUser().GetLogin() != "alice" {
+		t.Errorf("GetUser %v, %v", resp, err)
+	}
+
+	_, err = client.GetUser(ctx, &userv1.GetUserRequest{Id: "not uuid"})
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Errorf("GetUser of invalid id: code %s, want %s", got, codes.InvalidArgument)
+	}
+
+	_, err = client.GetUser(ctx, &userv1.GetUserRequest{Id: "00000000-0000-0000-0000-000000000001"})
+	if got := status.Code(err); got != codes.NotFound {
+		t.Errorf("GetUser of unknown id: code %s, want %s", got, codes.NotFound)
+	}
+
+	_, err = client.UpdateUser(ctx, &userv1.UpdateUserRequest{Id: s.ids["bobby"], Email: "alice@example.com"})
+	if got := status.Code(err); got != codes.AlreadyExists {
+		t.Errorf("UpdateUser with taken email: code %s, want %s", got, codes.AlreadyExists)
+	}
+
+	_, err = client.CreateUser(ctx, &userv1.CreateUserRequest{Age: 1000})
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Errorf("CreateUser of age 1000: code %s, want %s", got, codes.InvalidArgument)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	s := newTestServer(t)
+	client := userv1.NewUserServiceClient(s.conn)
+	ctx := authorized("Bearer " + token)
+
+	if _, err := client.GetUser(ctx, &userv1.GetUserRequest{Id: s.ids["alice"]}); err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if _, err := client.ListUsers(ctx, &userv1.ListUsersRequest{}); err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+
+	// Delete is made and then fails, so it is rolled back.
+	_, err := client.DeleteUser(ctx, &userv1.DeleteUserRequest{Id: s.ids["alice"]})
+	if got := status.Code(err); got != codes.Internal {
+		t.Errorf("DeleteUser: code %s, want %s", got, codes.Internal)
+	}
+	if _, err = client.GetUser(ctx, &userv1.GetUserRequest{Id: s.ids["alice"]}); err != nil {
+		t.Errorf("GetUser after failed delete: %v", err)
+	}
+
+	s.transactor.mu.Lock()
+	defer s.transactor.mu.Unlock()
+	readOnly := make([]bool, 0, len(s.transactor.opts))
+	for _, opts := range s.transactor.opts {
+		readOnly = append(readOnly, opts.ReadOnly)
+	}
+	if want := []bool{true, true, false, true}; !slices.Equal(readOnly, want) {
+		t.Errorf("read-only transactions %v, want %v", readOnly, want)
+	}
+}
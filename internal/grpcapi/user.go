@@ -0,0 +1,168 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	userv1 "github.com/srgklmv/comfortel/internal/grpcapi/user/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s server) CreateUser(ctx context.Context, req *userv1.CreateUserRequest) (*userv1.CreateUserResponse, error) {
+	dto := userDomain.CreateUserRequestDTO{
+		Login:      req.GetLogin(),
+		FirstName:  req.GetFirstName(),
+		LastName:   req.GetLastName(),
+		MiddleName: req.GetMiddleName(),
+		Email:      req.GetEmail(),
+		Sex:        req.GetSex(),
+		Password:   req.GetPassword(),
+		AvatarURL:  req.GetAvatarUrl(),
+	}
+	if req.GetAge() > userDomain.MaxAge {
+		return nil, status.Error(codes.InvalidArgument, "age may be exaggerated a little bit")
+	}
+	dto.Age = uint8(req.GetAge())
+
+	response, code := s.userUsecase.CreateUser(ctx, dto)
+	if err := toStatus(response, code); err != nil {
+		return nil, err
+	}
+
+	created, ok := response.(userDomain.CreateUserResponseDTO)
+	if !ok {
+		return nil, unexpectedResponse(response)
+	}
+
+	return &userv1.CreateUserResponse{Id: created.Created}, nil
+}
+
+func (s server) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.GetUserResponse, error) {
+	response, code := s.userUsecase.GetUserByID(ctx, req.GetId())
+	if err := toStatus(response, code); err != nil {
+		return nil, err
+	}
+
+	user, ok := response.(userDomain.GetUserDTO)
+	if !ok {
+		return nil, unexpectedResponse(response)
+	}
+
+	return &userv1.GetUserResponse{User: toProtoUser(user)}, nil
+}
+
+func (s server) ListUsers(ctx context.Context, _ *userv1.ListUsersRequest) (*userv1.ListUsersResponse, error) {
+	response, code := s.userUsecase.GetUsers(ctx)
+	if err := toStatus(response, code); err != nil {
+		return nil, err
+	}
+
+	users, ok := response.([]userDomain.GetUserDTO)
+	if !ok {
+		return nil, unexpectedResponse(response)
+	}
+
+	result := &userv1.ListUsersResponse{Users: make([]*userv1.User, 0, len(users))}
+	for _, user := range users {
+		result.Users = append(result.Users, toProtoUser(user))
+	}
+
+	return result, nil
+}
+
+func (s server) UpdateUser(ctx context.Context, req *userv1.UpdateUserRequest) (*userv1.UpdateUserResponse, error) {
+	dto := userDomain.UpdateUserRequestDTO{
+		FirstName:  req.GetFirstName(),
+		LastName:   req.GetLastName(),
+		MiddleName: req.GetMiddleName(),
+		Email:      req.GetEmail(),
+		AvatarURL:  req.GetAvatarUrl(),
+		IsActive:   req.IsActive,
+	}
+
+	response, code := s.userUsecase.UpdateUser(ctx, req.GetId(), dto)
+	if err := toStatus(response, code); err != nil {
+		return nil, err
+	}
+
+	user, ok := response.(userDomain.GetUserDTO)
+	if !ok {
+		return nil, unexpectedResponse(response)
+	}
+
+	return &userv1.UpdateUserResponse{User: toProtoUser(user)}, nil
+}
+
+func (s server) DeleteUser(ctx context.Context, req *userv1.DeleteUserRequest) (*userv1.DeleteUserResponse, error) {
+	response, code := s.userUsecase.DeleteUser(ctx, req.GetId())
+	if err := toStatus(response, code); err != nil {
+		return nil, err
+	}
+
+	deleted, ok := response.(userDomain.DeleteUserResponseDTO)
+	if !ok {
+		return nil, unexpectedResponse(response)
+	}
+
+	return &userv1.DeleteUserResponse{Id: deleted.Deleted}, nil
+}
+
+func toProtoUser(dto userDomain.GetUserDTO) *userv1.User {
+	return &userv1.User{
+		Id:           dto.ID,
+		Login:        dto.Login,
+		FirstName:    dto.FirstName,
+		LastName:     dto.LastName,
+		MiddleName:   dto.MiddleName,
+		Email:        dto.Email,
+		Sex:          dto.Sex,
+		Age:          uint32(dto.Age),
+		AvatarUrl:    dto.AvatarURL,
+		RegisterDate: dto.RegisterDate,
+		IsActive:     dto.IsActive,
+	}
+}
+
+// toStatus converts usecase response with HTTP status into gRPC error. Returns nil on success.
+func toStatus(response any, httpStatus int) error {
+	if httpStatus < http.StatusBadRequest {
+		return nil
+	}
+
+	msg := http.StatusText(httpStatus)
+	if appErr, ok := response.(apperror.AppError); ok {
+		msg = string(appErr.Error)
+		if appErr.Message != "" {
+			msg = fmt.Sprintf("%s %s", appErr.Error, appErr.Message)
+		}
+	}
+
+	return status.Error(codeFromHTTP(httpStatus), msg)
+}
+
+func codeFromHTTP(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	}
+
+	return codes.Internal
+}
+
+func unexpectedResponse(response any) error {
+	return status.Errorf(codes.Internal, "unexpected usecase response type %T", response)
+}
@@ -0,0 +1,134 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	checkTimeout = 2 * time.Second
+	// cacheTTL keeps frequent probes of several kinds from hitting dependencies on each request.
+	cacheTTL = time.Second
+)
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusReady    = "ready"
+	StatusNotReady = "not ready"
+)
+
+// Component is dependency checked by readiness probe. App is not ready if required component is down,
+// optional ones are only reported.
+type Component struct {
+	Name     string
+	Check    func(ctx context.Context) error
+	Optional bool
+}
+
+type ComponentReport struct {
+	Status   string `json:"status"`
+	Optional bool   `json:"optional,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type Report struct {
+	Status       string                     `json:"status"`
+	ShuttingDown bool                       `json:"shuttingDown,omitempty"`
+	Components   map[string]ComponentReport `json:"components,omitempty"`
+}
+
+type checker struct {
+	ready      func() bool
+	components []Component
+
+	mu        sync.Mutex
+	report    Report
+	checkedAt time.Time
+}
+
+// New returns handlers of liveness and readiness probes. Ready reports whether app accepts requests,
+// app is not ready without checking components while it is false.
+func New(ready func() bool, components ...Component) *checker {
+	return &checker{
+		ready:      ready,
+		components: components,
+	}
+}
+
+// Live responds 200 while process serves requests.
+func (c *checker) Live(gc *gin.Context) {
+	gc.JSON(http.StatusOK, Report{Status: StatusUp})
+}
+
+// Ready responds 200 with status of each component if app is ready, and 503 otherwise.
+func (c *checker) Ready(gc *gin.Context) {
+	if !c.ready() {
+		gc.JSON(http.StatusServiceUnavailable, Report{Status: StatusNotReady, ShuttingDown: true})
+		return
+	}
+
+	report := c.check(gc.Request.Context())
+	status := http.StatusOK
+	if report.Status != StatusReady {
+		status = http.StatusServiceUnavailable
+	}
+	gc.JSON(status, report)
+}
+
+// check runs checks of components concurrently, or returns cached report if it is fresh.
+func (c *checker) check(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checkedAt) < cacheTTL {
+		return c.report
+	}
+
+	// Probe may give up early, but result is cached for others.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), checkTimeout)
+	defer cancel()
+
+	reports := make([]ComponentReport, len(c.components))
+	var wg sync.WaitGroup
+	for i, component := range c.components {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			err := component.Check(ctx)
+			reports[i] = ComponentReport{
+				Status:   StatusUp,
+				Optional: component.Optional,
+				Duration: time.Since(start).Round(time.Microsecond).String(),
+			}
+			if err != nil {
+				reports[i].Status = StatusDown
+				reports[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:     StatusReady,
+		Components: make(map[string]ComponentReport, len(c.components)),
+	}
+	for i, component := range c.components {
+		report.Components[component.Name] = reports[i]
+		if reports[i].Status == StatusDown && !component.Optional {
+			report.Status = StatusNotReady
+		}
+	}
+
+	c.report = report
+	c.checkedAt = time.Now()
+
+	return report
+}
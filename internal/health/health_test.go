@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func probe(t *testing.T, c *checker, path string) (int, Report) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.GET("/healthz", c.Live)
+	engine.GET("/readyz", c.Ready)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var report Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("json.Unmarshal %q: %v", w.Body, err)
+	}
+
+	return w.Code, report
+}
+
+func component(name string, err error, optional bool) Component {
+	return Component{
+		Name:     name,
+		Check:    func(context.Context) error { return err },
+		Optional: optional,
+	}
+}
+
+func TestReady(t *testing.T) {
+	down := errors.New("connection refused")
+
+	tests := []struct {
+		name       string
+		components []Component
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "all up",
+			components: []Component{component("database", nil, false), component("publisher", nil, true)},
+			wantStatus: http.StatusOK,
+			want:       StatusReady,
+		},
+		{
+			name:       "optional down",
+			components: []Component{component("database", nil, false), component("publisher", down, true)},
+			wantStatus: http.StatusOK,
+			want:       StatusReady,
+		},
+		{
+			name:       "required down",
+			components: []Component{component("database", down, false), component("publisher", nil, true)},
+			wantStatus: http.StatusServiceUnavailable,
+			want:       StatusNotReady,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, report := probe(t, New(func() bool { return true }, tt.components...), "/readyz")
+			if status != tt.wantStatus || report.Status != tt.want {
+				t.Fatalf("status %d %q, want %d %q", status, report.Status, tt.wantStatus, tt.want)
+			}
+			for _, c := range tt.components {
+				got := report.Components[c.Name]
+				want := StatusUp
+				if c.Check(context.Background()) != nil {
+					want = StatusDown
+				}
+				if got.Status != want || got.Optional != c.Optional || (want == StatusDown) != (got.Error != "") {
+					t.Errorf("component %s: %+v, want status %s", c.Name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadyWhileShuttingDown(t *testing.T) {
+	var checks atomic.Int32
+	c := New(func() bool { return false }, Component{Name: "database", Check: func(context.Context) error {
+		checks.Add(1)
+		return nil
+	}})
+
+	status, report := probe(t, c, "/readyz")
+	if status != http.StatusServiceUnavailable || !report.ShuttingDown || report.Status != StatusNotReady {
+		t.Errorf("status %d, report %+v, want 503 shutting down", status, report)
+	}
+	if checks.Load() != 0 {
+		t.Errorf("components checked %d times while shutting down", checks.Load())
+	}
+
+	// Liveness does not depend on readiness.
+	if status, report = probe(t, c, "/healthz"); status != http.StatusOK || report.Status != StatusUp {
+		t.Errorf("liveness status %d, report %+v", status, report)
+	}
+}
+
+func TestReadyCachesReport(t *testing.T) {
+	var checks atomic.Int32
+	c := New(func() bool { return true }, Component{Name: "database", Check: func(context.Context) error {
+		checks.Add(1)
+		return nil
+	}})
+
+	for range 3 {
+		if status, _ := probe(t, c, "/readyz"); status != http.StatusOK {
+			t.Fatalf("status %d", status)
+		}
+	}
+	if checks.Load() != 1 {
+		t.Errorf("component checked %d times, want once within cache TTL", checks.Load())
+	}
+}
@@ -0,0 +1,47 @@
+package idempotency
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+const cleanInterval = 10 * time.Minute
+
+type keyRepository interface {
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+}
+
+// cleaner removes expired idempotency keys. Expired keys are ignored anyway, so it only keeps table small.
+type cleaner struct {
+	repo keyRepository
+}
+
+func NewCleaner(repo keyRepository) *cleaner {
+	return &cleaner{repo: repo}
+}
+
+// Run removes expired keys periodically until ctx is cancelled.
+func (c *cleaner) Run(ctx context.Context) {
+	ticker := time.NewTicker(cleanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := c.repo.DeleteExpiredIdempotencyKeys(ctx)
+		if err != nil {
+			logger.Error("idempotency keys clean error", slog.String("error", err.Error()))
+			continue
+		}
+		if n > 0 {
+			logger.Info("expired idempotency keys removed", slog.Int64("count", n))
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests which matched no route, so random paths don't create series.
+const unmatchedRoute = "unmatched"
+
+type httpMetrics struct {
+	skip func(c *gin.Context) bool
+}
+
+type HTTPOption func(*httpMetrics)
+
+// SkipRequests makes HTTP not record requests for which skip returns true, e.g. attempts replayed
+// through engine, which are recorded as part of original request.
+func SkipRequests(skip func(c *gin.Context) bool) HTTPOption {
+	return func(m *httpMetrics) {
+		m.skip = skip
+	}
+}
+
+// HTTP records count and latency of requests by route template, e.g. "/api/user/:id".
+func HTTP(opts ...HTTPOption) gin.HandlerFunc {
+	var m httpMetrics
+	for _, opt := range opts {
+		opt(&m)
+	}
+
+	return func(c *gin.Context) {
+		if m.skip != nil && m.skip(c) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
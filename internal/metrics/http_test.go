@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHTTPSkipsRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(HTTP(SkipRequests(func(c *gin.Context) bool { return c.GetHeader("X-Skip") != "" })))
+	engine.GET("/skip-test", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	counter := httpRequests.WithLabelValues(http.MethodGet, "/skip-test", "204")
+	before := testutil.ToFloat64(counter)
+
+	for _, skip := range []bool{false, true, true} {
+		req := httptest.NewRequest(http.MethodGet, "/skip-test", nil)
+		if skip {
+			req.Header.Set("X-Skip", "1")
+		}
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Errorf("counted %v requests, want 1", got)
+	}
+}
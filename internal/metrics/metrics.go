@@ -0,0 +1,138 @@
+// Package metrics holds Prometheus collectors of app. Collectors are registered in Registry, which is served
+// by Handler on separate listener.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "comfortel"
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repository_query_duration_seconds",
+		Help:      "Latency of database queries by repository method and result.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"method", "result"})
+
+	passwordHashDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "password_hash_duration_seconds",
+		Help:      "Duration of bcrypt password hashing.",
+		Buckets:   []float64{.1, .25, .5, .75, 1, 1.5, 2, 3, 5},
+	})
+
+	userEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "user_events_total",
+		Help:      "User changes by event type, e.g. user.created and user.deleted.",
+	}, []string{"type"})
+	validationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "validation_failures_total",
+		Help:      "Rejected request fields.",
+	}, []string{"field"})
+	authFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Requests rejected for missing or wrong credentials by API.",
+	}, []string{"api"})
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by rate limit policy.",
+	}, []string{"policy"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		queryDuration,
+		passwordHashDuration,
+		userEvents,
+		validationFailures,
+		authFailures,
+		rateLimited,
+	)
+}
+
+// Handler serves metrics of Registry in Prometheus format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveQuery records query of repository method, see repository.WithQueryObserver.
+func ObserveQuery(method string, duration time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	queryDuration.WithLabelValues(method, result).Observe(duration.Seconds())
+}
+
+// ObservePasswordHash records duration of password hashing.
+func ObservePasswordHash(duration time.Duration) {
+	passwordHashDuration.Observe(duration.Seconds())
+}
+
+// UserEvent counts user change of event type.
+func UserEvent(eventType string) {
+	userEvents.WithLabelValues(eventType).Inc()
+}
+
+// ValidationFailed counts fields rejected by validation. Validation error is errors like "invalid login"
+// joined one by one, each counted by its field.
+func ValidationFailed(validationErr error) {
+	if joined, ok := validationErr.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			ValidationFailed(err)
+		}
+		return
+	}
+
+	field, ok := strings.CutPrefix(validationErr.Error(), "invalid ")
+	if !ok {
+		field = "other"
+	}
+	validationFailures.WithLabelValues(strings.ToLower(strings.ReplaceAll(field, " ", "_"))).Inc()
+}
+
+// AuthFailed counts request rejected by authentication of api, e.g. "scim" or "grpc".
+func AuthFailed(api string) {
+	authFailures.WithLabelValues(api).Inc()
+}
+
+// RateLimited counts request rejected by rate limit policy.
+func RateLimited(policy string) {
+	rateLimited.WithLabelValues(policy).Inc()
+}
+
+// RegisterDB exports pool statistics of db, see sql.DBStats, labelled by name.
+func RegisterDB(name string, db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
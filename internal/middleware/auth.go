@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	"github.com/srgklmv/comfortel/internal/metrics"
+)
+
+// principalKey is gin context key of principal of request, see Principal.
+const principalKey = "comfortel.principal"
+
+// Authenticate sets principal of requests which carry one of tokens of realm as bearer token, realms are
+// tried in order of names. It rejects nothing, routes are guarded by BearerAuth or own auth of API.
+func Authenticate(realms map[string][]string) gin.HandlerFunc {
+	names := slices.Sorted(maps.Keys(realms))
+
+	return func(c *gin.Context) {
+		for _, realm := range names {
+			if authenticate(c, realm, realms[realm]) {
+				break
+			}
+		}
+		c.Next()
+	}
+}
+
+// Principal returns authenticated caller of request, which is realm and hash of its token, like "scim:9f86d0...".
+func Principal(c *gin.Context) (string, bool) {
+	principal := c.GetString(principalKey)
+	return principal, principal != ""
+}
+
+// BearerAuth rejects requests with 401 unless they carry one of tokens as bearer token, all of them are
+// rejected if there are no tokens. Realm names API in WWW-Authenticate header and in metrics.
+func BearerAuth(realm string, tokens []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authenticate(c, realm, tokens) {
+			c.Next()
+			return
+		}
+
+		metrics.AuthFailed(realm)
+		c.Header("WWW-Authenticate", `Bearer realm="`+realm+`"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.UnauthorizedErrorText,
+			Message: "Authorization failure.",
+		})
+	}
+}
+
+// authenticate sets principal of request and returns true if its bearer token is one of tokens.
+func authenticate(c *gin.Context, realm string, tokens []string) bool {
+	token, ok := bearerToken(c)
+	if !ok {
+		return false
+	}
+
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			sum := sha256.Sum256([]byte(token))
+			c.Set(principalKey, realm+":"+hex.EncodeToString(sum[:]))
+			return true
+		}
+	}
+
+	return false
+}
+
+// bearerToken returns token of Authorization header, scheme of which is case-insensitive.
+func bearerToken(c *gin.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+
+	return token, true
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+)
+
+// MaxBodySize rejects requests with body larger than limit bytes with 413. Body without Content-Length
+// is cut at limit, so handler fails to read it.
+func MaxBodySize(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, apperror.AppError{
+				Code:    apperror.AnyIntYouWantErrorCode,
+				Error:   apperror.BadRequestErrorText,
+				Message: fmt.Sprintf("Request body is larger than %d bytes.", limit),
+			})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
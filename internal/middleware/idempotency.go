@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	"github.com/srgklmv/comfortel/internal/domain/idempotency"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+type idempotencyRepository interface {
+	ReserveIdempotencyKey(ctx context.Context, caller, key, requestHash string, ttl time.Duration) (idempotency.Key, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, caller, key string, statusCode int, contentType string, body []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, caller, key string) error
+}
+
+// Idempotency makes POST and PATCH requests with Idempotency-Key header safe to retry. First response is
+// stored by caller, key and request hash, identical retries get it replayed, retries with different
+// request are rejected with 422 and retries while first request is served with 409. Key is reserved and
+// response is stored outside request transaction, so it must go before Transaction, and error responses
+// are replayed too. Server errors and panics release key, so such requests may be retried with same key.
+func Idempotency(repo idempotencyRepository, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Attempt replayed by Transaction middleware writes its response to original request, which stores it.
+		if Replayed(c) {
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader(idempotency.Header)
+		if key == "" || (c.Request.Method != http.MethodPost && c.Request.Method != http.MethodPatch) {
+			c.Next()
+			return
+		}
+		if len(key) > idempotency.MaxKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, apperror.AppError{
+				Code:    apperror.AnyIntYouWantErrorCode,
+				Error:   apperror.BadRequestErrorText,
+				Message: "Idempotency-Key is too long.",
+			})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, apperror.AppError{
+				Code:    apperror.AnyIntYouWantErrorCode,
+				Error:   apperror.BadRequestErrorText,
+				Message: "Request body invalid.",
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		caller := callerOf(c)
+		hash := requestHash(c.Request, body)
+		stored, reserved, err := repo.ReserveIdempotencyKey(c.Request.Context(), caller, key, hash, ttl)
+		if err != nil {
+			logger.ErrorContext(c, "idempotency key reserve error", slog.String("error", err.Error()))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, apperror.AppError{
+				Code:  apperror.AnyIntYouWantErrorCode,
+				Error: apperror.InternalErrorText,
+			})
+			return
+		}
+
+		if !reserved {
+			switch {
+			case stored.RequestHash != hash:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, apperror.AppError{
+					Code:    apperror.AnyIntYouWantErrorCode,
+					Error:   apperror.BadRequestErrorText,
+					Message: "Idempotency-Key is already used with different request.",
+				})
+			case !stored.Completed:
+				c.AbortWithStatusJSON(http.StatusConflict, apperror.AppError{
+					Code:    apperror.AnyIntYouWantErrorCode,
+					Error:   apperror.BadRequestErrorText,
+					Message: "Request with this Idempotency-Key is in progress.",
+				})
+			default:
+				c.Header(idempotency.ReplayedHeader, "true")
+				c.Data(stored.StatusCode, stored.ContentType, stored.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		// Key is released if handler panics, context of request may be cancelled by then.
+		ctx := context.WithoutCancel(c.Request.Context())
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := repo.ReleaseIdempotencyKey(ctx, caller, key); err != nil {
+				logger.ErrorContext(ctx, "idempotency key release error", slog.String("error", err.Error()))
+			}
+		}()
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		if writer.Status() >= http.StatusInternalServerError {
+			return
+		}
+		err = repo.CompleteIdempotencyKey(ctx, caller, key, writer.Status(), writer.Header().Get("Content-Type"), writer.body.Bytes())
+		if err != nil {
+			logger.ErrorContext(ctx, "idempotency key save error", slog.String("error", err.Error()))
+			return
+		}
+		completed = true
+	}
+}
+
+// callerOf identifies client by credentials if there are any, otherwise by address.
+// Value is hashed, so credentials are not stored.
+func callerOf(c *gin.Context) string {
+	caller := "ip:" + c.ClientIP()
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		caller = "auth:" + auth
+	}
+
+	sum := sha256.Sum256([]byte(caller))
+	return hex.EncodeToString(sum[:])
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter copies response body to be stored.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
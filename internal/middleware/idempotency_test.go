@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/srgklmv/comfortel/internal/domain/idempotency"
+	"github.com/srgklmv/comfortel/internal/repository"
+	"github.com/srgklmv/comfortel/migrations"
+	"github.com/srgklmv/comfortel/pkg/database"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+// idempotencyServer serves POST /items, which responds status of "status" query with number of call,
+// after Idempotency middleware and, if tx is set, in sqlite transaction.
+type idempotencyServer struct {
+	engine *gin.Engine
+	calls  atomic.Int64
+	// release, if set, is waited for by handler.
+	release chan struct{}
+}
+
+func newIdempotencyServer(t *testing.T, ttl time.Duration, tx bool) *idempotencyServer {
+	t.Helper()
+	logger.SetDefault(slog.New(slog.DiscardHandler))
+	gin.SetMode(gin.TestMode)
+
+	conn, err := database.NewSQLite(filepath.Join(t.TempDir(), "idempotency.db"))
+	if err != nil {
+		t.Fatalf("database.NewSQLite: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Shutdown(conn) })
+	version, err := database.LatestVersion(migrations.FS, database.DriverSQLite)
+	if err != nil {
+		t.Fatalf("database.LatestVersion: %v", err)
+	}
+	if err = database.Migrate(conn, database.DriverSQLite, migrations.FS, version); err != nil {
+		t.Fatalf("database.Migrate: %v", err)
+	}
+
+	s := &idempotencyServer{engine: gin.New()}
+	s.engine.ContextWithFallback = true
+	repo := repository.New(conn, database.DriverSQLite)
+	handlers := []gin.HandlerFunc{Idempotency(repo, ttl)}
+	if tx {
+		handlers = append(handlers, Transaction(repository.NewTransactor(conn), s.engine))
+	}
+	s.engine.POST("/items", append(handlers,
+		func(c *gin.Context) {
+			n := s.calls.Add(1)
+			if s.release != nil {
+				<-s.release
+			}
+
+			status := http.StatusCreated
+			switch c.Query("status") {
+			case "conflict":
+				status = http.StatusConflict
+			case "error":
+				status = http.StatusInternalServerError
+			}
+			c.JSON(status, gin.H{"call": n})
+		},
+	)...)
+
+	return s
+}
+
+func (s *idempotencyServer) do(key, query, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/items"+query, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(idempotency.Header, key)
+	}
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestIdempotencyReplaysFirstResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{name: "success", status: http.StatusCreated},
+		{name: "client error", query: "?status=conflict", status: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newIdempotencyServer(t, time.Hour, true)
+
+			first := s.do("key", tt.query, `{"a":1}`)
+			second := s.do("key", tt.query, `{"a":1}`)
+			if first.Code != tt.status || second.Code != tt.status || second.Body.String() != first.Body.String() {
+				t.Errorf("responses %d %s and %d %s, want same of status %d", first.Code, first.Body, second.Code, second.Body, tt.status)
+			}
+			if first.Header().Get(idempotency.ReplayedHeader) != "" || second.Header().Get(idempotency.ReplayedHeader) != "true" {
+				t.Error("only second response must be marked as replayed")
+			}
+			if got := s.calls.Load(); got != 1 {
+				t.Errorf("handler is called %d times, want 1", got)
+			}
+
+			if w := s.do("other-key", tt.query, `{"a":1}`); w.Code != tt.status || s.calls.Load() != 2 {
+				t.Errorf("request with other key: status %d, calls %d", w.Code, s.calls.Load())
+			}
+			if w := s.do("", tt.query, `{"a":1}`); w.Code != tt.status || s.calls.Load() != 3 {
+				t.Errorf("request without key: status %d, calls %d", w.Code, s.calls.Load())
+			}
+		})
+	}
+}
+
+func TestIdempotencyRejectsDifferentRequest(t *testing.T) {
+	s := newIdempotencyServer(t, time.Hour, true)
+
+	s.do("key", "", `{"a":1}`)
+	for _, query := range []string{"", "?status=conflict"} {
+		if w := s.do("key", query, `{"a":2}`); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("status %d, want %d", w.Code, http.StatusUnprocessableEntity)
+		}
+	}
+	if got := s.calls.Load(); got != 1 {
+		t.Errorf("handler is called %d times, want 1", got)
+	}
+}
+
+func TestIdempotencyReleasesKeyAfterServerError(t *testing.T) {
+	s := newIdempotencyServer(t, time.Hour, true)
+
+	if w := s.do("key", "?status=error", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if w := s.do("key", "?status=error", `{}`); w.Header().Get(idempotency.ReplayedHeader) != "" {
+		t.Error("server error is replayed")
+	}
+	if got := s.calls.Load(); got != 2 {
+		t.Errorf("handler is called %d times, want 2", got)
+	}
+}
+
+func TestIdempotencyKeyExpires(t *testing.T) {
+	const ttl = 50 * time.Millisecond
+	s := newIdempotencyServer(t, ttl, true)
+
+	s.do("key", "", `{"a":1}`)
+	time.Sleep(2 * ttl)
+
+	// Expired key is reserved again, even for different request.
+	w := s.do("key", "", `{"a":2}`)
+	if w.Code != http.StatusCreated || w.Header().Get(idempotency.ReplayedHeader) != "" {
+		t.Errorf("status %d, replayed %q, want fresh %d", w.Code, w.Header().Get(idempotency.ReplayedHeader), http.StatusCreated)
+	}
+	if got := s.calls.Load(); got != 2 {
+		t.Errorf("handler is called %d times, want 2", got)
+	}
+}
+
+// Sqlite transaction of first request would hold write lock, so requests are served without transactions.
+func TestIdempotencyRejectsConcurrentDuplicate(t *testing.T) {
+	s := newIdempotencyServer(t, time.Hour, false)
+	s.release = make(chan struct{})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- s.do("key", "", `{"a":1}`) }()
+	for s.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if w := s.do("key", "", `{"a":1}`); w.Code != http.StatusConflict {
+		t.Errorf("duplicate in progress: status %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	close(s.release)
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Fatalf("first request: status %d, want %d", w.Code, http.StatusCreated)
+	}
+	if w := s.do("key", "", `{"a":1}`); w.Code != http.StatusCreated || w.Header().Get(idempotency.ReplayedHeader) != "true" {
+		t.Errorf("duplicate after first one: status %d, want replayed %d", w.Code, http.StatusCreated)
+	}
+	if got := s.calls.Load(); got != 1 {
+		t.Errorf("handler is called %d times, want 1", got)
+	}
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	"github.com/srgklmv/comfortel/internal/domain/ratelimit"
+	"github.com/srgklmv/comfortel/internal/metrics"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+type rateLimitStore interface {
+	// TakeRateLimitTokens takes n tokens from bucket of key, which holds limit tokens and gets one back each interval.
+	// Backlog is time until bucket is full again.
+	TakeRateLimitTokens(ctx context.Context, key string, n int, interval time.Duration, limit int) (allowed bool, backlog time.Duration, err error)
+}
+
+type rateLimit struct {
+	store    rateLimitStore
+	policies []ratelimit.Policy
+	// costs are cost functions by method and route.
+	costs   map[string]func(c *gin.Context) int
+	apiKeys []string
+}
+
+type RateLimitOption func(*rateLimit)
+
+// RouteCost makes request to route take cost(c) tokens instead of one, e.g. one per operation of batch.
+// Cost is capped by limit of policy, so costly request empties bucket instead of never passing.
+func RouteCost(method, route string, cost func(c *gin.Context) int) RateLimitOption {
+	return func(r *rateLimit) {
+		r.costs[method+" "+route] = cost
+	}
+}
+
+// APIKeys sets keys which apikey policies count requests by, e.g. tokens of SCIM API. Requests with other
+// key or without one are counted by IP, so caller can't get fresh bucket by making key up.
+func APIKeys(keys ...string) RateLimitOption {
+	return func(r *rateLimit) {
+		r.apiKeys = keys
+	}
+}
+
+// rateLimitState is state of bucket of policy after request, which is reported in headers.
+type rateLimitState struct {
+	policy     ratelimit.Policy
+	allowed    bool
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+// tighterThan reports whether s should be reported instead of other: rejection with longer wait,
+// or fewer remaining tokens if both are allowed.
+func (s *rateLimitState) tighterThan(other *rateLimitState) bool {
+	if s.allowed != other.allowed {
+		return !s.allowed
+	}
+	if !s.allowed {
+		return s.retryAfter > other.retryAfter
+	}
+
+	return s.remaining < other.remaining
+}
+
+// RateLimit rejects requests with 429 when bucket of any matching policy is empty. Response carries
+// RateLimit-* headers of the most restrictive matching policy and Retry-After if rejected.
+// Requests are let through if store fails, so its outage does not take API down.
+func RateLimit(store rateLimitStore, policies []ratelimit.Policy, opts ...RateLimitOption) gin.HandlerFunc {
+	r := rateLimit{
+		store:    store,
+		policies: policies,
+		costs:    make(map[string]func(c *gin.Context) int),
+	}
+	for _, opt := range opts {
+		opt(&r)
+	}
+
+	return func(c *gin.Context) {
+		// Attempt replayed by Transaction middleware is part of request which has already taken its tokens.
+		if Replayed(c) {
+			c.Next()
+			return
+		}
+
+		route := c.FullPath()
+		cost := 1
+		if costOf, ok := r.costs[c.Request.Method+" "+route]; ok {
+			cost = max(costOf(c), 1)
+		}
+
+		var tightest *rateLimitState
+		for _, p := range r.policies {
+			if !p.Matches(c.Request.Method, route) {
+				continue
+			}
+
+			n := min(cost, p.Limit)
+			allowed, backlog, err := r.store.TakeRateLimitTokens(c, r.bucketKey(c, p), n, p.Interval(), p.Limit)
+			if err != nil {
+				logger.ErrorContext(c, "rate limit error", slog.String("error", err.Error()))
+				continue
+			}
+
+			state := &rateLimitState{policy: p, allowed: allowed, reset: backlog}
+			if allowed {
+				state.remaining = int((p.Interval()*time.Duration(p.Limit) - backlog) / p.Interval())
+			} else {
+				state.retryAfter = backlog + p.Interval()*time.Duration(n-p.Limit)
+				metrics.RateLimited(p.String())
+			}
+
+			if tightest == nil || state.tighterThan(tightest) {
+				tightest = state
+			}
+		}
+
+		if tightest == nil {
+			c.Next()
+			return
+		}
+
+		c.Header(ratelimit.HeaderLimit, strconv.Itoa(tightest.policy.Limit))
+		c.Header(ratelimit.HeaderRemaining, strconv.Itoa(tightest.remaining))
+		c.Header(ratelimit.HeaderReset, seconds(tightest.reset))
+		c.Header(ratelimit.HeaderPolicy, strconv.Itoa(tightest.policy.Limit)+";w="+seconds(tightest.policy.Period))
+
+		if !tightest.allowed {
+			c.Header(ratelimit.HeaderRetryAfter, seconds(tightest.retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, apperror.AppError{
+				Code:    apperror.AnyIntYouWantErrorCode,
+				Error:   apperror.TooManyRequestsErrorText,
+				Message: "Rate limit exceeded, retry later.",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// bucketKey returns key of bucket of request in policy. Identity of caller is hashed, so API keys are not stored.
+func (r rateLimit) bucketKey(c *gin.Context, p ratelimit.Policy) string {
+	identity := "ip:" + c.ClientIP()
+	switch p.Key {
+	case ratelimit.KeyUser:
+		if principal, ok := Principal(c); ok {
+			identity = "user:" + principal
+		}
+	case ratelimit.KeyAPIKey:
+		if key, ok := r.apiKey(c); ok {
+			identity = "apikey:" + key
+		}
+	}
+
+	sum := sha256.Sum256([]byte(p.String() + "\n" + identity))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKey returns API key of request, which is X-API-Key header or bearer token, if it is one of known keys.
+func (r rateLimit) apiKey(c *gin.Context) (string, bool) {
+	key := c.GetHeader(ratelimit.HeaderAPIKey)
+	if key == "" {
+		key, _ = bearerToken(c)
+	}
+	if key == "" {
+		return "", false
+	}
+
+	for _, k := range r.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// seconds formats d as whole seconds rounded up, as headers require.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(max(d, 0).Seconds())), 10)
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/srgklmv/comfortel/internal/domain/ratelimit"
+)
+
+// keyStore allows every request and counts requests by bucket key.
+type keyStore map[string]int
+
+func (s keyStore) TakeRateLimitTokens(_ context.Context, key string, _ int, _ time.Duration, _ int) (bool, time.Duration, error) {
+	s[key]++
+	return true, 0, nil
+}
+
+func TestRateLimitCountsByKnownAPIKeysOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy, err := ratelimit.ParsePolicy("GET /limited apikey 10/1m")
+	if err != nil {
+		t.Fatalf("ratelimit.ParsePolicy: %v", err)
+	}
+
+	store := keyStore{}
+	engine := gin.New()
+	engine.Use(RateLimit(store, []ratelimit.Policy{policy}, APIKeys("known-token")))
+	engine.GET("/limited", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	headers := []map[string]string{
+		{},
+		{ratelimit.HeaderAPIKey: "made-up-1"},
+		{"Authorization": "Bearer made-up-2"},
+		{ratelimit.HeaderAPIKey: "known-token"},
+		{"Authorization": "Bearer known-token"},
+	}
+	for _, h := range headers {
+		req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+		for k, v := range h {
+			req.Header.Set(k, v)
+		}
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Requests without key or with made-up ones share bucket of IP, known key has its own.
+	var counts []int
+	for _, n := range store {
+		counts = append(counts, n)
+	}
+	slices.Sort(counts)
+	if want := []int{2, 3}; !slices.Equal(counts, want) {
+		t.Errorf("requests by bucket %v, want %v", counts, want)
+	}
+}
+
+func TestRateLimitCountsByPrincipal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy, err := ratelimit.ParsePolicy("GET /limited user 10/1m")
+	if err != nil {
+		t.Fatalf("ratelimit.ParsePolicy: %v", err)
+	}
+
+	store := keyStore{}
+	engine := gin.New()
+	engine.Use(
+		Authenticate(map[string][]string{"scim": {"scim-1", "scim-2"}, "webhook": {"webhook-1"}}),
+		RateLimit(store, []ratelimit.Policy{policy}),
+	)
+	engine.GET("/limited", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	headers := []map[string]string{
+		{},
+		{"Authorization": "Bearer made-up"},
+		{ratelimit.HeaderAPIKey: "scim-1"},
+		{"Authorization": "Bearer scim-1"},
+		{"Authorization": "bearer scim-1"},
+		{"Authorization": "Bearer scim-2"},
+		{"Authorization": "Bearer webhook-1"},
+	}
+	for _, h := range headers {
+		req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+		for k, v := range h {
+			req.Header.Set(k, v)
+		}
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Unauthenticated requests share bucket of IP, each token has its own, whatever case of scheme is.
+	var counts []int
+	for _, n := range store {
+		counts = append(counts, n)
+	}
+	slices.Sort(counts)
+	if want := []int{1, 1, 2, 3}; !slices.Equal(counts, want) {
+		t.Errorf("requests by bucket %v, want %v", counts, want)
+	}
+}
+
+func TestBearerAuthSetsPrincipal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.GET("/guarded", BearerAuth("webhook", []string{"token"}), func(c *gin.Context) {
+		principal, _ := Principal(c)
+		c.String(http.StatusOK, principal)
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{name: "no token", status: http.StatusUnauthorized},
+		{name: "wrong token", authorization: "Bearer other", status: http.StatusUnauthorized},
+		{name: "basic scheme", authorization: "Basic token", status: http.StatusUnauthorized},
+		{name: "token", authorization: "Bearer token", status: http.StatusOK},
+		{name: "lowercase scheme", authorization: "bearer token", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/guarded", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d", w.Code, tt.status)
+			}
+			if w.Code == http.StatusOK && !strings.HasPrefix(w.Body.String(), "webhook:") {
+				t.Errorf("principal %q, want one of webhook realm", w.Body)
+			}
+		})
+	}
+}
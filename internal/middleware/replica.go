@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/srgklmv/comfortel/internal/repository"
+)
+
+// lastWriteCookie holds time of caller's last write in unix milliseconds, so instance other than one which
+// served write knows about it.
+const lastWriteCookie = "comfortel_last_write"
+
+type replicaReads struct {
+	window    time.Duration
+	mu        sync.Mutex
+	writes    map[string]time.Time
+	lastSweep time.Time
+}
+
+// ReplicaReads lets GET and HEAD requests read from replicas, see repository.PreferReplica. Caller who sent
+// request of other method within window reads from primary, so it sees its own writes despite replication lag.
+// Writes are remembered by instance which served them and in cookie, so clients keeping cookies read their
+// writes from any instance. Other clients do only if balancer sends them to same instance.
+// It must come before Transaction middleware.
+func ReplicaReads(window time.Duration) gin.HandlerFunc {
+	r := &replicaReads{
+		window: window,
+		writes: make(map[string]time.Time),
+	}
+
+	return r.handle
+}
+
+func (r *replicaReads) handle(c *gin.Context) {
+	caller := callerOf(c)
+
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		// Write is recorded before it is made, because response is sent before next middleware returns
+		// and next read of caller may come before that.
+		now := time.Now()
+		r.recordWrite(caller, now)
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(lastWriteCookie, strconv.FormatInt(now.UnixMilli(), 10), int(math.Ceil(r.window.Seconds())), "/", "", false, true)
+		c.Next()
+		return
+	}
+
+	if !r.wroteRecently(caller) && !r.cookieWroteRecently(c) {
+		c.Request = c.Request.WithContext(repository.PreferReplica(c.Request.Context()))
+	}
+	c.Next()
+}
+
+func (r *replicaReads) recordWrite(caller string, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.writes[caller] = now
+
+	if now.Sub(r.lastSweep) > r.window {
+		for k, t := range r.writes {
+			if now.Sub(t) > r.window {
+				delete(r.writes, k)
+			}
+		}
+		r.lastSweep = now
+	}
+}
+
+func (r *replicaReads) wroteRecently(caller string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.writes[caller]
+	return ok && time.Since(t) <= r.window
+}
+
+func (r *replicaReads) cookieWroteRecently(c *gin.Context) bool {
+	value, err := c.Cookie(lastWriteCookie)
+	if err != nil {
+		return false
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return time.Since(time.UnixMilli(ms)) <= r.window
+}
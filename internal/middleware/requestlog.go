@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+const (
+	HeaderRequestID = "X-Request-ID"
+	// maxRequestIDLength bounds id taken from client, longer ones are replaced.
+	maxRequestIDLength = 128
+	userIDKey          = "userID"
+)
+
+// SetUserID records id of user which request is about, to be written to access log.
+func SetUserID(c *gin.Context, id string) {
+	c.Set(userIDKey, id)
+}
+
+// RequestLog takes request id from X-Request-ID header or generates one, returns it in response header
+// and puts logger with request_id to request context, see logger.NewContext. After request it writes
+// access log record.
+func RequestLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Attempt replayed by Transaction middleware already carries logger and is logged as original request.
+		if Replayed(c) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+
+		id := c.GetHeader(HeaderRequestID)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Header(HeaderRequestID, id)
+
+		l := logger.With(slog.String("request_id", id))
+		c.Request = c.Request.WithContext(logger.NewContext(c.Request.Context(), l))
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if userID := c.GetString(userIDKey); userID != "" {
+			attrs = append(attrs, slog.String("user_id", userID))
+		}
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		l.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Recovery responds 500 to request which panicked and logs panic with request id instead of plain text
+// of gin.Recovery.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				if r == http.ErrAbortHandler {
+					panic(r)
+				}
+				logger.FromContext(c.Request.Context()).LogAttrs(c.Request.Context(), slog.LevelError, "panic recovered",
+					slog.String("error", fmt.Sprint(r)),
+					slog.String("stack", string(debug.Stack())),
+				)
+				c.AbortWithStatus(http.StatusInternalServerError)
+			}
+		}()
+
+		c.Next()
+	}
+}
+
+// validRequestID reports whether id from client is safe to log and return: not empty, not too long
+// and of printable ASCII without spaces.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Swappable is middleware which can be replaced while server is running, e.g. after config reload.
+type Swappable struct {
+	handler atomic.Pointer[gin.HandlerFunc]
+}
+
+func NewSwappable(handler gin.HandlerFunc) *Swappable {
+	s := &Swappable{}
+	s.Swap(handler)
+
+	return s
+}
+
+// Swap replaces middleware for requests which come after it.
+func (s *Swappable) Swap(handler gin.HandlerFunc) {
+	s.handler.Store(&handler)
+}
+
+func (s *Swappable) Handle(c *gin.Context) {
+	(*s.handler.Load())(c)
+}
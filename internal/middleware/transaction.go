@@ -1,29 +1,301 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	"github.com/srgklmv/comfortel/internal/repository"
 	"github.com/srgklmv/comfortel/pkg/logger"
 )
 
-func Transaction(conn *sql.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		tx, err := conn.BeginTx(c.Request.Context(), &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+const (
+	defaultMaxRetries = 3
+	retryBackoff      = 20 * time.Millisecond
+)
+
+// errRollback makes transactor roll back transaction of failed request.
+var errRollback = errors.New("request failed")
+
+type transactor interface {
+	WithinTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error
+}
+
+type transaction struct {
+	transactor transactor
+	replay     http.Handler
+	isolation  sql.IsolationLevel
+	readOnly   *bool
+	maxRetries int
+}
+
+type TransactionOption func(*transaction)
+
+// Isolation sets isolation level of transaction. Default is read committed.
+func Isolation(level sql.IsolationLevel) TransactionOption {
+	return func(t *transaction) {
+		t.isolation = level
+	}
+}
+
+// ReadOnly overrides default, which is read-only transaction for GET and HEAD requests only.
+func ReadOnly(readOnly bool) TransactionOption {
+	return func(t *transaction) {
+		t.readOnly = &readOnly
+	}
+}
+
+// MaxRetries sets number of retries after serialization failure or deadlock. Default is 3, 0 disables retries.
+func MaxRetries(n int) TransactionOption {
+	return func(t *transaction) {
+		t.maxRetries = n
+	}
+}
+
+// Transaction runs rest of handlers in transaction, which is carried by request context. It is committed if response status is below 400
+// and rolled back otherwise, or if handler panics. Response is buffered until then, so client
+// never gets response of rolled back attempt.
+//
+// If transaction fails with serialization failure or deadlock, request is served again in fresh
+// transaction by replay, which must be engine the route is registered in. Routes that don't need
+// transaction are registered without this middleware.
+func Transaction(transactor transactor, replay http.Handler, opts ...TransactionOption) gin.HandlerFunc {
+	t := transaction{
+		transactor: transactor,
+		replay:     replay,
+		isolation:  sql.LevelReadCommitted,
+		maxRetries: defaultMaxRetries,
+	}
+	for _, opt := range opts {
+		opt(&t)
+	}
+
+	return t.handle
+}
+
+// attemptKey is request context key of attempt state. It marks replayed requests, which are
+// served once and report retryable failure back to first attempt instead of retrying themselves.
+type attemptKey struct{}
+
+type attemptState struct {
+	final     bool
+	retryable bool
+}
+
+// Replayed reports whether request is attempt replayed by Transaction middleware. It goes through all
+// middlewares again, so ones which must see request once skip it.
+func Replayed(c *gin.Context) bool {
+	_, replayed := c.Request.Context().Value(attemptKey{}).(*attemptState)
+	return replayed
+}
+
+func (t transaction) handle(c *gin.Context) {
+	if state, ok := c.Request.Context().Value(attemptKey{}).(*attemptState); ok {
+		state.retryable = t.run(c, state.final)
+		return
+	}
+
+	var body []byte
+	if t.maxRetries > 0 && c.Request.Body != nil {
+		var err error
+		body, err = io.ReadAll(c.Request.Body)
 		if err != nil {
-			logger.Error("transaction begin err:", err)
-			c.AbortWithError(http.StatusInternalServerError, err)
-			c.Next()
+			c.AbortWithStatusJSON(http.StatusBadRequest, apperror.AppError{
+				Code:    apperror.AnyIntYouWantErrorCode,
+				Error:   apperror.BadRequestErrorText,
+				Message: "Request body invalid.",
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if !t.run(c, t.maxRetries == 0) {
+		return
+	}
+
+	for attempt := 1; attempt <= t.maxRetries; attempt++ {
+		logger.WarnContext(c, "transaction retry", slog.String("path", c.FullPath()), slog.Int("attempt", attempt))
+
+		delay := retryBackoff<<attempt + time.Duration(rand.Int64N(int64(retryBackoff)))
+		select {
+		case <-c.Request.Context().Done():
+			c.AbortWithStatus(http.StatusServiceUnavailable)
+			return
+		case <-time.After(delay):
 		}
 
-		c.Set("tx", tx)
+		state := &attemptState{final: attempt == t.maxRetries}
+		req := c.Request.Clone(context.WithValue(c.Request.Context(), attemptKey{}, state))
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		rec := newRecorder()
+		t.replay.ServeHTTP(rec, req)
+
+		if !state.retryable {
+			rec.copyTo(c.Writer)
+			c.Abort()
+			return
+		}
+	}
+}
+
+// run serves request in single transaction. It returns true if transaction failed with retryable error
+// and response is discarded, which happens only if attempt is not final.
+func (t transaction) run(c *gin.Context, final bool) bool {
+	readOnly := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+	if t.readOnly != nil {
+		readOnly = *t.readOnly
+	}
+
+	writer := &bufferedWriter{ResponseWriter: c.Writer, header: http.Header{}, status: http.StatusOK}
+	c.Writer = writer
+	request := c.Request
+	// Panic goes on to recovery middleware with original writer, transaction is rolled back by transactor.
+	defer func() {
+		c.Writer = writer.ResponseWriter
+		c.Request = request
+	}()
+
+	var tx *repository.Tx
+	opts := &sql.TxOptions{Isolation: t.isolation, ReadOnly: readOnly}
+	err := t.transactor.WithinTx(c.Request.Context(), opts, func(ctx context.Context) error {
+		tx, _ = repository.TxFromContext(ctx)
+		c.Request = c.Request.WithContext(ctx)
 		c.Next()
 
-		err = tx.Commit()
-		if err != nil {
-			logger.Error("transaction commit err:", err)
-			c.AbortWithError(http.StatusInternalServerError, err)
+		if writer.Status() >= http.StatusBadRequest {
+			return errRollback
 		}
+		return nil
+	})
+
+	switch {
+	case err == nil:
+	case errors.Is(err, errRollback):
+		if tx != nil && tx.Retryable() && !final {
+			return true
+		}
+	default:
+		if tx != nil && tx.Retryable() && !final {
+			return true
+		}
+
+		logger.ErrorContext(c, "transaction error", slog.String("error", err.Error()))
+		writer.reset()
+		c.AbortWithStatusJSON(http.StatusInternalServerError, apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		})
+	}
+
+	writer.flush()
+	return false
+}
+
+// bufferedWriter holds response until transaction outcome is known.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+	wrote  bool
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.wrote {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.wrote = true
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.wrote = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.wrote {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.wrote
+}
+
+// reset discards buffered response.
+func (w *bufferedWriter) reset() {
+	w.header = http.Header{}
+	w.status = http.StatusOK
+	w.body.Reset()
+	w.wrote = false
+}
+
+// Flush does nothing, because response may not be sent before commit.
+func (w *bufferedWriter) Flush() {}
+
+func (w *bufferedWriter) flush() {
+	for k, v := range w.header {
+		w.ResponseWriter.Header()[k] = v
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+}
+
+// recorder collects response of replayed request.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newRecorder() *recorder {
+	return &recorder{header: http.Header{}, status: http.StatusOK}
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(code int) {
+	r.status = code
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+func (r *recorder) copyTo(w gin.ResponseWriter) {
+	for k, v := range r.header {
+		w.Header()[k] = v
 	}
+	w.WriteHeader(r.status)
+	w.WriteHeaderNow()
+	_, _ = w.Write(r.body.Bytes())
 }
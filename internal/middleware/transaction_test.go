@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/srgklmv/comfortel/internal/metrics"
+	"github.com/srgklmv/comfortel/internal/repository"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+// fakeDB is database whose statements fail with serialization failure while failures are left.
+type fakeDB struct {
+	mu        sync.Mutex
+	beginErr  error
+	failures  int
+	commits   int
+	rollbacks int
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{db}, nil }
+func (db *fakeDB) Driver() driver.Driver                        { return nil }
+
+func (db *fakeDB) count(n *int) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	*n++
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	if c.db.beginErr != nil {
+		return nil, c.db.beginErr
+	}
+	return fakeTx(c), nil
+}
+
+func (c fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	if c.db.failures > 0 {
+		c.db.failures--
+		return nil, &pq.Error{Code: "40001"}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx fakeConn
+
+func (t fakeTx) Commit() error   { t.db.count(&t.db.commits); return nil }
+func (t fakeTx) Rollback() error { t.db.count(&t.db.rollbacks); return nil }
+
+// transactionServer serves route /tx/:status which executes statement in transaction and responds status,
+// or panics if status is "panic". Statement failure is responded with 500.
+type transactionServer struct {
+	engine *gin.Engine
+	db     *fakeDB
+	calls  int
+	log    *bytes.Buffer
+}
+
+func newTransactionServer(t *testing.T, db *fakeDB, opts ...TransactionOption) *transactionServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	s := &transactionServer{engine: gin.New(), db: db, log: &bytes.Buffer{}}
+	logger.SetDefault(slog.New(slog.NewJSONHandler(s.log, nil)))
+
+	conn := sql.OpenDB(db)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	s.engine.ContextWithFallback = true
+	s.engine.Use(RequestLog(), Recovery(), metrics.HTTP(metrics.SkipRequests(Replayed)))
+	s.engine.POST("/tx/:status", Transaction(repository.NewTransactor(conn), s.engine, opts...), func(c *gin.Context) {
+		s.calls++
+		tx, _ := repository.TxFromContext(c)
+		if _, err := tx.ExecContext(c, "update"); err != nil {
+			c.String(http.StatusInternalServerError, "failed")
+			return
+		}
+
+		if c.Param("status") == "panic" {
+			panic("handler failed")
+		}
+		c.String(map[string]int{"ok": http.StatusOK, "not-found": http.StatusNotFound}[c.Param("status")], c.Param("status"))
+	})
+
+	return s
+}
+
+func (s *transactionServer) do(status string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/tx/"+status, strings.NewReader("body")))
+	return w
+}
+
+// logged returns number of log records with message msg.
+func (s *transactionServer) logged(msg string) int {
+	return strings.Count(s.log.String(), `"msg":"`+msg+`"`)
+}
+
+// requestsCounted returns number of requests to route with status recorded in metrics.
+func requestsCounted(t *testing.T, route, status string) float64 {
+	t.Helper()
+
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Registry.Gather: %v", err)
+	}
+
+	var total float64
+	for _, f := range families {
+		if f.GetName() != "comfortel_http_requests_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["route"] == route && labels["status"] == status {
+				total += m.GetCounter().GetValue()
+			}
+		}
+	}
+
+	return total
+}
+
+func TestTransactionCommitsOrRollsBack(t *testing.T) {
+	tests := []struct {
+		status    string
+		code      int
+		commits   int
+		rollbacks int
+	}{
+		{status: "ok", code: http.StatusOK, commits: 1},
+		{status: "not-found", code: http.StatusNotFound, rollbacks: 1},
+		{status: "panic", code: http.StatusInternalServerError, rollbacks: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			s := newTransactionServer(t, &fakeDB{})
+
+			w := s.do(tt.status)
+			if w.Code != tt.code {
+				t.Errorf("status %d, want %d", w.Code, tt.code)
+			}
+			if s.db.commits != tt.commits || s.db.rollbacks != tt.rollbacks {
+				t.Errorf("%d commits and %d rollbacks, want %d and %d", s.db.commits, s.db.rollbacks, tt.commits, tt.rollbacks)
+			}
+		})
+	}
+}
+
+func TestTransactionBeginFailure(t *testing.T) {
+	s := newTransactionServer(t, &fakeDB{beginErr: errors.New("connection refused")})
+
+	if w := s.do("ok"); w.Code != http.StatusInternalServerError {
+		t.Errorf("status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if s.calls != 0 {
+		t.Errorf("handler is called %d times without transaction", s.calls)
+	}
+}
+
+func TestTransactionRetriesSerializationFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		code      int
+		body      string
+		commits   int
+		rollbacks int
+	}{
+		{name: "succeeds on last retry", failures: 3, code: http.StatusOK, body: "ok", commits: 1, rollbacks: 3},
+		{name: "fails every attempt", failures: 10, code: http.StatusInternalServerError, body: "failed", rollbacks: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const maxRetries = 3
+			s := newTransactionServer(t, &fakeDB{failures: tt.failures}, MaxRetries(maxRetries))
+			route, status := "/tx/:status", "200"
+			if tt.code != http.StatusOK {
+				status = "500"
+			}
+			before := requestsCounted(t, route, status)
+
+			w := s.do("ok")
+			if w.Code != tt.code || w.Body.String() != tt.body {
+				t.Errorf("response %d %q, want %d %q", w.Code, w.Body, tt.code, tt.body)
+			}
+			if s.calls != maxRetries+1 {
+				t.Errorf("handler is called %d times, want %d", s.calls, maxRetries+1)
+			}
+			if s.db.commits != tt.commits || s.db.rollbacks != tt.rollbacks {
+				t.Errorf("%d commits and %d rollbacks, want %d and %d", s.db.commits, s.db.rollbacks, tt.commits, tt.rollbacks)
+			}
+			if got := s.logged("transaction retry"); got != maxRetries {
+				t.Errorf("%d retries logged, want %d", got, maxRetries)
+			}
+			if got := s.logged("request"); got != 1 {
+				t.Errorf("%d requests logged, want 1", got)
+			}
+			if got := requestsCounted(t, route, status) - before; got != 1 {
+				t.Errorf("%v requests counted, want 1", got)
+			}
+		})
+	}
+}
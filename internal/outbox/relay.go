@@ -0,0 +1,213 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/srgklmv/comfortel/internal/domain/event"
+	"github.com/srgklmv/comfortel/pkg/logger"
+	"github.com/srgklmv/comfortel/pkg/publisher"
+)
+
+const (
+	pollInterval   = time.Second
+	batchSize      = 100
+	publishTimeout = 10 * time.Second
+	// claimLease must be longer than publishTimeout, batch stops publishing when it is about to expire.
+	claimLease = time.Minute
+)
+
+type transactor interface {
+	WithinTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error
+}
+
+type outboxRepository interface {
+	LockOutbox(ctx context.Context) (bool, error)
+	SequenceOutbox(ctx context.Context) error
+	ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]event.Record, error)
+	MarkPublished(ctx context.Context, seqs []int64) error
+	MarkFailed(ctx context.Context, seq int64, reason string) error
+	ReleaseOutbox(ctx context.Context, seqs []int64) error
+}
+
+// relay publishes outbox events in order of commit. Events are claimed with lease in short transaction,
+// published without one and marked published only after publisher accepted them, so delivery is
+// at-least-once and consumers should deduplicate by event id.
+// If event of some user fails, rest of that user's events wait for next batch, which keeps order per user.
+type relay struct {
+	transactor transactor
+	repo       outboxRepository
+	publisher  publisher.Publisher
+}
+
+func NewRelay(transactor transactor, repo outboxRepository, publisher publisher.Publisher) *relay {
+	return &relay{
+		transactor: transactor,
+		repo:       repo,
+		publisher:  publisher,
+	}
+}
+
+// Run publishes events until ctx is cancelled.
+func (r *relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := r.processBatch(ctx)
+		if err != nil {
+			logger.Error("outbox relay error", slog.String("error", err.Error()))
+		}
+		// Full batch means there may be more events, so don't wait for next tick.
+		if err == nil && n == batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// outcome is result of publishing claimed batch.
+type outcome struct {
+	published []int64
+	failed    []failure
+	// skipped are events not tried because of failed event of the same user or stopping relay.
+	skipped []int64
+}
+
+type failure struct {
+	seq    int64
+	reason string
+}
+
+// processBatch publishes one batch of events and returns number of claimed ones.
+func (r *relay) processBatch(ctx context.Context) (int, error) {
+	if ctx.Err() != nil {
+		return 0, nil
+	}
+
+	records, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	result := r.publishAll(ctx, records, time.Now().Add(claimLease-publishTimeout))
+
+	if err = r.complete(ctx, result); err != nil {
+		return 0, err
+	}
+
+	return len(records), nil
+}
+
+// claim sequences committed events and leases oldest unpublished ones. Lease is committed before
+// publishing, so slow publisher holds neither row locks nor connection.
+// Claim is made under advisory lock, so concurrent relays don't claim same events.
+func (r *relay) claim(ctx context.Context) ([]event.Record, error) {
+	var records []event.Record
+	opts := &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+	err := r.transactor.WithinTx(ctx, opts, func(txCtx context.Context) error {
+		locked, err := r.repo.LockOutbox(txCtx)
+		if err != nil {
+			return fmt.Errorf("repo.LockOutbox: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+
+		if err = r.repo.SequenceOutbox(txCtx); err != nil {
+			return fmt.Errorf("repo.SequenceOutbox: %w", err)
+		}
+
+		records, err = r.repo.ClaimOutbox(txCtx, batchSize, claimLease)
+		if err != nil {
+			return fmt.Errorf("repo.ClaimOutbox: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// publishAll publishes records in order until deadline, after which lease may expire and events
+// may be claimed by other relay.
+func (r *relay) publishAll(ctx context.Context, records []event.Record, deadline time.Time) outcome {
+	var result outcome
+	blocked := make(map[uuid.UUID]struct{})
+	for _, rec := range records {
+		if _, ok := blocked[rec.AggregateID]; ok || ctx.Err() != nil || time.Now().After(deadline) {
+			result.skipped = append(result.skipped, rec.Seq)
+			continue
+		}
+
+		if err := r.publish(ctx, rec); err != nil {
+			blocked[rec.AggregateID] = struct{}{}
+			logger.Error(
+				"outbox event publish error",
+				slog.Int64("seq", rec.Seq),
+				slog.String("event_id", rec.ID.String()),
+				slog.String("error", err.Error()),
+			)
+			result.failed = append(result.failed, failure{seq: rec.Seq, reason: err.Error()})
+			continue
+		}
+		result.published = append(result.published, rec.Seq)
+	}
+
+	return result
+}
+
+// complete saves result of batch and releases its lease.
+func (r *relay) complete(ctx context.Context, result outcome) error {
+	// Transaction is not bound to ctx, so results of published events are saved even if relay is stopping.
+	// Otherwise they would be sent again.
+	opts := &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+	return r.transactor.WithinTx(context.WithoutCancel(ctx), opts, func(txCtx context.Context) error {
+		if len(result.published) > 0 {
+			if err := r.repo.MarkPublished(txCtx, result.published); err != nil {
+				return fmt.Errorf("repo.MarkPublished: %w", err)
+			}
+		}
+
+		for _, f := range result.failed {
+			if err := r.repo.MarkFailed(txCtx, f.seq, f.reason); err != nil {
+				return fmt.Errorf("repo.MarkFailed: %w", err)
+			}
+		}
+
+		if len(result.skipped) > 0 {
+			if err := r.repo.ReleaseOutbox(txCtx, result.skipped); err != nil {
+				return fmt.Errorf("repo.ReleaseOutbox: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *relay) publish(ctx context.Context, rec event.Record) error {
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+
+	return r.publisher.Publish(ctx, publisher.Message{
+		ID:      rec.ID.String(),
+		Type:    rec.Type,
+		Key:     rec.AggregateID.String(),
+		Payload: rec.Payload,
+	})
+}
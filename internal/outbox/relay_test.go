@@ -0,0 +1,208 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/srgklmv/comfortel/internal/domain/event"
+	"github.com/srgklmv/comfortel/pkg/logger"
+	"github.com/srgklmv/comfortel/pkg/publisher"
+	"github.com/srgklmv/comfortel/pkg/publisher/natstest"
+)
+
+type storedRecord struct {
+	rec       event.Record
+	claimed   bool
+	published bool
+	lastError string
+}
+
+// fakeOutbox keeps outbox in memory and tracks whether transaction is open.
+type fakeOutbox struct {
+	records []*storedRecord
+	inTx    bool
+}
+
+func (f *fakeOutbox) WithinTx(ctx context.Context, _ *sql.TxOptions, fn func(ctx context.Context) error) error {
+	f.inTx = true
+	defer func() { f.inTx = false }()
+
+	return fn(ctx)
+}
+
+func (f *fakeOutbox) add(aggregateID uuid.UUID) event.Record {
+	seq := int64(len(f.records) + 1)
+	rec := event.Record{Seq: seq, ID: uuid.New(), Type: "user.updated", AggregateID: aggregateID, Payload: []byte(`{}`)}
+	f.records = append(f.records, &storedRecord{rec: rec})
+
+	return rec
+}
+
+func (f *fakeOutbox) LockOutbox(context.Context) (bool, error) {
+	return true, nil
+}
+
+func (f *fakeOutbox) SequenceOutbox(context.Context) error {
+	var last int64
+	for _, r := range f.records {
+		last = max(last, r.rec.Position)
+	}
+	for _, r := range f.records {
+		if r.rec.Position == 0 {
+			last++
+			r.rec.Position = last
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeOutbox) ClaimOutbox(_ context.Context, limit int, _ time.Duration) ([]event.Record, error) {
+	var records []event.Record
+	for _, r := range f.records {
+		if !r.published && !r.claimed && len(records) < limit {
+			r.claimed = true
+			records = append(records, r.rec)
+		}
+	}
+
+	return records, nil
+}
+
+func (f *fakeOutbox) MarkPublished(_ context.Context, seqs []int64) error {
+	for _, r := range f.records {
+		if slices.Contains(seqs, r.rec.Seq) {
+			r.published, r.claimed, r.lastError = true, false, ""
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeOutbox) MarkFailed(_ context.Context, seq int64, reason string) error {
+	r := f.records[seq-1]
+	r.claimed, r.lastError = false, reason
+	r.rec.Attempts++
+
+	return nil
+}
+
+func (f *fakeOutbox) ReleaseOutbox(_ context.Context, seqs []int64) error {
+	for _, r := range f.records {
+		if slices.Contains(seqs, r.rec.Seq) {
+			r.claimed = false
+		}
+	}
+
+	return nil
+}
+
+// txChecker fails publishes made while transaction of outbox is open.
+type txChecker struct {
+	publisher.Publisher
+	outbox *fakeOutbox
+}
+
+func (c txChecker) Publish(ctx context.Context, msg publisher.Message) error {
+	if c.outbox.inTx {
+		return errors.New("published within transaction")
+	}
+
+	return c.Publisher.Publish(ctx, msg)
+}
+
+func newRelay(t *testing.T) (*relay, *fakeOutbox, *natstest.Server) {
+	t.Helper()
+	logger.SetDefault(slog.New(slog.DiscardHandler))
+
+	server, err := natstest.NewServer()
+	if err != nil {
+		t.Fatalf("natstest.NewServer: %v", err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+
+	p, err := publisher.NewNATS(server.URL(), "comfortel")
+	if err != nil {
+		t.Fatalf("publisher.NewNATS: %v", err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+
+	outbox := &fakeOutbox{}
+	return NewRelay(outbox, outbox, txChecker{Publisher: p, outbox: outbox}), outbox, server
+}
+
+// publishedIDs returns ids of messages stored by server in order of arrival.
+func publishedIDs(server *natstest.Server) []string {
+	var ids []string
+	for _, msg := range server.Messages() {
+		ids = append(ids, msg.Header.Get(jetstream.MsgIDHeader))
+	}
+
+	return ids
+}
+
+func TestRelayPublishesClaimedEventsAfterCommit(t *testing.T) {
+	r, outbox, server := newRelay(t)
+	ctx := context.Background()
+
+	userA, userB := uuid.New(), uuid.New()
+	want := []string{outbox.add(userA).ID.String(), outbox.add(userB).ID.String(), outbox.add(userA).ID.String()}
+
+	n, err := r.processBatch(ctx)
+	if err != nil {
+		t.Fatalf("processBatch: %v", err)
+	}
+	if n != len(want) {
+		t.Fatalf("processed %d events, want %d", n, len(want))
+	}
+
+	if got := publishedIDs(server); !slices.Equal(got, want) {
+		t.Fatalf("published %v, want %v", got, want)
+	}
+	for _, r := range outbox.records {
+		if !r.published || r.claimed || r.lastError != "" {
+			t.Errorf("event %d: published %t, claimed %t, error %q", r.rec.Seq, r.published, r.claimed, r.lastError)
+		}
+	}
+}
+
+func TestRelayKeepsOrderOfUserAfterFailure(t *testing.T) {
+	r, outbox, server := newRelay(t)
+	ctx := context.Background()
+
+	userA, userB := uuid.New(), uuid.New()
+	a1, b1, a2 := outbox.add(userA), outbox.add(userB), outbox.add(userA)
+
+	server.FailNext(1)
+	if _, err := r.processBatch(ctx); err != nil {
+		t.Fatalf("processBatch: %v", err)
+	}
+
+	// First event of user A is rejected, so second one waits and only event of user B goes out.
+	if got, want := publishedIDs(server), []string{b1.ID.String()}; !slices.Equal(got, want) {
+		t.Fatalf("published %v, want %v", got, want)
+	}
+	failed := outbox.records[a1.Seq-1]
+	if failed.published || failed.claimed || failed.lastError == "" || failed.rec.Attempts != 1 {
+		t.Fatalf("failed event: published %t, claimed %t, error %q, attempts %d",
+			failed.published, failed.claimed, failed.lastError, failed.rec.Attempts)
+	}
+	if skipped := outbox.records[a2.Seq-1]; skipped.published || skipped.claimed {
+		t.Fatalf("skipped event: published %t, claimed %t", skipped.published, skipped.claimed)
+	}
+
+	if _, err := r.processBatch(ctx); err != nil {
+		t.Fatalf("processBatch: %v", err)
+	}
+	want := []string{b1.ID.String(), a1.ID.String(), a2.ID.String()}
+	if got := publishedIDs(server); !slices.Equal(got, want) {
+		t.Fatalf("published %v, want %v", got, want)
+	}
+}
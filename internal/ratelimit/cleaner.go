@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+const cleanInterval = 10 * time.Minute
+
+type bucketRepository interface {
+	DeleteFullRateLimits(ctx context.Context) (int64, error)
+}
+
+// cleaner removes full buckets from database. Missing bucket is full anyway, so it only keeps table small.
+type cleaner struct {
+	repo bucketRepository
+}
+
+func NewCleaner(repo bucketRepository) *cleaner {
+	return &cleaner{repo: repo}
+}
+
+// Run removes full buckets periodically until ctx is cancelled.
+func (c *cleaner) Run(ctx context.Context) {
+	ticker := time.NewTicker(cleanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := c.repo.DeleteFullRateLimits(ctx)
+		if err != nil {
+			logger.Error("rate limits clean error", slog.String("error", err.Error()))
+			continue
+		}
+		if n > 0 {
+			logger.Debug("full rate limit buckets removed", slog.Int64("count", n))
+		}
+	}
+}
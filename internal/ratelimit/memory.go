@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often full buckets are removed from memory.
+const sweepInterval = time.Minute
+
+// memoryStore keeps buckets in memory of single instance, see repository.TakeRateLimitTokens for shared one.
+type memoryStore struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *memoryStore {
+	return &memoryStore{tats: make(map[string]time.Time)}
+}
+
+// TakeRateLimitTokens takes n tokens from bucket of key, which holds limit tokens and gets one back each interval.
+// Bucket is kept as theoretical arrival time of next request (GCRA). Backlog is time until bucket is full again.
+func (s *memoryStore) TakeRateLimitTokens(_ context.Context, key string, n int, interval time.Duration, limit int) (bool, time.Duration, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > sweepInterval {
+		for k, tat := range s.tats {
+			if !tat.After(now) {
+				delete(s.tats, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	tat := s.tats[key]
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval * time.Duration(n))
+	if next.Sub(now) > interval*time.Duration(limit) {
+		return false, tat.Sub(now), nil
+	}
+	s.tats[key] = next
+
+	return true, next.Sub(now), nil
+}
@@ -0,0 +1,485 @@
+// Package contract tests that repository implementation behaves like one usecases were written against.
+// Tests are shared by postgres, sqlite and in-memory repositories, so the latter can stand in for the former.
+package contract
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+)
+
+type UserRepository interface {
+	GetUserByLogin(ctx context.Context, login string) (userDomain.User, error)
+	GetUserByEmail(ctx context.Context, email string) (userDomain.User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (userDomain.User, error)
+	GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]userDomain.User, error)
+	GetUsersByLogins(ctx context.Context, logins []string) ([]userDomain.User, error)
+	FindUsers(ctx context.Context, filter userDomain.Filter) ([]userDomain.User, int, error)
+	CreateUser(ctx context.Context, data userDomain.User, hashedPassword string) (uuid.UUID, error)
+	UpdateUser(ctx context.Context, data userDomain.User) (userDomain.User, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
+}
+
+type Transactor interface {
+	WithinTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error
+}
+
+// Repository is user repository together with transactor its transactions go through.
+type Repository interface {
+	UserRepository
+	Transactor
+}
+
+// errRollback is returned from units of work which tests roll back.
+var errRollback = errors.New("rollback")
+
+var tests = []struct {
+	name string
+	run  func(ctx context.Context, e *env)
+}{
+	{name: "create and get", run: testCreateAndGet},
+	{name: "unique login", run: testUniqueLogin},
+	{name: "unique email", run: testUniqueEmail},
+	{name: "not found", run: testNotFound},
+	{name: "update", run: testUpdate},
+	{name: "delete", run: testDelete},
+	{name: "get many", run: testGetMany},
+	{name: "find", run: testFind},
+	{name: "find past end", run: testFindPastEnd},
+	{name: "commit", run: testCommit},
+	{name: "rollback", run: testRollback},
+	{name: "savepoint", run: testSavepoint},
+	{name: "panic", run: testPanic},
+}
+
+// Test runs contract as subtests of t, each against repository returned by newRepo.
+// Users created by tests have unique logins and are deleted afterwards, so database doesn't need to be empty.
+func Test(t *testing.T, newRepo func(t *testing.T) Repository) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			e := &env{t: t, repo: newRepo(t), prefix: "contract" + uuid.NewString()[:8]}
+			t.Cleanup(func() { e.cleanup(ctx) })
+
+			tt.run(ctx, e)
+		})
+	}
+}
+
+// env is state of single test.
+type env struct {
+	t       *testing.T
+	repo    Repository
+	prefix  string
+	created []uuid.UUID
+}
+
+// user returns valid user with login and email unique to test.
+func (e *env) user(name string) userDomain.User {
+	return userDomain.User{
+		Login:     e.prefix + name,
+		Email:     e.prefix + name + "@example.com",
+		FirstName: "First",
+		LastName:  "Last",
+		Sex:       "male",
+		Age:       30,
+	}
+}
+
+// tryCreate creates user and remembers it to be deleted after test.
+func (e *env) tryCreate(ctx context.Context, u userDomain.User) (uuid.UUID, error) {
+	id, err := e.repo.CreateUser(ctx, u, "hashed")
+	if err == nil {
+		e.created = append(e.created, id)
+	}
+
+	return id, err
+}
+
+func (e *env) create(ctx context.Context, u userDomain.User) uuid.UUID {
+	e.t.Helper()
+
+	id, err := e.tryCreate(ctx, u)
+	if err != nil {
+		e.t.Fatalf("repo.CreateUser: %v", err)
+	}
+
+	return id
+}
+
+func (e *env) get(ctx context.Context, id uuid.UUID) userDomain.User {
+	e.t.Helper()
+
+	u, err := e.repo.GetUserByID(ctx, id)
+	if err != nil {
+		e.t.Fatalf("repo.GetUserByID: %v", err)
+	}
+
+	return u
+}
+
+func (e *env) cleanup(ctx context.Context) {
+	for _, id := range e.created {
+		_, err := e.repo.DeleteUser(ctx, id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			e.t.Errorf("cleanup: repo.DeleteUser: %v", err)
+		}
+	}
+}
+
+// exists reports whether user can be got by id outside of any transaction.
+func (e *env) exists(ctx context.Context, id uuid.UUID) bool {
+	e.t.Helper()
+
+	_, err := e.repo.GetUserByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		e.t.Fatalf("repo.GetUserByID: %v", err)
+	}
+
+	return true
+}
+
+func testCreateAndGet(ctx context.Context, e *env) {
+	want := e.user("alice")
+	id := e.create(ctx, want)
+
+	byID := e.get(ctx, id)
+	if byID.ID != id || byID.Login != want.Login || byID.Email != want.Email || byID.FirstName != want.FirstName ||
+		byID.LastName != want.LastName || byID.Sex != want.Sex || byID.Age != want.Age {
+		e.t.Fatalf("got %+v, want %+v", byID, want)
+	}
+	if !byID.IsActive {
+		e.t.Error("created user is not active")
+	}
+	if byID.CreatedAt.IsZero() || byID.UpdatedAt.IsZero() {
+		e.t.Error("timestamps are not set")
+	}
+
+	byLogin, err := e.repo.GetUserByLogin(ctx, strings.ToUpper(want.Login))
+	if err != nil {
+		e.t.Fatalf("repo.GetUserByLogin: %v", err)
+	}
+	if byLogin.ID != id {
+		e.t.Errorf("login lookup is not case-insensitive: got %s, want %s", byLogin.ID, id)
+	}
+
+	byEmail, err := e.repo.GetUserByEmail(ctx, strings.ToUpper(want.Email))
+	if err != nil {
+		e.t.Fatalf("repo.GetUserByEmail: %v", err)
+	}
+	if byEmail.ID != id {
+		e.t.Errorf("email lookup is not case-insensitive: got %s, want %s", byEmail.ID, id)
+	}
+}
+
+func testUniqueLogin(ctx context.Context, e *env) {
+	e.create(ctx, e.user("bob"))
+
+	duplicate := e.user("bob")
+	duplicate.Login = strings.ToUpper(duplicate.Login)
+	duplicate.Email = e.prefix + "other@example.com"
+	if _, err := e.tryCreate(ctx, duplicate); !errors.Is(err, userDomain.ErrLoginTaken) {
+		e.t.Errorf("got %v, want %v", err, userDomain.ErrLoginTaken)
+	}
+}
+
+func testUniqueEmail(ctx context.Context, e *env) {
+	e.create(ctx, e.user("carol"))
+
+	duplicate := e.user("dave")
+	duplicate.Email = strings.ToUpper(e.user("carol").Email)
+	if _, err := e.tryCreate(ctx, duplicate); !errors.Is(err, userDomain.ErrEmailTaken) {
+		e.t.Errorf("create: got %v, want %v", err, userDomain.ErrEmailTaken)
+	}
+
+	id := e.create(ctx, e.user("dave"))
+	_, err := e.repo.UpdateUser(ctx, userDomain.User{ID: id, Email: e.user("carol").Email, IsActive: true})
+	if !errors.Is(err, userDomain.ErrEmailTaken) {
+		e.t.Errorf("update: got %v, want %v", err, userDomain.ErrEmailTaken)
+	}
+}
+
+func testNotFound(ctx context.Context, e *env) {
+	missing := uuid.New()
+
+	if _, err := e.repo.GetUserByID(ctx, missing); !errors.Is(err, sql.ErrNoRows) {
+		e.t.Errorf("repo.GetUserByID: got %v, want %v", err, sql.ErrNoRows)
+	}
+	if _, err := e.repo.GetUserByLogin(ctx, e.prefix+"nobody"); !errors.Is(err, sql.ErrNoRows) {
+		e.t.Errorf("repo.GetUserByLogin: got %v, want %v", err, sql.ErrNoRows)
+	}
+	if _, err := e.repo.GetUserByEmail(ctx, e.prefix+"nobody@example.com"); !errors.Is(err, sql.ErrNoRows) {
+		e.t.Errorf("repo.GetUserByEmail: got %v, want %v", err, sql.ErrNoRows)
+	}
+	if _, err := e.repo.UpdateUser(ctx, userDomain.User{ID: missing, FirstName: "Name"}); !errors.Is(err, sql.ErrNoRows) {
+		e.t.Errorf("repo.UpdateUser: got %v, want %v", err, sql.ErrNoRows)
+	}
+	if _, err := e.repo.DeleteUser(ctx, missing); !errors.Is(err, sql.ErrNoRows) {
+		e.t.Errorf("repo.DeleteUser: got %v, want %v", err, sql.ErrNoRows)
+	}
+}
+
+func testUpdate(ctx context.Context, e *env) {
+	id := e.create(ctx, e.user("erin"))
+	before := e.get(ctx, id)
+
+	// Empty fields are left as is, activity is always set.
+	updated, err := e.repo.UpdateUser(ctx, userDomain.User{ID: id, FirstName: "Changed", Email: e.prefix + "new@example.com"})
+	if err != nil {
+		e.t.Fatalf("repo.UpdateUser: %v", err)
+	}
+	if updated.FirstName != "Changed" || updated.Email != e.prefix+"new@example.com" || updated.IsActive {
+		e.t.Errorf("changes are not applied: %+v", updated)
+	}
+	if updated.LastName != before.LastName || updated.Login != before.Login || updated.Age != before.Age {
+		e.t.Errorf("empty fields are changed: %+v", updated)
+	}
+	if updated.UpdatedAt.Before(before.UpdatedAt) || !updated.CreatedAt.Equal(before.CreatedAt) {
+		e.t.Errorf("timestamps are wrong: got %v and %v", updated.CreatedAt, updated.UpdatedAt)
+	}
+
+	if got := e.get(ctx, id); got.FirstName != "Changed" {
+		e.t.Error("update is not saved")
+	}
+}
+
+func testDelete(ctx context.Context, e *env) {
+	id := e.create(ctx, e.user("frank"))
+
+	deleted, err := e.repo.DeleteUser(ctx, id)
+	if err != nil {
+		e.t.Fatalf("repo.DeleteUser: %v", err)
+	}
+	if deleted != id {
+		e.t.Errorf("got %s, want %s", deleted, id)
+	}
+	if e.exists(ctx, id) {
+		e.t.Error("deleted user is found")
+	}
+
+	// Login of deleted user is free again.
+	e.create(ctx, e.user("frank"))
+}
+
+func testGetMany(ctx context.Context, e *env) {
+	first := e.create(ctx, e.user("grace"))
+	second := e.create(ctx, e.user("heidi"))
+
+	byIDs, err := e.repo.GetUsersByIDs(ctx, []uuid.UUID{first, second, uuid.New()})
+	if err != nil {
+		e.t.Fatalf("repo.GetUsersByIDs: %v", err)
+	}
+	if len(byIDs) != 2 {
+		e.t.Errorf("repo.GetUsersByIDs: got %d users, want 2", len(byIDs))
+	}
+
+	byLogins, err := e.repo.GetUsersByLogins(ctx, []string{" " + strings.ToUpper(e.user("grace").Login) + " ", e.prefix + "nobody"})
+	if err != nil {
+		e.t.Fatalf("repo.GetUsersByLogins: %v", err)
+	}
+	if len(byLogins) != 1 || byLogins[0].ID != first {
+		e.t.Errorf("repo.GetUsersByLogins: got %+v, want user %s", byLogins, first)
+	}
+}
+
+func testFind(ctx context.Context, e *env) {
+	var ids []uuid.UUID
+	for _, name := range []string{"ivan", "judy", "karl"} {
+		u := e.user(name)
+		if name == "judy" {
+			u.Sex = "female"
+		}
+		ids = append(ids, e.create(ctx, u))
+	}
+
+	// Users created at same time may be in any order, so pages are checked to be ordered and to cover all users.
+	first, total, err := e.repo.FindUsers(ctx, userDomain.Filter{Login: e.prefix, Limit: 2})
+	if err != nil {
+		e.t.Fatalf("repo.FindUsers: %v", err)
+	}
+	if total != 3 || len(first) != 2 {
+		e.t.Fatalf("first page: got %d users of %d, want 2 of 3", len(first), total)
+	}
+
+	second, total, err := e.repo.FindUsers(ctx, userDomain.Filter{Login: e.prefix, Limit: 2, Offset: 2})
+	if err != nil {
+		e.t.Fatalf("repo.FindUsers: %v", err)
+	}
+	if total != 3 || len(second) != 1 {
+		e.t.Fatalf("second page: got %d users of %d, want 1 of 3", len(second), total)
+	}
+
+	all := append(first, second...)
+	for i, u := range all {
+		if !slices.Contains(ids, u.ID) || slices.IndexFunc(all[:i], func(prev userDomain.User) bool { return prev.ID == u.ID }) >= 0 {
+			e.t.Fatal("pages don't cover all users once")
+		}
+		if i > 0 && (u.CreatedAt.Before(all[i-1].CreatedAt) ||
+			u.CreatedAt.Equal(all[i-1].CreatedAt) && u.ID.String() < all[i-1].ID.String()) {
+			e.t.Fatal("users are not ordered by creation time and id")
+		}
+	}
+
+	page, total, err := e.repo.FindUsers(ctx, userDomain.Filter{Login: e.prefix, Sex: "female", Limit: 10})
+	if err != nil {
+		e.t.Fatalf("repo.FindUsers: %v", err)
+	}
+	if total != 1 || len(page) != 1 || page[0].ID != ids[1] {
+		e.t.Errorf("by sex: got %d users of %d, want 1 of 1", len(page), total)
+	}
+
+	page, _, err = e.repo.FindUsers(ctx, userDomain.Filter{Login: e.prefix + "%", Limit: 10})
+	if err != nil {
+		e.t.Fatalf("repo.FindUsers: %v", err)
+	}
+	if len(page) != 0 {
+		e.t.Error("wildcards in login filter are not escaped")
+	}
+}
+
+func testFindPastEnd(ctx context.Context, e *env) {
+	for _, name := range []string{"liam", "mona"} {
+		e.create(ctx, e.user(name))
+	}
+
+	for _, offset := range []int{2, 5} {
+		page, total, err := e.repo.FindUsers(ctx, userDomain.Filter{Login: e.prefix, Limit: 2, Offset: offset})
+		if err != nil {
+			e.t.Fatalf("repo.FindUsers: %v", err)
+		}
+		if total != 2 || len(page) != 0 {
+			e.t.Errorf("offset %d: got %d users of %d, want 0 of 2", offset, len(page), total)
+		}
+	}
+
+	page, total, err := e.repo.FindUsers(ctx, userDomain.Filter{Login: e.prefix + "-nobody", Limit: 2, Offset: 2})
+	if err != nil {
+		e.t.Fatalf("repo.FindUsers: %v", err)
+	}
+	if total != 0 || len(page) != 0 {
+		e.t.Errorf("no match: got %d users of %d, want 0 of 0", len(page), total)
+	}
+}
+
+func testCommit(ctx context.Context, e *env) {
+	var id uuid.UUID
+	err := e.repo.WithinTx(ctx, nil, func(ctx context.Context) error {
+		var err error
+		if id, err = e.tryCreate(ctx, e.user("leo")); err != nil {
+			return err
+		}
+
+		// Changes are visible inside transaction before commit.
+		_, err = e.repo.GetUserByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		e.t.Fatalf("repo.WithinTx: %v", err)
+	}
+
+	if !e.exists(ctx, id) {
+		e.t.Error("committed user is not found")
+	}
+}
+
+func testRollback(ctx context.Context, e *env) {
+	var id uuid.UUID
+	err := e.repo.WithinTx(ctx, nil, func(ctx context.Context) error {
+		var err error
+		if id, err = e.tryCreate(ctx, e.user("mallory")); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		e.t.Fatalf("repo.WithinTx: got %v, want error of fn", err)
+	}
+	if e.exists(ctx, id) {
+		e.t.Error("rolled back user is found")
+	}
+
+	// Failed statement rolls back whole transaction too.
+	err = e.repo.WithinTx(ctx, nil, func(ctx context.Context) error {
+		if id, err = e.tryCreate(ctx, e.user("niaj")); err != nil {
+			return err
+		}
+		duplicate := e.user("niaj")
+		duplicate.Email = e.prefix + "other@example.com"
+		_, err = e.tryCreate(ctx, duplicate)
+		return err
+	})
+	if !errors.Is(err, userDomain.ErrLoginTaken) {
+		e.t.Fatalf("repo.WithinTx: got %v, want %v", err, userDomain.ErrLoginTaken)
+	}
+	if e.exists(ctx, id) {
+		e.t.Error("user of failed transaction is found")
+	}
+}
+
+func testSavepoint(ctx context.Context, e *env) {
+	var outer, inner uuid.UUID
+	err := e.repo.WithinTx(ctx, nil, func(ctx context.Context) error {
+		var err error
+		if outer, err = e.tryCreate(ctx, e.user("olivia")); err != nil {
+			return err
+		}
+
+		err = e.repo.WithinTx(ctx, nil, func(ctx context.Context) error {
+			if inner, err = e.tryCreate(ctx, e.user("peggy")); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			e.t.Errorf("nested repo.WithinTx: got %v, want error of fn", err)
+		}
+
+		// Transaction is usable after savepoint is rolled back.
+		_, err = e.repo.GetUserByID(ctx, outer)
+		return err
+	})
+	if err != nil {
+		e.t.Fatalf("repo.WithinTx: %v", err)
+	}
+
+	if !e.exists(ctx, outer) {
+		e.t.Error("user of outer transaction is not found")
+	}
+	if e.exists(ctx, inner) {
+		e.t.Error("user of rolled back savepoint is found")
+	}
+}
+
+func testPanic(ctx context.Context, e *env) {
+	var id uuid.UUID
+	recovered := func() (r any) {
+		defer func() {
+			r = recover()
+		}()
+
+		_ = e.repo.WithinTx(ctx, nil, func(ctx context.Context) error {
+			var err error
+			if id, err = e.tryCreate(ctx, e.user("rupert")); err != nil {
+				return err
+			}
+			panic(errRollback)
+		})
+		return nil
+	}()
+	if recovered != errRollback {
+		e.t.Fatalf("panic is not propagated: got %v", recovered)
+	}
+
+	if e.exists(ctx, id) {
+		e.t.Error("user of panicked transaction is found")
+	}
+}
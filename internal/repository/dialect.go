@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/srgklmv/comfortel/pkg/database"
+)
+
+// dialect is SQL flavour of database. Queries are written for postgres, placeholders and returning
+// work in sqlite as is, and dialect provides what differs: time arithmetic and arrays, which sqlite
+// stores as JSON.
+type dialect string
+
+func (d dialect) sqlite() bool {
+	return d == database.DriverSQLite
+}
+
+// now returns current timestamp expression. Sqlite one has format of timestamps stored by migrations,
+// so they compare as text.
+func (d dialect) now() string {
+	if d.sqlite() {
+		return `strftime('%Y-%m-%d %H:%M:%f', 'now')`
+	}
+
+	return "current_timestamp"
+}
+
+// nowPlusSeconds returns current timestamp shifted by seconds passed as param.
+func (d dialect) nowPlusSeconds(param string) string {
+	if d.sqlite() {
+		return fmt.Sprintf(`strftime('%%Y-%%m-%%d %%H:%%M:%%f', 'now', %s || ' seconds')`, param)
+	}
+
+	return fmt.Sprintf("current_timestamp + make_interval(secs => %s)", param)
+}
+
+// epoch returns current time in unix seconds with fraction.
+func (d dialect) epoch() string {
+	if d.sqlite() {
+		return "unixepoch('subsec')"
+	}
+
+	return "extract(epoch from current_timestamp)::double precision"
+}
+
+// greatest returns greater of two values.
+func (d dialect) greatest(a, b string) string {
+	if d.sqlite() {
+		return fmt.Sprintf("max(%s, %s)", a, b)
+	}
+
+	return fmt.Sprintf("greatest(%s, %s)", a, b)
+}
+
+// anyOf returns condition that value equals any element of array.
+func (d dialect) anyOf(value, array string) string {
+	if d.sqlite() {
+		return fmt.Sprintf("%s in (select value from json_each(%s))", value, array)
+	}
+
+	return fmt.Sprintf("%s = any(%s)", value, array)
+}
+
+// array returns query argument of slice.
+func (d dialect) array(a any) any {
+	if d.sqlite() {
+		return jsonArray{a: a}
+	}
+
+	return pq.Array(a)
+}
+
+// scanArray returns scanner of array column into pointer to slice.
+func (d dialect) scanArray(dest any) sql.Scanner {
+	if d.sqlite() {
+		return jsonArray{a: dest}
+	}
+
+	return pq.Array(dest)
+}
+
+// jsonArray is array stored as JSON text.
+type jsonArray struct {
+	a any
+}
+
+func (j jsonArray) Value() (driver.Value, error) {
+	b, err := json.Marshal(j.a)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	return string(b), nil
+}
+
+func (j jsonArray) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), j.a)
+	case []byte:
+		return json.Unmarshal(v, j.a)
+	}
+
+	return fmt.Errorf("cannot scan %T into json array", src)
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/srgklmv/comfortel/internal/domain/idempotency"
+)
+
+// ReserveIdempotencyKey stores new key, or replaces expired one, and returns true.
+// If key exists and is not expired, it is returned with false. Reservation called outside transaction
+// is visible to concurrent ones at once, in transaction they wait until it ends.
+func (r repository) ReserveIdempotencyKey(ctx context.Context, caller, key, requestHash string, ttl time.Duration) (idempotency.Key, bool, error) {
+	db := r.db(ctx, "ReserveIdempotencyKey")
+
+	var reserved bool
+	err := db.QueryRowContext(
+		ctx,
+		`insert into idempotency_key (caller, key, request_hash, expires_at)
+		values ($1, $2, $3, `+r.dialect.nowPlusSeconds("$4")+`)
+		on conflict (caller, key) do update
+		set request_hash = excluded.request_hash, status_code = null, content_type = null, body = null,
+			created_at = `+r.dialect.now()+`, expires_at = excluded.expires_at
+		where idempotency_key.expires_at <= `+r.dialect.now()+`
+		returning true;`,
+		caller, key, requestHash, ttl.Seconds(),
+	).Scan(&reserved)
+	if err == nil {
+		return idempotency.Key{}, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return idempotency.Key{}, false, fmt.Errorf("queryRowContext: %w", err)
+	}
+
+	existing := idempotency.Key{Caller: caller, Key: key}
+	var (
+		statusCode  sql.NullInt64
+		contentType sql.NullString
+	)
+	err = db.QueryRowContext(
+		ctx,
+		`select request_hash, status_code, content_type, body, expires_at
+		from idempotency_key
+		where caller = $1 and key = $2;`,
+		caller, key,
+	).Scan(&existing.RequestHash, &statusCode, &contentType, &existing.Body, &existing.ExpiresAt)
+	if err != nil {
+		return idempotency.Key{}, false, fmt.Errorf("queryRowContext: %w", err)
+	}
+	existing.Completed = statusCode.Valid
+	existing.StatusCode = int(statusCode.Int64)
+	existing.ContentType = contentType.String
+
+	return existing, false, nil
+}
+
+// CompleteIdempotencyKey stores response of request reserved key.
+func (r repository) CompleteIdempotencyKey(ctx context.Context, caller, key string, statusCode int, contentType string, body []byte) error {
+	db := r.db(ctx, "CompleteIdempotencyKey")
+
+	_, err := db.ExecContext(
+		ctx,
+		`update idempotency_key
+		set status_code = $3, content_type = $4, body = $5
+		where caller = $1 and key = $2;`,
+		caller, key, statusCode, contentType, body,
+	)
+	if err != nil {
+		return fmt.Errorf("execContext: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey deletes key whose request failed, so it may be reserved again.
+func (r repository) ReleaseIdempotencyKey(ctx context.Context, caller, key string) error {
+	db := r.db(ctx, "ReleaseIdempotencyKey")
+
+	_, err := db.ExecContext(ctx, `delete from idempotency_key where caller = $1 and key = $2;`, caller, key)
+	if err != nil {
+		return fmt.Errorf("execContext: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys removes expired keys and returns their number.
+func (r repository) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	db := r.db(ctx, "DeleteExpiredIdempotencyKeys")
+
+	res, err := db.ExecContext(ctx, `delete from idempotency_key where expires_at <= `+r.dialect.now()+`;`)
+	if err != nil {
+		return 0, fmt.Errorf("execContext: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("res.RowsAffected: %w", err)
+	}
+
+	return n, nil
+}
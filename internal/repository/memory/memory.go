@@ -0,0 +1,169 @@
+// Package memory is in-memory implementation of repository used by usecases. It keeps semantics of
+// postgres repository: unique case-insensitive logins and emails, sql.ErrNoRows for missing entities
+// and transactions with savepoints. Transactions are serialized, so it is meant for tests and local runs.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/srgklmv/comfortel/internal/domain/event"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	webhookDomain "github.com/srgklmv/comfortel/internal/domain/webhook"
+)
+
+var errReadOnly = errors.New("cannot execute write in read-only transaction")
+
+type txKey struct{}
+
+type tx struct {
+	repo     *repository
+	readOnly bool
+}
+
+type storedUser struct {
+	user     userDomain.User
+	password string
+}
+
+type state struct {
+	users         map[uuid.UUID]storedUser
+	subscriptions map[uuid.UUID]webhookDomain.Subscription
+	deliveries    map[uuid.UUID]webhookDomain.Delivery
+	attempts      map[uuid.UUID][]webhookDomain.Attempt
+	outbox        []event.Record
+	seq           int64
+	clock         time.Time
+}
+
+func newState() *state {
+	return &state{
+		users:         make(map[uuid.UUID]storedUser),
+		subscriptions: make(map[uuid.UUID]webhookDomain.Subscription),
+		deliveries:    make(map[uuid.UUID]webhookDomain.Delivery),
+		attempts:      make(map[uuid.UUID][]webhookDomain.Attempt),
+	}
+}
+
+// clone returns copy of state, which is restored if transaction or savepoint is rolled back.
+// Stored values are never modified in place, so maps are copied shallowly.
+func (s *state) clone() *state {
+	c := newState()
+	for k, v := range s.users {
+		c.users[k] = v
+	}
+	for k, v := range s.subscriptions {
+		c.subscriptions[k] = v
+	}
+	for k, v := range s.deliveries {
+		c.deliveries[k] = v
+	}
+	for k, v := range s.attempts {
+		c.attempts[k] = v
+	}
+	c.outbox = append(c.outbox, s.outbox...)
+	c.seq = s.seq
+	c.clock = s.clock
+
+	return c
+}
+
+type repository struct {
+	mu    sync.Mutex
+	state *state
+}
+
+func New() *repository {
+	return &repository{state: newState()}
+}
+
+// WithinTx runs fn in transaction, which is committed if fn returns nil and rolled back if it returns error
+// or panics. Transaction holds store exclusively until it ends. If ctx already carries transaction,
+// fn runs in savepoint of it instead and opts are ignored.
+func (r *repository) WithinTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error {
+	if _, ok := r.txFromContext(ctx); ok {
+		return r.withinSnapshot(ctx, fn)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := &tx{repo: r, readOnly: opts != nil && opts.ReadOnly}
+
+	return r.withinSnapshot(context.WithValue(ctx, txKey{}, t), fn)
+}
+
+func (r *repository) withinSnapshot(ctx context.Context, fn func(ctx context.Context) error) error {
+	snapshot := r.state.clone()
+
+	committed := false
+	defer func() {
+		if !committed {
+			r.state = snapshot
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	committed = true
+
+	return nil
+}
+
+func (r *repository) txFromContext(ctx context.Context) (*tx, bool) {
+	t, ok := ctx.Value(txKey{}).(*tx)
+	if !ok || t.repo != r {
+		return nil, false
+	}
+
+	return t, true
+}
+
+// read runs fn with state, locking store unless ctx carries transaction which already holds it.
+func (r *repository) read(ctx context.Context, fn func(s *state) error) error {
+	if _, ok := r.txFromContext(ctx); ok {
+		return fn(r.state)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return fn(r.state)
+}
+
+// write is read for changes. fn must check everything before changing state, because without
+// transaction there is nothing to roll back to.
+func (r *repository) write(ctx context.Context, fn func(s *state) error) error {
+	if t, ok := r.txFromContext(ctx); ok {
+		if t.readOnly {
+			return errReadOnly
+		}
+		return fn(r.state)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return fn(r.state)
+}
+
+// now returns current time with precision of postgres timestamp. It never returns same time twice,
+// so entities are ordered by creation like in postgres, where statements don't share timestamp.
+func (s *state) now() time.Time {
+	t := time.Now().UTC().Truncate(time.Microsecond)
+	if !t.After(s.clock) {
+		t = s.clock.Add(time.Microsecond)
+	}
+	s.clock = t
+
+	return t
+}
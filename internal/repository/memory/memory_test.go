@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/srgklmv/comfortel/internal/repository/contract"
+)
+
+func TestContract(t *testing.T) {
+	contract.Test(t, func(*testing.T) contract.Repository {
+		return New()
+	})
+}
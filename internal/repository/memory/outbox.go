@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/srgklmv/comfortel/internal/domain/event"
+	sqlRepository "github.com/srgklmv/comfortel/internal/repository"
+)
+
+func (r *repository) AddToOutbox(ctx context.Context, e event.Event) error {
+	if _, ok := r.txFromContext(ctx); !ok {
+		return fmt.Errorf("r.txFromContext: %w", sqlRepository.ErrNoTx)
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	err = r.write(ctx, func(s *state) error {
+		s.seq++
+		s.outbox = append(s.outbox, event.Record{
+			Seq:         s.seq,
+			ID:          e.ID,
+			Type:        e.Type,
+			AggregateID: e.AggregateID,
+			Payload:     payload,
+			CreatedAt:   s.now(),
+		})
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
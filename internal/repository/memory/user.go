@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+)
+
+func (r *repository) CreateUser(ctx context.Context, data userDomain.User, hashedPassword string) (uuid.UUID, error) {
+	id := uuid.New()
+
+	err := r.write(ctx, func(s *state) error {
+		if err := s.checkUnique(uuid.Nil, data.Login, data.Email); err != nil {
+			return err
+		}
+
+		data.ID = id
+		data.IsActive = true
+		data.CreatedAt = s.now()
+		data.UpdatedAt = data.CreatedAt
+		s.users[id] = storedUser{user: data, password: hashedPassword}
+
+		return nil
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("write: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r *repository) UpdateUser(ctx context.Context, user userDomain.User) (userDomain.User, error) {
+	var updated userDomain.User
+
+	err := r.write(ctx, func(s *state) error {
+		stored, ok := s.users[user.ID]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		if err := s.checkUnique(user.ID, "", user.Email); err != nil {
+			return err
+		}
+
+		u := &stored.user
+		if user.Email != "" {
+			u.Email = user.Email
+		}
+		if user.FirstName != "" {
+			u.FirstName = user.FirstName
+		}
+		if user.LastName != "" {
+			u.LastName = user.LastName
+		}
+		if user.MiddleName != "" {
+			u.MiddleName = user.MiddleName
+		}
+		if user.AvatarURL != "" {
+			u.AvatarURL = user.AvatarURL
+		}
+		u.IsActive = user.IsActive
+		u.UpdatedAt = s.now()
+
+		s.users[user.ID] = stored
+		updated = stored.user
+
+		return nil
+	})
+	if err != nil {
+		return user, fmt.Errorf("write: %w", err)
+	}
+
+	return updated, nil
+}
+
+func (r *repository) GetUserByLogin(ctx context.Context, login string) (userDomain.User, error) {
+	return r.findUser(ctx, func(u userDomain.User) bool {
+		return strings.ToLower(u.Login) == strings.ToLower(login)
+	})
+}
+
+func (r *repository) GetUserByEmail(ctx context.Context, email string) (userDomain.User, error) {
+	return r.findUser(ctx, func(u userDomain.User) bool {
+		return u.Email != "" && strings.ToLower(u.Email) == strings.ToLower(email)
+	})
+}
+
+func (r *repository) GetUserByID(ctx context.Context, id uuid.UUID) (userDomain.User, error) {
+	return r.findUser(ctx, func(u userDomain.User) bool {
+		return u.ID == id
+	})
+}
+
+func (r *repository) DeleteUser(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	err := r.write(ctx, func(s *state) error {
+		if _, ok := s.users[id]; !ok {
+			return sql.ErrNoRows
+		}
+		delete(s.users, id)
+
+		return nil
+	})
+	if err != nil {
+		return id, fmt.Errorf("write: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r *repository) GetUsers(ctx context.Context) ([]userDomain.User, error) {
+	return r.filterUsers(ctx, func(userDomain.User) bool {
+		return true
+	})
+}
+
+func (r *repository) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]userDomain.User, error) {
+	return r.filterUsers(ctx, func(u userDomain.User) bool {
+		return slices.Contains(ids, u.ID)
+	})
+}
+
+func (r *repository) GetUsersByLogins(ctx context.Context, logins []string) ([]userDomain.User, error) {
+	params := make([]string, 0, len(logins))
+	for _, login := range logins {
+		params = append(params, userDomain.NormalizeLogin(login))
+	}
+
+	return r.filterUsers(ctx, func(u userDomain.User) bool {
+		return slices.Contains(params, strings.ToLower(u.Login))
+	})
+}
+
+// FindUsers returns page of users matching filter and total count of matching users.
+func (r *repository) FindUsers(ctx context.Context, filter userDomain.Filter) ([]userDomain.User, int, error) {
+	matching, err := r.filterUsers(ctx, func(u userDomain.User) bool {
+		if filter.Login != "" && !strings.Contains(strings.ToLower(u.Login), filter.Login) {
+			return false
+		}
+		if filter.Email != "" && strings.ToLower(u.Email) != filter.Email {
+			return false
+		}
+		if filter.Sex != "" && u.Sex != filter.Sex {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("r.filterUsers: %w", err)
+	}
+
+	start := min(max(filter.Offset, 0), len(matching))
+	end := min(start+max(filter.Limit, 0), len(matching))
+	if start == end {
+		return nil, len(matching), nil
+	}
+
+	return matching[start:end], len(matching), nil
+}
+
+func (r *repository) findUser(ctx context.Context, match func(u userDomain.User) bool) (userDomain.User, error) {
+	var user userDomain.User
+
+	err := r.read(ctx, func(s *state) error {
+		for _, stored := range s.users {
+			if match(stored.user) {
+				user = stored.user
+				return nil
+			}
+		}
+
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return userDomain.User{}, fmt.Errorf("read: %w", err)
+	}
+
+	return user, nil
+}
+
+// filterUsers returns matching users ordered by creation time, as listings of postgres repository are.
+func (r *repository) filterUsers(ctx context.Context, match func(u userDomain.User) bool) ([]userDomain.User, error) {
+	var users []userDomain.User
+
+	err := r.read(ctx, func(s *state) error {
+		for _, stored := range s.users {
+			if match(stored.user) {
+				users = append(users, stored.user)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	slices.SortFunc(users, func(a, b userDomain.User) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+
+	return users, nil
+}
+
+// checkUnique returns error if login or email is taken by user other than id. Empty values are not checked,
+// like nulls in unique index.
+func (s *state) checkUnique(id uuid.UUID, login, email string) error {
+	for _, stored := range s.users {
+		if stored.user.ID == id {
+			continue
+		}
+		if login != "" && strings.ToLower(stored.user.Login) == strings.ToLower(login) {
+			return userDomain.ErrLoginTaken
+		}
+		if email != "" && strings.ToLower(stored.user.Email) == strings.ToLower(email) {
+			return userDomain.ErrEmailTaken
+		}
+	}
+
+	return nil
+}
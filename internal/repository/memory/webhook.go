@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/srgklmv/comfortel/internal/domain/event"
+	webhookDomain "github.com/srgklmv/comfortel/internal/domain/webhook"
+)
+
+const deliveriesPageSize = 100
+
+func (r *repository) CreateSubscription(ctx context.Context, sub webhookDomain.Subscription) (uuid.UUID, error) {
+	id := uuid.New()
+
+	err := r.write(ctx, func(s *state) error {
+		sub.ID = id
+		sub.Events = slices.Clone(sub.Events)
+		sub.CreatedAt = s.now()
+		s.subscriptions[id] = sub
+
+		return nil
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("write: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r *repository) GetSubscriptions(ctx context.Context) ([]webhookDomain.Subscription, error) {
+	var subscriptions []webhookDomain.Subscription
+
+	err := r.read(ctx, func(s *state) error {
+		for _, sub := range s.subscriptions {
+			subscriptions = append(subscriptions, sub)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	slices.SortFunc(subscriptions, func(a, b webhookDomain.Subscription) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return subscriptions, nil
+}
+
+func (r *repository) GetSubscriptionByID(ctx context.Context, id uuid.UUID) (webhookDomain.Subscription, error) {
+	var sub webhookDomain.Subscription
+
+	err := r.read(ctx, func(s *state) error {
+		var ok bool
+		if sub, ok = s.subscriptions[id]; !ok {
+			return sql.ErrNoRows
+		}
+
+		return nil
+	})
+	if err != nil {
+		return webhookDomain.Subscription{}, fmt.Errorf("read: %w", err)
+	}
+
+	return sub, nil
+}
+
+// DeleteSubscription deletes subscription with its deliveries and their attempts.
+func (r *repository) DeleteSubscription(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	err := r.write(ctx, func(s *state) error {
+		if _, ok := s.subscriptions[id]; !ok {
+			return sql.ErrNoRows
+		}
+
+		delete(s.subscriptions, id)
+		for deliveryID, d := range s.deliveries {
+			if d.SubscriptionID == id {
+				delete(s.deliveries, deliveryID)
+				delete(s.attempts, deliveryID)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("write: %w", err)
+	}
+
+	return id, nil
+}
+
+// EnqueueDeliveries creates delivery of event for every active subscription to its type.
+func (r *repository) EnqueueDeliveries(ctx context.Context, e event.Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	err = r.write(ctx, func(s *state) error {
+		createdAt := s.now()
+		for _, sub := range s.subscriptions {
+			if !sub.IsActive || !slices.Contains(sub.Events, e.Type) {
+				continue
+			}
+
+			id := uuid.New()
+			s.deliveries[id] = webhookDomain.Delivery{
+				ID:             id,
+				SubscriptionID: sub.ID,
+				EventID:        e.ID,
+				EventType:      e.Type,
+				Payload:        payload,
+				Status:         webhookDomain.StatusPending,
+				NextAttemptAt:  createdAt,
+				CreatedAt:      createdAt,
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeliveries returns latest deliveries of subscription. Status is optional.
+func (r *repository) GetDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string) ([]webhookDomain.Delivery, error) {
+	var deliveries []webhookDomain.Delivery
+
+	err := r.read(ctx, func(s *state) error {
+		for _, d := range s.deliveries {
+			if d.SubscriptionID == subscriptionID && (status == "" || d.Status == status) {
+				deliveries = append(deliveries, d)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	slices.SortFunc(deliveries, func(a, b webhookDomain.Delivery) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	if len(deliveries) > deliveriesPageSize {
+		deliveries = deliveries[:deliveriesPageSize]
+	}
+
+	return deliveries, nil
+}
+
+func (r *repository) GetDelivery(ctx context.Context, id uuid.UUID) (webhookDomain.Delivery, error) {
+	var d webhookDomain.Delivery
+
+	err := r.read(ctx, func(s *state) error {
+		var ok bool
+		if d, ok = s.deliveries[id]; !ok {
+			return sql.ErrNoRows
+		}
+
+		return nil
+	})
+	if err != nil {
+		return webhookDomain.Delivery{}, fmt.Errorf("read: %w", err)
+	}
+
+	return d, nil
+}
+
+func (r *repository) GetDeliveryAttempts(ctx context.Context, deliveryID uuid.UUID) ([]webhookDomain.Attempt, error) {
+	var attempts []webhookDomain.Attempt
+
+	err := r.read(ctx, func(s *state) error {
+		attempts = slices.Clone(s.attempts[deliveryID])
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// RedeliverDelivery schedules delivery for immediate sending with fresh attempts budget.
+func (r *repository) RedeliverDelivery(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	err := r.write(ctx, func(s *state) error {
+		d, ok := s.deliveries[id]
+		if !ok {
+			return sql.ErrNoRows
+		}
+
+		d.Status = webhookDomain.StatusPending
+		d.Attempts = 0
+		d.NextAttemptAt = s.now()
+		d.DeliveredAt = nil
+		s.deliveries[id] = d
+
+		return nil
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("write: %w", err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/srgklmv/comfortel/internal/domain/event"
+)
+
+// outboxLockKey is advisory lock key held by relay, so only one relay sequences and claims events at a time.
+const outboxLockKey = 7_301_001
+
+func (r repository) AddToOutbox(ctx context.Context, e event.Event) error {
+	tx, err := r.tx(ctx, "AddToOutbox")
+	if err != nil {
+		return fmt.Errorf("r.tx: %w", err)
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`insert into outbox (event_id, event_type, aggregate_id, payload)
+		values ($1, $2, $3, $4);`,
+		e.ID, e.Type, e.AggregateID, payload,
+	)
+	if err != nil {
+		return fmt.Errorf("execContext: %w", err)
+	}
+
+	return nil
+}
+
+// LockOutbox takes transaction level advisory lock of outbox relay.
+// Returns false if it is held by other transaction.
+func (r repository) LockOutbox(ctx context.Context) (bool, error) {
+	tx, err := r.tx(ctx, "LockOutbox")
+	if err != nil {
+		return false, fmt.Errorf("r.tx: %w", err)
+	}
+
+	// Relay transaction is only writer while it lasts in sqlite.
+	if r.dialect.sqlite() {
+		return true, nil
+	}
+
+	var locked bool
+	err = tx.QueryRowContext(ctx, `select pg_try_advisory_xact_lock($1);`, outboxLockKey).Scan(&locked)
+	if err != nil {
+		return false, fmt.Errorf("queryRowContext: %w", err)
+	}
+
+	return locked, nil
+}
+
+// SequenceOutbox assigns positions following the latest one to committed events without position,
+// in order of sequence. Events committed later get greater positions, whatever their sequence is.
+// Must be called under LockOutbox.
+func (r repository) SequenceOutbox(ctx context.Context) error {
+	tx, err := r.tx(ctx, "SequenceOutbox")
+	if err != nil {
+		return fmt.Errorf("r.tx: %w", err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`update outbox as o
+		set position = p.position
+		from (
+			select seq, (select coalesce(max(position), 0) from outbox) + row_number() over (order by seq) as position
+			from outbox
+			where position is null
+		) as p
+		where o.seq = p.seq;`,
+	)
+	if err != nil {
+		return fmt.Errorf("execContext: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimOutbox leases up to limit oldest unpublished events for lease and returns them in order of position.
+// Events of aggregate with leased event are skipped, so events of one aggregate are never published
+// by two relays at once. Must be called under LockOutbox.
+func (r repository) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]event.Record, error) {
+	tx, err := r.tx(ctx, "ClaimOutbox")
+	if err != nil {
+		return nil, fmt.Errorf("r.tx: %w", err)
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`update outbox
+		set claimed_until = `+r.dialect.nowPlusSeconds("$2")+`
+		where seq in (
+			select seq
+			from outbox
+			where published_at is null and position is not null and aggregate_id not in (
+				select aggregate_id
+				from outbox
+				where published_at is null and claimed_until > `+r.dialect.now()+`
+			)
+			order by position
+			limit $1
+		)
+		returning seq, position, event_id, event_type, aggregate_id, payload, attempts, created_at;`,
+		limit, lease.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("queryContext: %w", err)
+	}
+	defer rows.Close()
+
+	var records []event.Record
+	for rows.Next() {
+		var rec event.Record
+		err = rows.Scan(&rec.Seq, &rec.Position, &rec.ID, &rec.Type, &rec.AggregateID, &rec.Payload, &rec.Attempts, &rec.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		records = append(records, rec)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	// Returning keeps no order.
+	slices.SortFunc(records, func(a, b event.Record) int {
+		return cmp.Compare(a.Position, b.Position)
+	})
+
+	return records, nil
+}
+
+func (r repository) MarkPublished(ctx context.Context, seqs []int64) error {
+	db := r.db(ctx, "MarkPublished")
+
+	_, err := db.ExecContext(
+		ctx,
+		`update outbox
+		set published_at = `+r.dialect.now()+`, attempts = attempts + 1, last_error = null, claimed_until = null
+		where `+r.dialect.anyOf("seq", "$1")+`;`,
+		r.dialect.array(seqs),
+	)
+	if err != nil {
+		return fmt.Errorf("execContext: %w", err)
+	}
+
+	return nil
+}
+
+func (r repository) MarkFailed(ctx context.Context, seq int64, reason string) error {
+	db := r.db(ctx, "MarkFailed")
+
+	_, err := db.ExecContext(
+		ctx,
+		`update outbox
+		set attempts = attempts + 1, last_error = $2, claimed_until = null
+		where seq = $1;`,
+		seq, reason,
+	)
+	if err != nil {
+		return fmt.Errorf("execContext: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseOutbox returns claimed events which were not tried to be published, so next claim picks them up.
+func (r repository) ReleaseOutbox(ctx context.Context, seqs []int64) error {
+	db := r.db(ctx, "ReleaseOutbox")
+
+	_, err := db.ExecContext(
+		ctx,
+		`update outbox
+		set claimed_until = null
+		where `+r.dialect.anyOf("seq", "$1")+`;`,
+		r.dialect.array(seqs),
+	)
+	if err != nil {
+		return fmt.Errorf("execContext: %w", err)
+	}
+
+	return nil
+}
+
+// GetLastEventPosition returns position of latest sequenced event or 0 if there are none.
+func (r repository) GetLastEventPosition(ctx context.Context) (int64, error) {
+	db := r.db(ctx, "GetLastEventPosition")
+
+	var position int64
+	err := db.QueryRowContext(ctx, `select coalesce(max(position), 0) from outbox;`).Scan(&position)
+	if err != nil {
+		return 0, fmt.Errorf("queryRowContext: %w", err)
+	}
+
+	return position, nil
+}
+
+// GetEventsAfter returns sequenced events with position greater than afterPosition in order of position,
+// published or not. Events are filtered by aggregate unless aggregateID is uuid.Nil.
+func (r repository) GetEventsAfter(ctx context.Context, afterPosition int64, aggregateID uuid.UUID, limit int) ([]event.Record, error) {
+	db := r.db(ctx, "GetEventsAfter")
+
+	rows, err := db.QueryContext(
+		ctx,
+		`select seq, position, event_id, event_type, aggregate_id, payload, attempts, created_at
+		from outbox
+		where position > $1 and ($2 or aggregate_id = $3)
+		order by position
+		limit $4;`,
+		afterPosition, aggregateID == uuid.Nil, aggregateID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("queryContext: %w", err)
+	}
+	defer rows.Close()
+
+	var records []event.Record
+	for rows.Next() {
+		var rec event.Record
+		err = rows.Scan(&rec.Seq, &rec.Position, &rec.ID, &rec.Type, &rec.AggregateID, &rec.Payload, &rec.Attempts, &rec.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		records = append(records, rec)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TakeRateLimitTokens takes n tokens from bucket of key, which holds limit tokens and gets one back each interval.
+// Bucket is kept as theoretical arrival time of next request (GCRA), which is the same token bucket in single
+// value, so take is single statement. Backlog is time until bucket is full again, it is returned if tokens
+// are not taken too.
+func (r repository) TakeRateLimitTokens(ctx context.Context, key string, n int, interval time.Duration, limit int) (bool, time.Duration, error) {
+	db := r.db(ctx, "TakeRateLimitTokens")
+	now := r.dialect.epoch()
+	tat := r.dialect.greatest("rate_limit.tat", now) + " + $2"
+
+	var backlog float64
+	err := db.QueryRowContext(
+		ctx,
+		`insert into rate_limit (key, tat)
+		values ($1, `+now+` + $2)
+		on conflict (key) do update
+		set tat = `+tat+`
+		where `+tat+` <= `+now+` + $3
+		returning tat - `+now+`;`,
+		key, (interval * time.Duration(n)).Seconds(), (interval * time.Duration(limit)).Seconds(),
+	).Scan(&backlog)
+	if err == nil {
+		return true, time.Duration(backlog * float64(time.Second)), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, 0, fmt.Errorf("queryRowContext: %w", err)
+	}
+
+	err = db.QueryRowContext(ctx, `select tat - `+now+` from rate_limit where key = $1;`, key).Scan(&backlog)
+	if err != nil {
+		return false, 0, fmt.Errorf("queryRowContext: %w", err)
+	}
+
+	return false, time.Duration(backlog * float64(time.Second)), nil
+}
+
+// DeleteFullRateLimits removes buckets which are full, as they are the same as missing ones, and returns
+// their number.
+func (r repository) DeleteFullRateLimits(ctx context.Context) (int64, error) {
+	db := r.db(ctx, "DeleteFullRateLimits")
+
+	res, err := db.ExecContext(ctx, `delete from rate_limit where tat <= `+r.dialect.epoch()+`;`)
+	if err != nil {
+		return 0, fmt.Errorf("execContext: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("res.RowsAffected: %w", err)
+	}
+
+	return n, nil
+}
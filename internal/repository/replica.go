@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+const (
+	replicaCheckInterval = 5 * time.Second
+	replicaCheckTimeout  = time.Second
+)
+
+type replicaKey struct{}
+
+// PreferReplica returns context in which read-only transactions of Transactor run on replica if there is healthy one.
+func PreferReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaKey{}, true)
+}
+
+func prefersReplica(ctx context.Context) bool {
+	prefers, _ := ctx.Value(replicaKey{}).(bool)
+	return prefers
+}
+
+// Replicas is pool of postgres read replicas. Transactions are spread over healthy replicas round-robin.
+type Replicas struct {
+	replicas []*replica
+	maxLag   time.Duration
+	next     atomic.Uint64
+}
+
+type replica struct {
+	index   int
+	conn    *sql.DB
+	healthy atomic.Bool
+}
+
+// NewReplicas returns pool of replicas, which are not used until first health check in Run.
+// Replica is healthy if it answers, receives WAL from primary and either replayed all of it or
+// replayed transaction not older than maxLag.
+func NewReplicas(conns []*sql.DB, maxLag time.Duration) *Replicas {
+	r := &Replicas{maxLag: maxLag}
+	for i, conn := range conns {
+		r.replicas = append(r.replicas, &replica{index: i, conn: conn})
+	}
+
+	return r
+}
+
+// Run checks health of replicas until ctx is cancelled.
+func (r *Replicas) Run(ctx context.Context) {
+	ticker := time.NewTicker(replicaCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, rep := range r.replicas {
+			r.check(ctx, rep)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Replicas) check(ctx context.Context, rep *replica) {
+	ctx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+	defer cancel()
+
+	var state replicaState
+	err := rep.conn.QueryRowContext(
+		ctx,
+		`select pg_is_in_recovery(),
+			(select pid from pg_stat_wal_receiver),
+			(select status from pg_stat_wal_receiver),
+			pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn(),
+			extract(epoch from now() - pg_last_xact_replay_timestamp());`,
+	).Scan(&state.inRecovery, &state.receiverPID, &state.receiverStatus, &state.replayedAll, &state.replayAge)
+	if err != nil {
+		// Check cut short by shutdown says nothing about replica.
+		if ctx.Err() != nil && errors.Is(err, context.Canceled) {
+			return
+		}
+		rep.setHealthy(false, fmt.Errorf("queryRowContext: %w", err).Error())
+		return
+	}
+
+	if reason := state.unhealthy(r.maxLag); reason != "" {
+		rep.setHealthy(false, reason)
+		return
+	}
+	rep.setHealthy(true, "")
+}
+
+// replicaState is result of health check query.
+type replicaState struct {
+	inRecovery  bool
+	receiverPID sql.NullInt64
+	// receiverStatus is visible to superusers and members of pg_read_all_stats only, pid is visible to everyone.
+	receiverStatus sql.NullString
+	// replayedAll is true if everything received from primary is replayed.
+	replayedAll sql.NullBool
+	// replayAge is age of last replayed transaction in seconds.
+	replayAge sql.NullFloat64
+}
+
+// unhealthy returns reason why replica must not serve reads, or empty string if it may.
+func (s replicaState) unhealthy(maxLag time.Duration) string {
+	// Promoted replica doesn't follow primary anymore.
+	if !s.inRecovery {
+		return "not in recovery"
+	}
+	if !s.receiverPID.Valid {
+		return "WAL receiver is not running"
+	}
+	if s.receiverStatus.Valid && s.receiverStatus.String != "streaming" {
+		return fmt.Sprintf("WAL receiver is not streaming: %q", s.receiverStatus.String)
+	}
+
+	// Replica which replayed all it received is caught up, however old its last transaction is,
+	// as primary may have no writes.
+	if s.replayedAll.Bool {
+		return ""
+	}
+	if !s.replayAge.Valid {
+		return "no transaction is replayed yet"
+	}
+	if lag := time.Duration(s.replayAge.Float64 * float64(time.Second)); lag > maxLag {
+		return fmt.Sprintf("replication lag %s", lag)
+	}
+
+	return ""
+}
+
+// pick returns next healthy replica, or nil if there are none.
+func (r *Replicas) pick() *replica {
+	n := uint64(len(r.replicas))
+	for range n {
+		rep := r.replicas[r.next.Add(1)%n]
+		if rep.healthy.Load() {
+			return rep
+		}
+	}
+
+	return nil
+}
+
+// Healthy returns number of replicas which are used for reads.
+func (r *Replicas) Healthy() int {
+	healthy := 0
+	for _, rep := range r.replicas {
+		if rep.healthy.Load() {
+			healthy++
+		}
+	}
+
+	return healthy
+}
+
+// Close closes connections of replicas.
+func (r *Replicas) Close() error {
+	for _, rep := range r.replicas {
+		if err := rep.conn.Close(); err != nil {
+			return fmt.Errorf("conn.Close: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (rep *replica) setHealthy(healthy bool, reason string) {
+	if rep.healthy.Swap(healthy) == healthy {
+		return
+	}
+
+	if healthy {
+		logger.Info("replica is up", slog.Int("replica", rep.index))
+	} else {
+		logger.Error("replica is down", slog.Int("replica", rep.index), slog.String("reason", reason))
+	}
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplicaStateUnhealthy(t *testing.T) {
+	const maxLag = 10 * time.Second
+	streaming := replicaState{
+		inRecovery:     true,
+		receiverPID:    sql.NullInt64{Int64: 42, Valid: true},
+		receiverStatus: sql.NullString{String: "streaming", Valid: true},
+	}
+
+	tests := []struct {
+		name   string
+		modify func(s *replicaState)
+		reason string
+	}{
+		{
+			name: "idle primary, everything replayed",
+			modify: func(s *replicaState) {
+				s.replayedAll = sql.NullBool{Bool: true, Valid: true}
+				s.replayAge = sql.NullFloat64{Float64: time.Hour.Seconds(), Valid: true}
+			},
+		},
+		{
+			name: "replaying, recent transaction",
+			modify: func(s *replicaState) {
+				s.replayedAll = sql.NullBool{Valid: true}
+				s.replayAge = sql.NullFloat64{Float64: 1, Valid: true}
+			},
+		},
+		{
+			name: "replaying, old transaction",
+			modify: func(s *replicaState) {
+				s.replayedAll = sql.NullBool{Valid: true}
+				s.replayAge = sql.NullFloat64{Float64: 30, Valid: true}
+			},
+			reason: "replication lag",
+		},
+		{
+			name: "status hidden from role without pg_read_all_stats",
+			modify: func(s *replicaState) {
+				s.receiverStatus = sql.NullString{}
+				s.replayedAll = sql.NullBool{Bool: true, Valid: true}
+			},
+		},
+		{
+			name: "receiver is not streaming",
+			modify: func(s *replicaState) {
+				s.receiverStatus = sql.NullString{String: "waiting", Valid: true}
+				s.replayedAll = sql.NullBool{Bool: true, Valid: true}
+			},
+			reason: "not streaming",
+		},
+		{
+			name: "receiver is not running",
+			modify: func(s *replicaState) {
+				s.receiverPID = sql.NullInt64{}
+				s.receiverStatus = sql.NullString{}
+			},
+			reason: "not running",
+		},
+		{
+			name:   "promoted",
+			modify: func(s *replicaState) { s.inRecovery = false },
+			reason: "not in recovery",
+		},
+		{
+			name:   "nothing replayed",
+			modify: func(s *replicaState) {},
+			reason: "no transaction",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := streaming
+			tt.modify(&state)
+
+			reason := state.unhealthy(maxLag)
+			if tt.reason == "" && reason != "" {
+				t.Errorf("unhealthy: %s", reason)
+			}
+			if tt.reason != "" && !strings.Contains(reason, tt.reason) {
+				t.Errorf("reason %q, want %q", reason, tt.reason)
+			}
+		})
+	}
+}
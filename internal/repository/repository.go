@@ -1,24 +1,84 @@
 package repository
 
 import (
-	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	"go.opentelemetry.io/otel"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const uniqueViolationCode = "23505"
+
+// Unique constraints names from migrations.
+const (
+	userLoginUniqueConstraint = "user_login_lower_key"
+	userEmailUniqueConstraint = "user_email_lower_key"
 )
 
+var tracer = otel.Tracer("github.com/srgklmv/comfortel/internal/repository")
+
 type repository struct {
-	conn *sql.DB
+	conn     *sql.DB
+	dialect  dialect
+	observer QueryObserver
 }
 
-func New(conn *sql.DB) *repository {
-	return &repository{conn: conn}
+// QueryObserver is called after each query with name of repository method which made it.
+type QueryObserver func(method string, duration time.Duration, err error)
+
+type Option func(*repository)
+
+// WithQueryObserver makes repository report its queries to observer, e.g. to record latency.
+func WithQueryObserver(observer QueryObserver) Option {
+	return func(r *repository) {
+		r.observer = observer
+	}
 }
 
-func getTxFromContext(ctx context.Context) (*sql.Tx, error) {
-	tx, ok := ctx.Value("tx").(*sql.Tx)
-	if !ok {
-		return nil, errors.New("no tx in context")
+// New returns repository working with database of driver, see database.Driver* constants.
+func New(conn *sql.DB, driverName string, opts ...Option) *repository {
+	r := &repository{conn: conn, dialect: dialect(driverName)}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// mapConstraintError converts unique violation errors into domain errors.
+// Other errors are returned as is.
+func mapConstraintError(err error) error {
+	var constraint string
+
+	var pqErr *pq.Error
+	var sqliteErr *sqlite.Error
+	switch {
+	case errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode:
+		constraint = pqErr.Constraint
+	case errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		// Sqlite error has constraint name in message only, e.g. "UNIQUE constraint failed: index 'user_login_lower_key'".
+		for _, c := range []string{userLoginUniqueConstraint, userEmailUniqueConstraint} {
+			if strings.Contains(sqliteErr.Error(), "'"+c+"'") {
+				constraint = c
+			}
+		}
+	default:
+		return err
+	}
+
+	switch constraint {
+	case userLoginUniqueConstraint:
+		return fmt.Errorf("%w: %w", userDomain.ErrLoginTaken, err)
+	case userEmailUniqueConstraint:
+		return fmt.Errorf("%w: %w", userDomain.ErrEmailTaken, err)
 	}
 
-	return tx, nil
+	return err
 }
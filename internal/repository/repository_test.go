@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/srgklmv/comfortel/internal/repository/contract"
+	"github.com/srgklmv/comfortel/migrations"
+	"github.com/srgklmv/comfortel/pkg/database"
+)
+
+// postgresDSNEnv names variable with DSN of postgres database to run tests against, they are skipped if it's empty.
+// Database is migrated to latest version, users created by tests are deleted afterwards.
+const postgresDSNEnv = "COMFORTEL_TEST_POSTGRES_DSN"
+
+// contractRepository joins repository and transactor of same database.
+type contractRepository struct {
+	contract.UserRepository
+	*Transactor
+}
+
+func TestPostgresContract(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresDSNEnv)
+	}
+
+	conn, err := database.NewReplica(dsn)
+	if err != nil {
+		t.Fatalf("database.NewReplica: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Shutdown(conn) })
+	migrateLatest(t, conn, database.DriverPostgres)
+
+	contract.Test(t, func(*testing.T) contract.Repository {
+		return contractRepository{New(conn, database.DriverPostgres), NewTransactor(conn)}
+	})
+}
+
+func TestSQLiteContract(t *testing.T) {
+	conn, err := database.NewSQLite(filepath.Join(t.TempDir(), "contract.db"))
+	if err != nil {
+		t.Fatalf("database.NewSQLite: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Shutdown(conn) })
+	migrateLatest(t, conn, database.DriverSQLite)
+
+	contract.Test(t, func(*testing.T) contract.Repository {
+		return contractRepository{New(conn, database.DriverSQLite), NewTransactor(conn)}
+	})
+}
+
+// migrateLatest migrates database to version of last embedded migration.
+func migrateLatest(t *testing.T, conn *sql.DB, driver string) {
+	t.Helper()
+
+	version, err := database.LatestVersion(migrations.FS, driver)
+	if err != nil {
+		t.Fatalf("database.LatestVersion: %v", err)
+	}
+	if err = database.Migrate(conn, driver, migrations.FS, version); err != nil {
+		t.Fatalf("database.Migrate: %v", err)
+	}
+}
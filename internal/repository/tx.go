@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Postgres error codes after which transaction may succeed if retried.
+const (
+	serializationFailureCode = "40001"
+	deadlockDetectedCode     = "40P01"
+)
+
+// ErrNoTx is returned by methods which make sense only in transaction, e.g. taking transaction level lock.
+var ErrNoTx = errors.New("no transaction in context")
+
+type txKey struct{}
+
+// querier is common part of *sql.DB and *Tx used by repository methods.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Tx is transaction used by repository methods. It remembers if any statement failed with retryable error,
+// because usecases don't return errors to transaction owner.
+type Tx struct {
+	*sql.Tx
+	retryable  atomic.Bool
+	savepoints atomic.Int64
+}
+
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	res, err := t.Tx.ExecContext(ctx, query, args...)
+	t.observe(err)
+	return res, err
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := t.Tx.QueryContext(ctx, query, args...)
+	t.observe(err)
+	return rows, err
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	row := t.Tx.QueryRowContext(ctx, query, args...)
+	t.observe(row.Err())
+	return row
+}
+
+// Retryable reports whether statement of transaction failed with serialization failure or deadlock.
+func (t *Tx) Retryable() bool {
+	return t.retryable.Load()
+}
+
+func (t *Tx) observe(err error) {
+	if IsRetryable(err) {
+		t.retryable.Store(true)
+	}
+}
+
+// IsRetryable reports whether err is serialization failure or deadlock, or sqlite database being busy
+// for longer than busy timeout.
+func IsRetryable(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == serializationFailureCode || pqErr.Code == deadlockDetectedCode
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code()&0xff == sqlite3.SQLITE_BUSY
+	}
+
+	return false
+}
+
+// TxFromContext returns transaction of context if there is one.
+func TxFromContext(ctx context.Context) (*Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*Tx)
+	return tx, ok
+}
+
+// Transactor is unit of work. Repository methods called with context passed to fn run in its transaction.
+type Transactor struct {
+	conn     *sql.DB
+	replicas *Replicas
+}
+
+type TransactorOption func(*Transactor)
+
+// WithReplicas makes read-only transactions run on replicas if context prefers them, see PreferReplica.
+func WithReplicas(replicas *Replicas) TransactorOption {
+	return func(t *Transactor) {
+		t.replicas = replicas
+	}
+}
+
+func NewTransactor(conn *sql.DB, opts ...TransactorOption) *Transactor {
+	t := &Transactor{conn: conn}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// WithinTx runs fn in transaction, which is committed if fn returns nil and rolled back if it returns error
+// or panics. If ctx already carries transaction, fn runs in savepoint of it instead and opts are ignored,
+// so only changes of fn are rolled back on error.
+func (t *Transactor) WithinTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) (err error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return t.withinSavepoint(ctx, tx, fn)
+	}
+
+	sqlTx, err := t.begin(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("t.begin: %w", err)
+	}
+	tx := &Tx{Tx: sqlTx}
+
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		tx.observe(err)
+		return fmt.Errorf("tx.Commit: %w", err)
+	}
+	committed = true
+
+	return nil
+}
+
+// begin starts transaction on primary, or on replica if transaction is read-only and ctx prefers replica.
+// If there is no healthy replica, or it fails to start transaction, primary is used.
+func (t *Transactor) begin(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if t.replicas != nil && opts != nil && opts.ReadOnly && prefersReplica(ctx) {
+		if rep := t.replicas.pick(); rep != nil {
+			tx, err := rep.conn.BeginTx(ctx, opts)
+			if err == nil {
+				return tx, nil
+			}
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("conn.BeginTx: %w", err)
+			}
+			rep.setHealthy(false, fmt.Errorf("conn.BeginTx: %w", err).Error())
+		}
+	}
+
+	tx, err := t.conn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("conn.BeginTx: %w", err)
+	}
+
+	return tx, nil
+}
+
+func (t *Transactor) withinSavepoint(ctx context.Context, tx *Tx, fn func(ctx context.Context) error) error {
+	name := pq.QuoteIdentifier(fmt.Sprintf("sp_%d", tx.savepoints.Add(1)))
+	if _, err := tx.ExecContext(ctx, "savepoint "+name+";"); err != nil {
+		return fmt.Errorf("execContext: %w", err)
+	}
+
+	released := false
+	defer func() {
+		if !released {
+			_, _ = tx.ExecContext(ctx, "rollback to savepoint "+name+";")
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "release savepoint "+name+";"); err != nil {
+		return fmt.Errorf("execContext: %w", err)
+	}
+	released = true
+
+	return nil
+}
+
+// db returns transaction of ctx, or connection pool if there is none, so methods work either way.
+// Method is name of repository method for observer and span.
+func (r repository) db(ctx context.Context, method string) querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return r.observed(tx, method)
+	}
+
+	return r.observed(r.conn, method)
+}
+
+// tx returns transaction of ctx for methods which make sense only in transaction.
+func (r repository) tx(ctx context.Context, method string) (querier, error) {
+	tx, ok := TxFromContext(ctx)
+	if !ok {
+		return nil, ErrNoTx
+	}
+
+	return r.observed(tx, method), nil
+}
+
+func (r repository) observed(q querier, method string) querier {
+	return instrumentedQuerier{querier: q, method: method, dialect: r.dialect, observer: r.observer}
+}
+
+// instrumentedQuerier traces queries made in traced requests and reports their duration to observer.
+// Rows are read after query is reported, and sql.ErrNoRows of QueryRowContext is known only on scan,
+// so it is not reported as error. Span has statement text, but not its arguments.
+type instrumentedQuerier struct {
+	querier
+	method   string
+	dialect  dialect
+	observer QueryObserver
+}
+
+func (q instrumentedQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, done := q.start(ctx, query)
+	res, err := q.querier.ExecContext(ctx, query, args...)
+	done(err)
+	return res, err
+}
+
+func (q instrumentedQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, done := q.start(ctx, query)
+	rows, err := q.querier.QueryContext(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+func (q instrumentedQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, done := q.start(ctx, query)
+	row := q.querier.QueryRowContext(ctx, query, args...)
+	done(row.Err())
+	return row
+}
+
+// start begins query and returns function which ends it with error of query.
+func (q instrumentedQuerier) start(ctx context.Context, query string) (context.Context, func(err error)) {
+	start := time.Now()
+
+	// Background workers poll all the time, so their queries start no traces of their own.
+	var span trace.Span
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		system := semconv.DBSystemPostgreSQL
+		if q.dialect.sqlite() {
+			system = semconv.DBSystemSqlite
+		}
+		ctx, span = tracer.Start(ctx, "repository."+q.method,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(system, semconv.DBQueryText(strings.TrimSpace(query))),
+		)
+	}
+
+	return ctx, func(err error) {
+		if q.observer != nil {
+			q.observer(q.method, time.Since(start), err)
+		}
+		if span != nil {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/google/uuid"
@@ -11,10 +12,7 @@ import (
 )
 
 func (r repository) CreateUser(ctx context.Context, data userDomain.User, hashedPassword string) (uuid.UUID, error) {
-	tx, err := getTxFromContext(ctx)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("getTxFromContext: %w", err)
-	}
+	db := r.db(ctx, "CreateUser")
 
 	isActive := true
 	entity := userDomain.EntityFromDomain(data)
@@ -22,7 +20,7 @@ func (r repository) CreateUser(ctx context.Context, data userDomain.User, hashed
 
 	var id uuid.UUID
 
-	err = tx.QueryRowContext(
+	err := db.QueryRowContext(
 		ctx,
 		`insert into "user" (login, email, first_name, last_name, middle_name, sex, age, avatar_url, is_active, password)
 		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
@@ -30,17 +28,14 @@ func (r repository) CreateUser(ctx context.Context, data userDomain.User, hashed
 		entity.Login, entity.Email, entity.FirstName, entity.LastName, entity.MiddleName, entity.Sex, entity.Age, entity.AvatarURL, entity.IsActive, hashedPassword,
 	).Scan(&id)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("queryRowContext: %w", err)
+		return uuid.Nil, fmt.Errorf("queryRowContext: %w", mapConstraintError(err))
 	}
 
 	return id, nil
 }
 
 func (r repository) UpdateUser(ctx context.Context, user userDomain.User) (userDomain.User, error) {
-	tx, err := getTxFromContext(ctx)
-	if err != nil {
-		return user, fmt.Errorf("getTxFromContext: %w", err)
-	}
+	db := r.db(ctx, "UpdateUser")
 
 	entity := userDomain.EntityFromDomain(user)
 
@@ -66,22 +61,27 @@ func (r repository) UpdateUser(ctx context.Context, user userDomain.User) (userD
 		fields = append(fields, fmt.Sprintf("avatar_url = $%d", len(fields)+1))
 		args = append(args, &entity.AvatarURL)
 	}
+	if entity.IsActive != nil {
+		fields = append(fields, fmt.Sprintf("is_active = $%d", len(fields)+1))
+		args = append(args, &entity.IsActive)
+	}
 
 	if len(fields) == 0 {
 		return user, errors.New("no fields passed")
 	}
 
+	fields = append(fields, "updated_at = "+r.dialect.now())
 	set := strings.Join(fields, ", ")
 	query := []string{
 		`update "user" set`,
 		set,
-		fmt.Sprintf("where id = $%d", len(fields)+1),
-		`returning id, login, email, first_name, last_name, middle_name, sex, age, avatar_url, is_active, created_at;`,
+		fmt.Sprintf("where id = $%d", len(args)+1),
+		`returning id, login, email, first_name, last_name, middle_name, sex, age, avatar_url, is_active, created_at, updated_at;`,
 	}
 	args = append(args, user.ID)
 	q := strings.Join(query, " ")
 
-	err = tx.QueryRowContext(
+	err := db.QueryRowContext(
 		ctx,
 		q,
 		args...,
@@ -97,29 +97,27 @@ func (r repository) UpdateUser(ctx context.Context, user userDomain.User) (userD
 		&entity.AvatarURL,
 		&entity.IsActive,
 		&entity.CreatedAt,
+		&entity.UpdatedAt,
 	)
 	if err != nil {
-		return user, fmt.Errorf("queryRowContext: %w", err)
+		return user, fmt.Errorf("queryRowContext: %w", mapConstraintError(err))
 	}
 
 	return entity.ToDomain(), nil
 }
 
 func (r repository) GetUserByLogin(ctx context.Context, login string) (userDomain.User, error) {
-	tx, err := getTxFromContext(ctx)
-	if err != nil {
-		return userDomain.User{}, fmt.Errorf("getTxFromContext: %w", err)
-	}
+	db := r.db(ctx, "GetUserByLogin")
 
 	var e userDomain.Entity
 
-	err = tx.QueryRowContext(
+	err := db.QueryRowContext(
 		ctx,
-		`select id, login, email, first_name, last_name, middle_name, sex, age, created_at, updated_at, avatar_url
+		`select id, login, email, first_name, last_name, middle_name, sex, age, created_at, updated_at, avatar_url, is_active
 		from "user"
-		where login = $1;`,
+		where lower(login) = lower($1);`,
 		login,
-	).Scan(&e.ID, &e.Login, &e.Email, &e.FirstName, &e.LastName, &e.MiddleName, &e.Sex, &e.Age, &e.CreatedAt, &e.UpdatedAt, &e.AvatarURL)
+	).Scan(&e.ID, &e.Login, &e.Email, &e.FirstName, &e.LastName, &e.MiddleName, &e.Sex, &e.Age, &e.CreatedAt, &e.UpdatedAt, &e.AvatarURL, &e.IsActive)
 	if err != nil {
 		return userDomain.User{}, fmt.Errorf("queryRowContext: %w", err)
 	}
@@ -127,17 +125,34 @@ func (r repository) GetUserByLogin(ctx context.Context, login string) (userDomai
 	return e.ToDomain(), nil
 }
 
-func (r repository) DeleteUser(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
-	tx, err := getTxFromContext(ctx)
+func (r repository) GetUserByEmail(ctx context.Context, email string) (userDomain.User, error) {
+	db := r.db(ctx, "GetUserByEmail")
+
+	var e userDomain.Entity
+
+	err := db.QueryRowContext(
+		ctx,
+		`select id, login, email, first_name, last_name, middle_name, sex, age, created_at, updated_at, avatar_url, is_active
+		from "user"
+		where lower(email) = lower($1);`,
+		email,
+	).Scan(&e.ID, &e.Login, &e.Email, &e.FirstName, &e.LastName, &e.MiddleName, &e.Sex, &e.Age, &e.CreatedAt, &e.UpdatedAt, &e.AvatarURL, &e.IsActive)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("getTxFromContext: %w", err)
+		return userDomain.User{}, fmt.Errorf("queryRowContext: %w", err)
 	}
 
-	err = tx.QueryRowContext(
+	return e.ToDomain(), nil
+}
+
+func (r repository) DeleteUser(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	db := r.db(ctx, "DeleteUser")
+
+	err := db.QueryRowContext(
 		ctx,
 		`delete
 		from "user"
-		where login = $1;`,
+		where id = $1
+		returning id;`,
 		id,
 	).Scan(&id)
 	if err != nil {
@@ -148,20 +163,17 @@ func (r repository) DeleteUser(ctx context.Context, id uuid.UUID) (uuid.UUID, er
 }
 
 func (r repository) GetUserByID(ctx context.Context, id uuid.UUID) (userDomain.User, error) {
-	tx, err := getTxFromContext(ctx)
-	if err != nil {
-		return userDomain.User{}, fmt.Errorf("getTxFromContext: %w", err)
-	}
+	db := r.db(ctx, "GetUserByID")
 
 	var e userDomain.Entity
 
-	err = tx.QueryRowContext(
+	err := db.QueryRowContext(
 		ctx,
-		`select id, login, email, first_name, last_name, middle_name, sex, age, created_at, updated_at, avatar_url
+		`select id, login, email, first_name, last_name, middle_name, sex, age, created_at, updated_at, avatar_url, is_active
 		from "user"
 		where id = $1;`,
 		id,
-	).Scan(&e.ID, &e.Login, &e.Email, &e.FirstName, &e.LastName, &e.MiddleName, &e.Sex, &e.Age, &e.CreatedAt, &e.UpdatedAt, &e.AvatarURL)
+	).Scan(&e.ID, &e.Login, &e.Email, &e.FirstName, &e.LastName, &e.MiddleName, &e.Sex, &e.Age, &e.CreatedAt, &e.UpdatedAt, &e.AvatarURL, &e.IsActive)
 	if err != nil {
 		return userDomain.User{}, fmt.Errorf("queryRowContext: %w", err)
 	}
@@ -170,30 +182,168 @@ func (r repository) GetUserByID(ctx context.Context, id uuid.UUID) (userDomain.U
 }
 
 func (r repository) GetUsers(ctx context.Context) ([]userDomain.User, error) {
-	tx, err := getTxFromContext(ctx)
+	db := r.db(ctx, "GetUsers")
+
+	rows, err := db.QueryContext(
+		ctx,
+		`select id, login, email, first_name, last_name, middle_name, sex, age, created_at, updated_at, avatar_url, is_active
+		from "user";`,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("getTxFromContext: %w", err)
+		return nil, fmt.Errorf("queryContext: %w", err)
 	}
+	defer rows.Close()
 
 	var users []userDomain.User
+	for rows.Next() {
+		var e userDomain.Entity
+		err = rows.Scan(&e.ID, &e.Login, &e.Email, &e.FirstName, &e.LastName, &e.MiddleName, &e.Sex, &e.Age, &e.CreatedAt, &e.UpdatedAt, &e.AvatarURL, &e.IsActive)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		users = append(users, e.ToDomain())
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return users, nil
+}
+
+func (r repository) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]userDomain.User, error) {
+	db := r.db(ctx, "GetUsersByIDs")
+
+	params := make([]string, 0, len(ids))
+	for _, id := range ids {
+		params = append(params, id.String())
+	}
 
-	rows, err := tx.QueryContext(
+	rows, err := db.QueryContext(
 		ctx,
-		`select id, login, email, first_name, last_name, middle_name, sex, age, created_at, updated_at, avatar_url
-		from "user";`,
+		`select id, login, email, first_name, last_name, middle_name, sex, age, created_at, updated_at, avatar_url, is_active
+		from "user"
+		where `+r.dialect.anyOf("id", "$1")+`;`,
+		r.dialect.array(params),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("queryContext: %w", err)
+	}
+	defer rows.Close()
+
+	var users []userDomain.User
+	for rows.Next() {
+		var e userDomain.Entity
+		err = rows.Scan(&e.ID, &e.Login, &e.Email, &e.FirstName, &e.LastName, &e.MiddleName, &e.Sex, &e.Age, &e.CreatedAt, &e.UpdatedAt, &e.AvatarURL, &e.IsActive)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		users = append(users, e.ToDomain())
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return users, nil
+}
+
+func (r repository) GetUsersByLogins(ctx context.Context, logins []string) ([]userDomain.User, error) {
+	db := r.db(ctx, "GetUsersByLogins")
+
+	params := make([]string, 0, len(logins))
+	for _, login := range logins {
+		params = append(params, userDomain.NormalizeLogin(login))
+	}
+
+	rows, err := db.QueryContext(
+		ctx,
+		`select id, login, email, first_name, last_name, middle_name, sex, age, created_at, updated_at, avatar_url, is_active
+		from "user"
+		where `+r.dialect.anyOf("lower(login)", "$1")+`;`,
+		r.dialect.array(params),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("queryRowContext: %w", err)
+		return nil, fmt.Errorf("queryContext: %w", err)
 	}
+	defer rows.Close()
 
+	var users []userDomain.User
 	for rows.Next() {
 		var e userDomain.Entity
-		err = rows.Scan(&e.ID, &e.Login, &e.Email, &e.FirstName, &e.LastName, &e.MiddleName, &e.Sex, &e.Age, &e.CreatedAt, &e.UpdatedAt, &e.AvatarURL)
+		err = rows.Scan(&e.ID, &e.Login, &e.Email, &e.FirstName, &e.LastName, &e.MiddleName, &e.Sex, &e.Age, &e.CreatedAt, &e.UpdatedAt, &e.AvatarURL, &e.IsActive)
 		if err != nil {
 			return nil, fmt.Errorf("rows.Scan: %w", err)
 		}
 		users = append(users, e.ToDomain())
 	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
 
 	return users, nil
 }
+
+// FindUsers returns page of users matching filter and total count of matching users.
+func (r repository) FindUsers(ctx context.Context, filter userDomain.Filter) ([]userDomain.User, int, error) {
+	db := r.db(ctx, "FindUsers")
+
+	var conditions []string
+	var args []any
+	if filter.Login != "" {
+		args = append(args, "%"+escapeLike(filter.Login)+"%")
+		conditions = append(conditions, fmt.Sprintf("lower(login) like $%d escape '\\'", len(args)))
+	}
+	if filter.Email != "" {
+		args = append(args, filter.Email)
+		conditions = append(conditions, fmt.Sprintf("lower(email) = $%d", len(args)))
+	}
+	if filter.Sex != "" {
+		args = append(args, filter.Sex)
+		conditions = append(conditions, fmt.Sprintf("sex = $%d", len(args)))
+	}
+
+	var where string
+	if len(conditions) > 0 {
+		where = " where " + strings.Join(conditions, " and ")
+	}
+	pageArgs := slices.Concat(args, []any{filter.Limit, filter.Offset})
+
+	rows, err := db.QueryContext(
+		ctx,
+		`select id, login, email, first_name, last_name, middle_name, sex, age, created_at, updated_at, avatar_url, is_active, count(*) over ()
+		from "user"`+where+
+			fmt.Sprintf(" order by created_at, id limit $%d offset $%d;", len(pageArgs)-1, len(pageArgs)),
+		pageArgs...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("queryContext: %w", err)
+	}
+	defer rows.Close()
+
+	var users []userDomain.User
+	var total int
+	for rows.Next() {
+		var e userDomain.Entity
+		err = rows.Scan(&e.ID, &e.Login, &e.Email, &e.FirstName, &e.LastName, &e.MiddleName, &e.Sex, &e.Age, &e.CreatedAt, &e.UpdatedAt, &e.AvatarURL, &e.IsActive, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("rows.Scan: %w", err)
+		}
+		users = append(users, e.ToDomain())
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	// Window count comes with rows, so page past the end or of zero limit is counted separately.
+	if len(users) == 0 {
+		err = db.QueryRowContext(ctx, `select count(*) from "user"`+where+`;`, args...).Scan(&total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("queryRowContext: %w", err)
+		}
+	}
+
+	return users, total, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,320 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/srgklmv/comfortel/internal/domain/event"
+	webhookDomain "github.com/srgklmv/comfortel/internal/domain/webhook"
+)
+
+const deliveriesPageSize = 100
+
+func (r repository) CreateSubscription(ctx context.Context, s webhookDomain.Subscription) (uuid.UUID, error) {
+	db := r.db(ctx, "CreateSubscription")
+
+	var id uuid.UUID
+	err := db.QueryRowContext(
+		ctx,
+		`insert into webhook_subscription (url, secret, events, is_active)
+		values ($1, $2, $3, $4)
+		returning id;`,
+		s.URL, s.Secret, r.dialect.array(s.Events), s.IsActive,
+	).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("queryRowContext: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r repository) GetSubscriptions(ctx context.Context) ([]webhookDomain.Subscription, error) {
+	db := r.db(ctx, "GetSubscriptions")
+
+	rows, err := db.QueryContext(
+		ctx,
+		`select id, url, secret, events, is_active, created_at
+		from webhook_subscription
+		order by created_at;`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("queryContext: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []webhookDomain.Subscription
+	for rows.Next() {
+		var s webhookDomain.Subscription
+		err = rows.Scan(&s.ID, &s.URL, &s.Secret, r.dialect.scanArray(&s.Events), &s.IsActive, &s.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (r repository) GetSubscriptionByID(ctx context.Context, id uuid.UUID) (webhookDomain.Subscription, error) {
+	db := r.db(ctx, "GetSubscriptionByID")
+
+	var s webhookDomain.Subscription
+	err := db.QueryRowContext(
+		ctx,
+		`select id, url, secret, events, is_active, created_at
+		from webhook_subscription
+		where id = $1;`,
+		id,
+	).Scan(&s.ID, &s.URL, &s.Secret, r.dialect.scanArray(&s.Events), &s.IsActive, &s.CreatedAt)
+	if err != nil {
+		return webhookDomain.Subscription{}, fmt.Errorf("queryRowContext: %w", err)
+	}
+
+	return s, nil
+}
+
+func (r repository) DeleteSubscription(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	db := r.db(ctx, "DeleteSubscription")
+
+	err := db.QueryRowContext(
+		ctx,
+		`delete from webhook_subscription
+		where id = $1
+		returning id;`,
+		id,
+	).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("queryRowContext: %w", err)
+	}
+
+	return id, nil
+}
+
+// EnqueueDeliveries creates delivery of event for every active subscription to its type.
+func (r repository) EnqueueDeliveries(ctx context.Context, e event.Event) error {
+	db := r.db(ctx, "EnqueueDeliveries")
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	_, err = db.ExecContext(
+		ctx,
+		`insert into webhook_delivery (subscription_id, event_id, event_type, payload)
+		select id, $1, $2, $3
+		from webhook_subscription
+		where is_active and `+r.dialect.anyOf("$2", "events")+`;`,
+		e.ID, e.Type, payload,
+	)
+	if err != nil {
+		return fmt.Errorf("execContext: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeliveries returns latest deliveries of subscription. Status is optional.
+func (r repository) GetDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string) ([]webhookDomain.Delivery, error) {
+	db := r.db(ctx, "GetDeliveries")
+
+	rows, err := db.QueryContext(
+		ctx,
+		`select id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, coalesce(last_error, ''), created_at, delivered_at
+		from webhook_delivery
+		where subscription_id = $1 and ($2 = '' or status = $2)
+		order by created_at desc
+		limit $3;`,
+		subscriptionID, status, deliveriesPageSize,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("queryContext: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []webhookDomain.Delivery
+	for rows.Next() {
+		var d webhookDomain.Delivery
+		err = rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r repository) GetDelivery(ctx context.Context, id uuid.UUID) (webhookDomain.Delivery, error) {
+	db := r.db(ctx, "GetDelivery")
+
+	var d webhookDomain.Delivery
+	err := db.QueryRowContext(
+		ctx,
+		`select id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, coalesce(last_error, ''), created_at, delivered_at
+		from webhook_delivery
+		where id = $1;`,
+		id,
+	).Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+	if err != nil {
+		return webhookDomain.Delivery{}, fmt.Errorf("queryRowContext: %w", err)
+	}
+
+	return d, nil
+}
+
+func (r repository) GetDeliveryAttempts(ctx context.Context, deliveryID uuid.UUID) ([]webhookDomain.Attempt, error) {
+	db := r.db(ctx, "GetDeliveryAttempts")
+
+	rows, err := db.QueryContext(
+		ctx,
+		`select delivery_id, coalesce(status_code, 0), coalesce(error, ''), duration_ms, attempted_at
+		from webhook_delivery_attempt
+		where delivery_id = $1
+		order by id;`,
+		deliveryID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("queryContext: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []webhookDomain.Attempt
+	for rows.Next() {
+		var a webhookDomain.Attempt
+		var durationMS int64
+		err = rows.Scan(&a.DeliveryID, &a.StatusCode, &a.Error, &durationMS, &a.AttemptedAt)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		a.Duration = time.Duration(durationMS) * time.Millisecond
+		attempts = append(attempts, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// RedeliverDelivery schedules delivery for immediate sending with fresh attempts budget.
+func (r repository) RedeliverDelivery(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	db := r.db(ctx, "RedeliverDelivery")
+
+	err := db.QueryRowContext(
+		ctx,
+		`update webhook_delivery
+		set status = $2, attempts = 0, next_attempt_at = `+r.dialect.now()+`, delivered_at = null
+		where id = $1
+		returning id;`,
+		id, webhookDomain.StatusPending,
+	).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("queryRowContext: %w", err)
+	}
+
+	return id, nil
+}
+
+// ClaimDeliveries locks due pending deliveries by moving their next attempt time lease forward,
+// so other workers skip them while they are being sent.
+func (r repository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]webhookDomain.Job, error) {
+	db := r.db(ctx, "ClaimDeliveries")
+
+	query := `update webhook_delivery d
+		set next_attempt_at = ` + r.dialect.nowPlusSeconds("$3") + `
+		from webhook_subscription s
+		where s.id = d.subscription_id and d.id in (
+			select id
+			from webhook_delivery
+			where status = $1 and next_attempt_at <= ` + r.dialect.now() + `
+			order by next_attempt_at
+			limit $2
+			for update skip locked
+		)
+		returning d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.created_at, s.url, s.secret;`
+	// Sqlite has no row locks, but its write transactions exclude each other anyway.
+	// Returning may not reference other tables, so subscription is selected by subqueries.
+	if r.dialect.sqlite() {
+		query = `update webhook_delivery
+		set next_attempt_at = ` + r.dialect.nowPlusSeconds("$3") + `
+		where id in (
+			select id
+			from webhook_delivery
+			where status = $1 and next_attempt_at <= ` + r.dialect.now() + `
+			order by next_attempt_at
+			limit $2
+		)
+		returning id, subscription_id, event_id, event_type, payload, status, attempts, created_at,
+			(select url from webhook_subscription s where s.id = subscription_id),
+			(select secret from webhook_subscription s where s.id = subscription_id);`
+	}
+
+	rows, err := db.QueryContext(ctx, query, webhookDomain.StatusPending, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("queryContext: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []webhookDomain.Job
+	for rows.Next() {
+		var j webhookDomain.Job
+		d := &j.Delivery
+		err = rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.CreatedAt, &j.URL, &j.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		jobs = append(jobs, j)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// RecordAttempt saves attempt to delivery log and updates delivery with its outcome.
+// retryIn is used for pending status only.
+func (r repository) RecordAttempt(ctx context.Context, attempt webhookDomain.Attempt, status string, retryIn time.Duration) error {
+	db := r.db(ctx, "RecordAttempt")
+
+	statusCode := sql.NullInt64{Int64: int64(attempt.StatusCode), Valid: attempt.StatusCode != 0}
+	attemptErr := sql.NullString{String: attempt.Error, Valid: attempt.Error != ""}
+
+	_, err := db.ExecContext(
+		ctx,
+		`insert into webhook_delivery_attempt (delivery_id, status_code, error, duration_ms)
+		values ($1, $2, $3, $4);`,
+		attempt.DeliveryID, statusCode, attemptErr, attempt.Duration.Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("execContext: %w", err)
+	}
+
+	_, err = db.ExecContext(
+		ctx,
+		`update webhook_delivery
+		set status = $2,
+			attempts = attempts + 1,
+			last_error = $3,
+			next_attempt_at = `+r.dialect.nowPlusSeconds("$4")+`,
+			delivered_at = case when $2 = $5 then `+r.dialect.now()+` end
+		where id = $1;`,
+		attempt.DeliveryID, status, attemptErr, retryIn.Seconds(), webhookDomain.StatusDelivered,
+	)
+	if err != nil {
+		return fmt.Errorf("execContext: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package scim
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+)
+
+func (h handler) ServiceProviderConfig(gc *gin.Context) {
+	write(gc, http.StatusOK, map[string]any{
+		"schemas": []string{ServiceProviderConfigSchema},
+		"patch":   map[string]any{"supported": true},
+		"bulk":    map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":  map[string]any{"supported": true, "maxResults": userDomain.MaxSearchLimit},
+		"changePassword": map[string]any{
+			"supported": false,
+		},
+		"sort": map[string]any{"supported": false},
+		"etag": map[string]any{"supported": false},
+		"authenticationSchemes": []map[string]any{
+			{
+				"type":        "oauthbearertoken",
+				"name":        "Bearer token",
+				"description": "Authentication with static bearer token from service configuration.",
+				"primary":     true,
+			},
+		},
+		"meta": map[string]any{
+			"resourceType": "ServiceProviderConfig",
+			"location":     h.baseURL(gc) + "/ServiceProviderConfig",
+		},
+	})
+}
+
+func (h handler) ResourceTypes(gc *gin.Context) {
+	write(gc, http.StatusOK, listOf(h.userResourceType(gc)))
+}
+
+func (h handler) Schemas(gc *gin.Context) {
+	write(gc, http.StatusOK, listOf(h.userSchema(gc)))
+}
+
+// listOf returns list response with arbitrary resources, used by discovery endpoints.
+func listOf(resources ...map[string]any) map[string]any {
+	return map[string]any{
+		"schemas":      []string{ListResponseSchema},
+		"totalResults": len(resources),
+		"startIndex":   1,
+		"itemsPerPage": len(resources),
+		"Resources":    resources,
+	}
+}
+
+func (h handler) userResourceType(gc *gin.Context) map[string]any {
+	return map[string]any{
+		"schemas":     []string{ResourceTypeSchema},
+		"id":          "User",
+		"name":        "User",
+		"endpoint":    "/Users",
+		"description": "User account",
+		"schema":      UserSchema,
+		"meta": map[string]any{
+			"resourceType": "ResourceType",
+			"location":     h.baseURL(gc) + "/ResourceTypes/User",
+		},
+	}
+}
+
+func (h handler) userSchema(gc *gin.Context) map[string]any {
+	return map[string]any{
+		"schemas":     []string{SchemaSchema},
+		"id":          UserSchema,
+		"name":        "User",
+		"description": "User account",
+		"attributes": []map[string]any{
+			attribute("userName", "string", true, "immutable", "server"),
+			{
+				"name":        "name",
+				"type":        "complex",
+				"multiValued": false,
+				"required":    false,
+				"mutability":  "readWrite",
+				"returned":    "default",
+				"subAttributes": []map[string]any{
+					attribute("givenName", "string", false, "readWrite", "none"),
+					attribute("familyName", "string", false, "readWrite", "none"),
+					attribute("middleName", "string", false, "readWrite", "none"),
+				},
+			},
+			multiValuedAttribute("emails", "server"),
+			multiValuedAttribute("photos", "none"),
+			attribute("active", "boolean", false, "readWrite", "none"),
+			{
+				"name":        "password",
+				"type":        "string",
+				"multiValued": false,
+				"required":    false,
+				"mutability":  "writeOnly",
+				"returned":    "never",
+				"uniqueness":  "none",
+			},
+		},
+		"meta": map[string]any{
+			"resourceType": "Schema",
+			"location":     h.baseURL(gc) + "/Schemas/" + UserSchema,
+		},
+	}
+}
+
+func attribute(name, typ string, required bool, mutability, uniqueness string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"type":        typ,
+		"multiValued": false,
+		"required":    required,
+		"caseExact":   false,
+		"mutability":  mutability,
+		"returned":    "default",
+		"uniqueness":  uniqueness,
+	}
+}
+
+func multiValuedAttribute(name, uniqueness string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"type":        "complex",
+		"multiValued": true,
+		"required":    false,
+		"mutability":  "readWrite",
+		"returned":    "default",
+		"uniqueness":  uniqueness,
+		"subAttributes": []map[string]any{
+			attribute("value", "string", false, "readWrite", "none"),
+			attribute("type", "string", false, "readWrite", "none"),
+			attribute("primary", "boolean", false, "readWrite", "none"),
+		},
+	}
+}
@@ -0,0 +1,116 @@
+package scim
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// comparison is single "attribute operator value" expression of SCIM filter.
+type comparison struct {
+	attr  string
+	op    string
+	value string
+}
+
+// supportedFilters lists operators allowed for each attribute. Attributes are lowercased.
+var supportedFilters = map[string][]string{
+	"id":           {"eq"},
+	"username":     {"eq", "co"},
+	"emails":       {"eq"},
+	"emails.value": {"eq"},
+}
+
+// parseFilter parses subset of RFC 7644 filter syntax: comparisons joined with "and". Search applies one
+// comparison per attribute, so attribute compared twice is rejected instead of having one comparison dropped.
+func parseFilter(filter string) ([]comparison, error) {
+	tokens, err := tokenize(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	var result []comparison
+	seen := make(map[string]bool)
+	for len(tokens) > 0 {
+		if len(tokens) < 3 {
+			return nil, errors.New("incomplete expression")
+		}
+
+		c := comparison{
+			attr:  strings.ToLower(tokens[0]),
+			op:    strings.ToLower(tokens[1]),
+			value: tokens[2],
+		}
+
+		ops, ok := supportedFilters[c.attr]
+		if !ok {
+			return nil, fmt.Errorf("filtering by %q is not supported", tokens[0])
+		}
+		supported := false
+		for _, op := range ops {
+			supported = supported || op == c.op
+		}
+		if !supported {
+			return nil, fmt.Errorf("operator %q is not supported for %q", tokens[1], tokens[0])
+		}
+
+		attr := strings.TrimSuffix(c.attr, ".value")
+		if seen[attr] {
+			return nil, fmt.Errorf("%q may be compared once", tokens[0])
+		}
+		seen[attr] = true
+
+		result = append(result, c)
+		tokens = tokens[3:]
+
+		if len(tokens) > 0 {
+			if !strings.EqualFold(tokens[0], "and") {
+				return nil, fmt.Errorf("logical operator %q is not supported", tokens[0])
+			}
+			tokens = tokens[1:]
+			if len(tokens) == 0 {
+				return nil, errors.New("incomplete expression")
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// tokenize splits filter by spaces keeping quoted strings as single unquoted token.
+func tokenize(filter string) ([]string, error) {
+	var tokens []string
+	rest := strings.TrimSpace(filter)
+	for rest != "" {
+		if rest[0] == '"' {
+			end := 1
+			for end < len(rest) && (rest[end] != '"' || rest[end-1] == '\\') {
+				end++
+			}
+			if end == len(rest) {
+				return nil, errors.New("unterminated string")
+			}
+
+			value, err := strconv.Unquote(rest[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", rest[:end+1])
+			}
+			tokens = append(tokens, value)
+			rest = strings.TrimSpace(rest[end+1:])
+			continue
+		}
+
+		token, tail, _ := strings.Cut(rest, " ")
+		if strings.ContainsAny(token, "()[]") {
+			return nil, fmt.Errorf("grouping is not supported: %s", token)
+		}
+		tokens = append(tokens, token)
+		rest = strings.TrimSpace(tail)
+	}
+
+	return tokens, nil
+}
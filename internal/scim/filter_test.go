@@ -0,0 +1,42 @@
+package scim
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []comparison
+		err    bool
+	}{
+		{filter: ""},
+		{filter: `userName eq "alice"`, want: []comparison{{attr: "username", op: "eq", value: "alice"}}},
+		{
+			filter: `UserName Co "a b" AND emails.value eq "a@example.com"`,
+			want:   []comparison{{attr: "username", op: "co", value: "a b"}, {attr: "emails.value", op: "eq", value: "a@example.com"}},
+		},
+		{filter: `id eq "x" and userName eq "alice"`, want: []comparison{{attr: "id", op: "eq", value: "x"}, {attr: "username", op: "eq", value: "alice"}}},
+		{filter: `userName eq "alice" and userName co "al"`, err: true},
+		{filter: `emails eq "a@example.com" and emails.value eq "b@example.com"`, err: true},
+		{filter: `userName sw "al"`, err: true},
+		{filter: `name.givenName eq "Alice"`, err: true},
+		{filter: `userName eq "alice" or userName eq "bob"`, err: true},
+		{filter: `(userName eq "alice")`, err: true},
+		{filter: `userName eq "alice`, err: true},
+		{filter: `userName eq "alice" and`, err: true},
+		{filter: `userName eq`, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			got, err := parseFilter(tt.filter)
+			if (err != nil) != tt.err {
+				t.Fatalf("error %v, want error %t", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
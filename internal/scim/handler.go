@@ -0,0 +1,405 @@
+package scim
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	"github.com/srgklmv/comfortel/internal/metrics"
+	"github.com/srgklmv/comfortel/internal/middleware"
+)
+
+const basePath = "/scim/v2"
+
+type usecase interface {
+	CreateUser(ctx context.Context, data userDomain.CreateUserRequestDTO) (any, int)
+	GetUserByID(ctx context.Context, id string) (any, int)
+	GetUserByLogin(ctx context.Context, login string) (any, int)
+	SearchUsers(ctx context.Context, data userDomain.SearchUsersRequestDTO) (any, int)
+	UpdateUser(ctx context.Context, id string, data userDomain.UpdateUserRequestDTO) (any, int)
+	DeleteUser(ctx context.Context, id string) (any, int)
+}
+
+type handler struct {
+	userUsecase    usecase
+	tokens         []string
+	trustedProxies []netip.Prefix
+}
+
+type Option func(*handler)
+
+// WithTrustedProxies sets proxies whose X-Forwarded-Proto is used in resource locations,
+// same as trusted proxies of gin engine.
+func WithTrustedProxies(proxies []netip.Prefix) Option {
+	return func(h *handler) {
+		h.trustedProxies = proxies
+	}
+}
+
+// New returns SCIM handler. Requests are authorized by bearer tokens,
+// all of them are rejected if there are no tokens.
+func New(uc usecase, tokens []string, opts ...Option) *handler {
+	h := &handler{
+		userUsecase: uc,
+		tokens:      tokens,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+func (h handler) Authenticate(gc *gin.Context) {
+	token, ok := strings.CutPrefix(gc.GetHeader("Authorization"), "Bearer ")
+	if ok {
+		for _, t := range h.tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+				gc.Next()
+				return
+			}
+		}
+	}
+
+	metrics.AuthFailed("scim")
+	gc.Header("WWW-Authenticate", `Bearer realm="scim"`)
+	writeError(gc, http.StatusUnauthorized, "", "Authorization failure.")
+	gc.Abort()
+}
+
+func (h handler) GetUsers(gc *gin.Context) {
+	startIndex, err := intQuery(gc, "startIndex", 1)
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := intQuery(gc, "count", userDomain.DefaultSearchLimit)
+	if err != nil || count < 0 {
+		count = userDomain.DefaultSearchLimit
+	}
+	count = min(count, userDomain.MaxSearchLimit)
+
+	filter, err := parseFilter(gc.Query("filter"))
+	if err != nil {
+		writeError(gc, http.StatusBadRequest, "invalidFilter", err.Error())
+		return
+	}
+
+	users, total, status, response := h.findUsers(gc, filter, startIndex, count)
+	if status >= http.StatusBadRequest {
+		writeUsecaseError(gc, response, status)
+		return
+	}
+
+	list := ListResponse{
+		Schemas:      []string{ListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(users),
+		Resources:    make([]User, 0, len(users)),
+	}
+	for _, u := range users {
+		list.Resources = append(list.Resources, fromDTO(u, h.location(gc, u.ID)))
+	}
+
+	write(gc, http.StatusOK, list)
+}
+
+// findUsers resolves filter with single user lookup if it contains exact id or userName match, which
+// is then checked against rest of comparisons, otherwise with users search.
+func (h handler) findUsers(ctx context.Context, filter []comparison, startIndex, count int) ([]userDomain.GetUserDTO, int, int, any) {
+	var exact *comparison
+	search := userDomain.SearchUsersRequestDTO{
+		Limit:  max(count, 1),
+		Offset: startIndex - 1,
+	}
+	for i, c := range filter {
+		switch {
+		case c.attr == "id" || (c.attr == "username" && c.op == "eq"):
+			exact = &filter[i]
+		case c.attr == "username":
+			search.Login = c.value
+		case c.attr == "emails" || c.attr == "emails.value":
+			search.Email = c.value
+		}
+	}
+
+	if exact == nil {
+		response, status := h.userUsecase.SearchUsers(ctx, search)
+		if status >= http.StatusBadRequest {
+			return nil, 0, status, response
+		}
+
+		page := response.(userDomain.UsersPageDTO)
+		if count == 0 {
+			return nil, page.Total, status, response
+		}
+		return page.Users, page.Total, status, response
+	}
+
+	var response any
+	var status int
+	if exact.attr == "id" {
+		response, status = h.userUsecase.GetUserByID(ctx, exact.value)
+	} else {
+		response, status = h.userUsecase.GetUserByLogin(ctx, exact.value)
+	}
+	if status == http.StatusNotFound || (exact.attr == "id" && status == http.StatusBadRequest) {
+		return nil, 0, http.StatusOK, nil
+	}
+	if status >= http.StatusBadRequest {
+		return nil, 0, status, response
+	}
+
+	u := response.(userDomain.GetUserDTO)
+	if !matches(u, filter) {
+		return nil, 0, http.StatusOK, nil
+	}
+	if startIndex > 1 || count == 0 {
+		return nil, 1, http.StatusOK, nil
+	}
+
+	return []userDomain.GetUserDTO{u}, 1, http.StatusOK, nil
+}
+
+func matches(u userDomain.GetUserDTO, filter []comparison) bool {
+	for _, c := range filter {
+		var ok bool
+		switch c.attr {
+		case "id":
+			ok = strings.EqualFold(u.ID, c.value)
+		case "username":
+			if c.op == "co" {
+				ok = strings.Contains(u.Login, userDomain.NormalizeLogin(c.value))
+			} else {
+				ok = u.Login == userDomain.NormalizeLogin(c.value)
+			}
+		case "emails", "emails.value":
+			ok = u.Email == userDomain.NormalizeEmail(c.value)
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (h handler) GetUser(gc *gin.Context) {
+	middleware.SetUserID(gc, gc.Param("id"))
+	response, status := h.userUsecase.GetUserByID(gc, gc.Param("id"))
+	if status >= http.StatusBadRequest {
+		writeUsecaseError(gc, response, status)
+		return
+	}
+
+	u := response.(userDomain.GetUserDTO)
+	write(gc, http.StatusOK, fromDTO(u, h.location(gc, u.ID)))
+}
+
+func (h handler) CreateUser(gc *gin.Context) {
+	var body User
+	if err := json.NewDecoder(gc.Request.Body).Decode(&body); err != nil {
+		writeError(gc, http.StatusBadRequest, "invalidSyntax", "Request body invalid.")
+		return
+	}
+
+	dto := body.toCreateDTO()
+	if dto.Password == "" {
+		// Provisioned users sign in through identity provider, but password is mandatory for us.
+		password, err := randomPassword()
+		if err != nil {
+			writeError(gc, http.StatusInternalServerError, "", string(apperror.InternalErrorText))
+			return
+		}
+		dto.Password = password
+	}
+
+	response, status := h.userUsecase.CreateUser(gc, dto)
+	if status >= http.StatusBadRequest {
+		writeUsecaseError(gc, response, status)
+		return
+	}
+	id := response.(userDomain.CreateUserResponseDTO).Created
+	middleware.SetUserID(gc, id)
+
+	if body.Active != nil && !*body.Active {
+		response, status = h.userUsecase.UpdateUser(gc, id, userDomain.UpdateUserRequestDTO{IsActive: body.Active})
+	} else {
+		response, status = h.userUsecase.GetUserByID(gc, id)
+	}
+	if status >= http.StatusBadRequest {
+		writeUsecaseError(gc, response, status)
+		return
+	}
+
+	u := response.(userDomain.GetUserDTO)
+	gc.Header("Location", h.location(gc, u.ID))
+	write(gc, http.StatusCreated, fromDTO(u, h.location(gc, u.ID)))
+}
+
+// ReplaceUser handles PUT. Login is immutable, so userName must match current one.
+func (h handler) ReplaceUser(gc *gin.Context) {
+	middleware.SetUserID(gc, gc.Param("id"))
+	var body User
+	if err := json.NewDecoder(gc.Request.Body).Decode(&body); err != nil {
+		writeError(gc, http.StatusBadRequest, "invalidSyntax", "Request body invalid.")
+		return
+	}
+
+	response, status := h.userUsecase.GetUserByID(gc, gc.Param("id"))
+	if status >= http.StatusBadRequest {
+		writeUsecaseError(gc, response, status)
+		return
+	}
+	current := response.(userDomain.GetUserDTO)
+	if body.UserName != "" && userDomain.NormalizeLogin(body.UserName) != current.Login {
+		writeError(gc, http.StatusBadRequest, "mutability", "userName can not be changed.")
+		return
+	}
+
+	h.update(gc, current.ID, body.toUpdateDTO())
+}
+
+func (h handler) PatchUser(gc *gin.Context) {
+	middleware.SetUserID(gc, gc.Param("id"))
+	var body PatchRequest
+	if err := json.NewDecoder(gc.Request.Body).Decode(&body); err != nil {
+		writeError(gc, http.StatusBadRequest, "invalidSyntax", "Request body invalid.")
+		return
+	}
+
+	dto, scimType, err := patchToDTO(body.Operations)
+	if err != nil {
+		writeError(gc, http.StatusBadRequest, scimType, err.Error())
+		return
+	}
+
+	h.update(gc, gc.Param("id"), dto)
+}
+
+func (h handler) update(gc *gin.Context, id string, dto userDomain.UpdateUserRequestDTO) {
+	response, status := h.userUsecase.UpdateUser(gc, id, dto)
+	if status >= http.StatusBadRequest {
+		writeUsecaseError(gc, response, status)
+		return
+	}
+
+	u := response.(userDomain.GetUserDTO)
+	write(gc, http.StatusOK, fromDTO(u, h.location(gc, u.ID)))
+}
+
+func (h handler) DeleteUser(gc *gin.Context) {
+	middleware.SetUserID(gc, gc.Param("id"))
+	response, status := h.userUsecase.DeleteUser(gc, gc.Param("id"))
+	if status >= http.StatusBadRequest {
+		writeUsecaseError(gc, response, status)
+		return
+	}
+
+	gc.Status(http.StatusNoContent)
+}
+
+func write(gc *gin.Context, status int, body any) {
+	gc.Header("Content-Type", contentType)
+	gc.Status(status)
+	if err := json.NewEncoder(gc.Writer).Encode(body); err != nil {
+		gc.Error(err)
+	}
+}
+
+func writeError(gc *gin.Context, status int, scimType, detail string) {
+	write(gc, status, Error{
+		Schemas:  []string{ErrorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+func writeUsecaseError(gc *gin.Context, response any, status int) {
+	detail := http.StatusText(status)
+	if appErr, ok := response.(apperror.AppError); ok {
+		detail = string(appErr.Error)
+		if appErr.Message != "" {
+			detail = fmt.Sprintf("%s %s", appErr.Error, appErr.Message)
+		}
+	}
+
+	var scimType string
+	switch status {
+	case http.StatusConflict:
+		scimType = "uniqueness"
+	case http.StatusBadRequest:
+		scimType = "invalidValue"
+	}
+
+	writeError(gc, status, scimType, detail)
+}
+
+// baseURL returns URL of SCIM endpoints as client sees it. X-Forwarded-Proto is honored only if request
+// comes from trusted proxy, otherwise anyone could make resources point to other scheme.
+func (h handler) baseURL(gc *gin.Context) string {
+	scheme := "http"
+	if gc.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := gc.GetHeader("X-Forwarded-Proto"); (proto == "http" || proto == "https") && h.fromTrustedProxy(gc) {
+		scheme = proto
+	}
+
+	return fmt.Sprintf("%s://%s%s", scheme, gc.Request.Host, basePath)
+}
+
+func (h handler) location(gc *gin.Context, id string) string {
+	return h.baseURL(gc) + "/Users/" + id
+}
+
+func (h handler) fromTrustedProxy(gc *gin.Context) bool {
+	addr, err := netip.ParseAddr(gc.RemoteIP())
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, proxy := range h.trustedProxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func intQuery(gc *gin.Context, name string, fallback int) (int, error) {
+	value := gc.Query(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	return strconv.Atoi(value)
+}
+
+const passwordAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func randomPassword() (string, error) {
+	b := make([]byte, 20)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(passwordAlphabet))))
+		if err != nil {
+			return "", fmt.Errorf("rand.Int: %w", err)
+		}
+		b[i] = passwordAlphabet[n.Int64()]
+	}
+
+	return string(b), nil
+}
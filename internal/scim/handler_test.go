@@ -0,0 +1,268 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	"github.com/srgklmv/comfortel/internal/middleware"
+	"github.com/srgklmv/comfortel/internal/repository/memory"
+	usecaseImpl "github.com/srgklmv/comfortel/internal/usecase"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+const testToken = "scim-token"
+
+type userCreator interface {
+	CreateUser(ctx context.Context, data userDomain.User, hashedPassword string) (uuid.UUID, error)
+}
+
+// quickUsecase creates users without hashing password, which takes about a second, and remembers their passwords.
+type quickUsecase struct {
+	usecase
+	repo      userCreator
+	passwords *[]string
+}
+
+func (uc quickUsecase) CreateUser(ctx context.Context, data userDomain.CreateUserRequestDTO) (any, int) {
+	*uc.passwords = append(*uc.passwords, data.Password)
+
+	id, err := uc.repo.CreateUser(ctx, data.ToDomain(), "hashed")
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+
+	return userDomain.CreateUserResponseDTO{Created: id.String()}, http.StatusOK
+}
+
+type server struct {
+	engine    *gin.Engine
+	repo      userCreator
+	passwords []string
+}
+
+// newServer serves SCIM routes like api does, with usecases over in-memory repository.
+func newServer(t *testing.T) *server {
+	t.Helper()
+	logger.SetDefault(slog.New(slog.DiscardHandler))
+	gin.SetMode(gin.TestMode)
+
+	mem := memory.New()
+	s := &server{engine: gin.New(), repo: mem}
+	h := New(quickUsecase{usecase: usecaseImpl.New(mem, mem), repo: mem, passwords: &s.passwords}, []string{testToken})
+
+	s.engine.ContextWithFallback = true
+	group := s.engine.Group(basePath, h.Authenticate)
+	group.GET("/ServiceProviderConfig", h.ServiceProviderConfig)
+	users := group.Group("/Users", middleware.Transaction(mem, s.engine))
+	users.GET("", h.GetUsers)
+	users.POST("", h.CreateUser)
+	users.GET("/:id", h.GetUser)
+
+	return s
+}
+
+func (s *server) seed(t *testing.T, login string) string {
+	t.Helper()
+
+	id, err := s.repo.CreateUser(context.Background(), userDomain.User{Login: login, Email: login + "@example.com"}, "hashed")
+	if err != nil {
+		t.Fatalf("repo.CreateUser: %v", err)
+	}
+
+	return id.String()
+}
+
+func (s *server) do(method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+
+	return w
+}
+
+func (s *server) list(t *testing.T, query url.Values) ListResponse {
+	t.Helper()
+
+	w := s.do(http.MethodGet, basePath+"/Users?"+query.Encode(), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+
+	return decode[ListResponse](t, w)
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("json.Unmarshal %q: %v", w.Body, err)
+	}
+
+	return v
+}
+
+func TestAuthenticate(t *testing.T) {
+	s := newServer(t)
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{name: "no token", status: http.StatusUnauthorized},
+		{name: "wrong token", authorization: "Bearer other", status: http.StatusUnauthorized},
+		{name: "token of other scheme", authorization: "Basic " + testToken, status: http.StatusUnauthorized},
+		{name: "token", authorization: "Bearer " + testToken, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, basePath+"/ServiceProviderConfig", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			s.engine.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d", w.Code, tt.status)
+			}
+			if w.Code == http.StatusUnauthorized {
+				if got := decode[Error](t, w); got.Status != "401" || w.Header().Get("WWW-Authenticate") == "" {
+					t.Errorf("got %+v and WWW-Authenticate %q", got, w.Header().Get("WWW-Authenticate"))
+				}
+			}
+		})
+	}
+}
+
+func TestGetUsersFilter(t *testing.T) {
+	s := newServer(t)
+	s.seed(t, "alice")
+	bob := s.seed(t, "bobby")
+	s.seed(t, "carol")
+
+	tests := []struct {
+		filter string
+		logins []string
+	}{
+		{filter: `userName eq "ALICE"`, logins: []string{"alice"}},
+		{filter: `userName eq "alice" and emails eq "alice@example.com"`, logins: []string{"alice"}},
+		{filter: `userName eq "alice" and emails eq "bobby@example.com"`},
+		{filter: `userName co "o"`, logins: []string{"bobby", "carol"}},
+		{filter: `userName co "o" and emails.value eq "carol@example.com"`, logins: []string{"carol"}},
+		{filter: `id eq "` + bob + `"`, logins: []string{"bobby"}},
+		{filter: `id eq "not-uuid"`},
+		{filter: `userName eq "nobody"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			list := s.list(t, url.Values{"filter": {tt.filter}})
+
+			var logins []string
+			for _, u := range list.Resources {
+				logins = append(logins, u.UserName)
+			}
+			slices.Sort(logins)
+			if strings.Join(logins, ",") != strings.Join(tt.logins, ",") || list.TotalResults != len(tt.logins) {
+				t.Errorf("got %v of %d, want %v", logins, list.TotalResults, tt.logins)
+			}
+		})
+	}
+}
+
+func TestGetUsersRejectsInvalidFilter(t *testing.T) {
+	s := newServer(t)
+
+	for _, filter := range []string{`userName eq "alice" and userName co "b"`, `userName eq "alice" or userName eq "bob"`, `title eq "x"`} {
+		t.Run(filter, func(t *testing.T) {
+			w := s.do(http.MethodGet, basePath+"/Users?"+url.Values{"filter": {filter}}.Encode(), "")
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			if got := decode[Error](t, w); got.ScimType != "invalidFilter" {
+				t.Errorf("scimType %q, want invalidFilter", got.ScimType)
+			}
+		})
+	}
+}
+
+func TestGetUsersPaging(t *testing.T) {
+	s := newServer(t)
+	for _, login := range []string{"alice", "bobby", "carol"} {
+		s.seed(t, login)
+	}
+
+	tests := []struct {
+		name       string
+		query      url.Values
+		startIndex int
+		items      int
+		total      int
+	}{
+		{name: "first page", query: url.Values{"count": {"2"}}, startIndex: 1, items: 2, total: 3},
+		{name: "last page", query: url.Values{"startIndex": {"3"}, "count": {"2"}}, startIndex: 3, items: 1, total: 3},
+		{name: "past the end", query: url.Values{"startIndex": {"10"}}, startIndex: 10, items: 0, total: 3},
+		{name: "count only", query: url.Values{"count": {"0"}}, startIndex: 1, items: 0, total: 3},
+		{name: "invalid start index", query: url.Values{"startIndex": {"0"}}, startIndex: 1, items: 3, total: 3},
+		{name: "exact match past the end", query: url.Values{"filter": {`userName eq "alice"`}, "startIndex": {"2"}}, startIndex: 2, items: 0, total: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := s.list(t, tt.query)
+			if list.StartIndex != tt.startIndex || list.ItemsPerPage != tt.items || len(list.Resources) != tt.items || list.TotalResults != tt.total {
+				t.Errorf("got start %d, %d items of %d, want start %d, %d items of %d",
+					list.StartIndex, len(list.Resources), list.TotalResults, tt.startIndex, tt.items, tt.total)
+			}
+		})
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		active bool
+	}{
+		{name: "active by default", body: `{"schemas":["` + UserSchema + `"],"userName":"alice"}`, active: true},
+		{name: "inactive", body: `{"schemas":["` + UserSchema + `"],"userName":"alice","active":false}`, active: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+
+			w := s.do(http.MethodPost, basePath+"/Users", tt.body)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status %d, body %s", w.Code, w.Body)
+			}
+			created := decode[User](t, w)
+			if created.Active == nil || *created.Active != tt.active {
+				t.Errorf("created user is active %v, want %t", created.Active, tt.active)
+			}
+			if location := w.Header().Get("Location"); !strings.HasSuffix(location, "/Users/"+created.ID) {
+				t.Errorf("Location %q is not of user %s", location, created.ID)
+			}
+			// Provisioned user without password gets random one.
+			if len(s.passwords) != 1 || len(s.passwords[0]) != 20 {
+				t.Errorf("passwords %q, want one random", s.passwords)
+			}
+
+			got := decode[User](t, s.do(http.MethodGet, basePath+"/Users/"+created.ID, ""))
+			if got.Active == nil || *got.Active != tt.active {
+				t.Errorf("stored user is active %v, want %t", got.Active, tt.active)
+			}
+		})
+	}
+}
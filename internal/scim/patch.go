@@ -0,0 +1,138 @@
+package scim
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+)
+
+// patchToDTO converts PATCH operations into update request.
+// Only "add" and "replace" are supported as user fields can not be cleared.
+func patchToDTO(ops []PatchOperation) (userDomain.UpdateUserRequestDTO, string, error) {
+	var dto userDomain.UpdateUserRequestDTO
+	if len(ops) == 0 {
+		return dto, "invalidValue", errors.New("no operations passed")
+	}
+
+	for _, op := range ops {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+		case "remove":
+			return dto, "mutability", errors.New("remove operation is not supported")
+		default:
+			return dto, "invalidSyntax", fmt.Errorf("unknown operation %q", op.Op)
+		}
+
+		if op.Path == "" {
+			values, ok := op.Value.(map[string]any)
+			if !ok {
+				return dto, "invalidValue", errors.New("value must be an object when path is omitted")
+			}
+			for path, value := range values {
+				if scimType, err := applyPatch(&dto, path, value); err != nil {
+					return dto, scimType, err
+				}
+			}
+			continue
+		}
+
+		if scimType, err := applyPatch(&dto, op.Path, op.Value); err != nil {
+			return dto, scimType, err
+		}
+	}
+
+	return dto, "", nil
+}
+
+func applyPatch(dto *userDomain.UpdateUserRequestDTO, path string, value any) (string, error) {
+	attr := strings.ToLower(path)
+	// Value filters like emails[type eq "work"].value address our single email or photo.
+	if base, _, ok := strings.Cut(attr, "["); ok {
+		attr = base
+	}
+	attr = strings.TrimSuffix(attr, ".value")
+
+	switch attr {
+	case "name":
+		name, ok := value.(map[string]any)
+		if !ok {
+			return "invalidValue", errors.New("name must be an object")
+		}
+		for sub, v := range name {
+			if scimType, err := applyPatch(dto, "name."+sub, v); err != nil {
+				return scimType, err
+			}
+		}
+	case "name.givenname":
+		return assignString(&dto.FirstName, path, value)
+	case "name.familyname":
+		return assignString(&dto.LastName, path, value)
+	case "name.middlename":
+		return assignString(&dto.MiddleName, path, value)
+	case "emails":
+		return assignMultiValued(&dto.Email, path, value)
+	case "photos":
+		return assignMultiValued(&dto.AvatarURL, path, value)
+	case "active":
+		active, err := parseBool(value)
+		if err != nil {
+			return "invalidValue", fmt.Errorf("%s: %w", path, err)
+		}
+		dto.IsActive = &active
+	case "username":
+		return "mutability", errors.New("userName can not be changed")
+	default:
+		return "invalidPath", fmt.Errorf("attribute %q is not supported", path)
+	}
+
+	return "", nil
+}
+
+func assignString(dst *string, path string, value any) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "invalidValue", fmt.Errorf("%s must be a string", path)
+	}
+	*dst = s
+
+	return "", nil
+}
+
+// assignMultiValued accepts plain value or list of multi-valued attributes and takes primary one.
+func assignMultiValued(dst *string, path string, value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		*dst = v
+		return "", nil
+	case []any:
+		var values []MultiValued
+		for _, item := range v {
+			m, ok := item.(map[string]any)
+			if !ok {
+				return "invalidValue", fmt.Errorf("%s items must be objects", path)
+			}
+			s, _ := m["value"].(string)
+			primary, _ := m["primary"].(bool)
+			values = append(values, MultiValued{Value: s, Primary: primary})
+		}
+		*dst = primaryValue(values)
+		return "", nil
+	}
+
+	return "invalidValue", fmt.Errorf("%s has invalid value", path)
+}
+
+// parseBool accepts JSON boolean and its string form, which some identity providers send.
+func parseBool(value any) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(strings.ToLower(v))
+	}
+
+	return false, errors.New("boolean expected")
+}
@@ -0,0 +1,148 @@
+package scim
+
+import (
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+)
+
+const (
+	UserSchema                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ListResponseSchema          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	PatchOpSchema               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ErrorSchema                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	ServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	ResourceTypeSchema          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+
+	contentType = "application/scim+json"
+)
+
+type User struct {
+	Schemas  []string      `json:"schemas"`
+	ID       string        `json:"id,omitempty"`
+	UserName string        `json:"userName"`
+	Name     *Name         `json:"name,omitempty"`
+	Emails   []MultiValued `json:"emails,omitempty"`
+	Photos   []MultiValued `json:"photos,omitempty"`
+	Active   *bool         `json:"active,omitempty"`
+	Password string        `json:"password,omitempty"`
+	Meta     *Meta         `json:"meta,omitempty"`
+}
+
+type Name struct {
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	MiddleName string `json:"middleName,omitempty"`
+}
+
+type MultiValued struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
+}
+
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []User   `json:"Resources"`
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// primaryValue returns value marked as primary or first one.
+func primaryValue(values []MultiValued) string {
+	for _, v := range values {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+
+	return ""
+}
+
+func fromDTO(dto userDomain.GetUserDTO, location string) User {
+	active := dto.IsActive
+	u := User{
+		Schemas:  []string{UserSchema},
+		ID:       dto.ID,
+		UserName: dto.Login,
+		Active:   &active,
+		Meta: &Meta{
+			ResourceType: "User",
+			Location:     location,
+		},
+	}
+
+	if dto.FirstName != "" || dto.LastName != "" || dto.MiddleName != "" {
+		u.Name = &Name{
+			GivenName:  dto.FirstName,
+			FamilyName: dto.LastName,
+			MiddleName: dto.MiddleName,
+		}
+	}
+	if dto.Email != "" {
+		u.Emails = []MultiValued{{Value: dto.Email, Type: "work", Primary: true}}
+	}
+	if dto.AvatarURL != "" {
+		u.Photos = []MultiValued{{Value: dto.AvatarURL, Type: "photo", Primary: true}}
+	}
+
+	return u
+}
+
+func (u User) toCreateDTO() userDomain.CreateUserRequestDTO {
+	dto := userDomain.CreateUserRequestDTO{
+		Login:       u.UserName,
+		Email:       primaryValue(u.Emails),
+		AvatarURL:   primaryValue(u.Photos),
+		Password:    u.Password,
+		Provisioned: true,
+	}
+	if u.Name != nil {
+		dto.FirstName = u.Name.GivenName
+		dto.LastName = u.Name.FamilyName
+		dto.MiddleName = u.Name.MiddleName
+	}
+
+	return dto
+}
+
+func (u User) toUpdateDTO() userDomain.UpdateUserRequestDTO {
+	dto := userDomain.UpdateUserRequestDTO{
+		Email:     primaryValue(u.Emails),
+		AvatarURL: primaryValue(u.Photos),
+		IsActive:  u.Active,
+	}
+	if u.Name != nil {
+		dto.FirstName = u.Name.GivenName
+		dto.LastName = u.Name.FamilyName
+		dto.MiddleName = u.Name.MiddleName
+	}
+
+	return dto
+}
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"sync"
+
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	"github.com/srgklmv/comfortel/internal/metrics"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+// errBatchOperationFailed makes transactor roll back failed operation, or whole atomic batch.
+var errBatchOperationFailed = errors.New("batch operation failed")
+
+type passwordHashesKey struct{}
+
+// passwordHashes returns hashes by index of create operation prepared by HashBatchPasswords. They are not
+// keyed by password, so request context doesn't carry plaintext passwords.
+func passwordHashes(ctx context.Context) map[int]string {
+	hashes, _ := ctx.Value(passwordHashesKey{}).(map[int]string)
+	return hashes
+}
+
+// batchOperationKey is context key of index of batch operation being run, see passwordHashes.
+type batchOperationKey struct{}
+
+// HashBatchPasswords hashes passwords of valid create operations of batch and returns ctx carrying hashes,
+// which BatchUsers uses instead of hashing. Hashing is slow, so it should be done before transaction is opened.
+// Passwords are hashed concurrently by as many goroutines as there are CPUs. If ctx carries hashes already,
+// e.g. request is replayed after failed transaction, it is returned as is.
+func (uc usecase) HashBatchPasswords(ctx context.Context, data userDomain.BatchRequestDTO) context.Context {
+	if passwordHashes(ctx) != nil || data.Validate() != nil {
+		return ctx
+	}
+
+	spanCtx, span := tracer.Start(ctx, "usecase.HashBatchPasswords")
+	defer span.End()
+
+	hashes := make(map[int]string)
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for i, op := range data.Operations {
+		if op.Op != userDomain.BatchOpCreate {
+			continue
+		}
+		createData, err := op.CreateData()
+		if err != nil {
+			continue
+		}
+		if validationErr, err := createData.Validate(); err != nil || validationErr != nil {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// Password which failed to hash is left to be hashed again by CreateUser, which reports error.
+			hashed, err := uc.hashPassword(spanCtx, createData.Password)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			hashes[i] = hashed
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return context.WithValue(ctx, passwordHashesKey{}, hashes)
+}
+
+// BatchUsers runs operations in order with single operation usecases. Atomic batch runs in one unit of work
+// and is rolled back on first failure, best-effort batch runs each operation in its own and rolls back
+// only failed ones. Inside request transaction units of work are savepoints. Passwords are hashed
+// before unit of work, unless HashBatchPasswords was called already.
+func (uc usecase) BatchUsers(ctx context.Context, data userDomain.BatchRequestDTO) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.BatchUsers")
+	defer span.End()
+
+	if validationErr := data.Validate(); validationErr != nil {
+		metrics.ValidationFailed(validationErr)
+		return apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.BadRequestErrorText,
+			Message: validationErr.Error(),
+		}, http.StatusBadRequest
+	}
+
+	ctx = uc.HashBatchPasswords(ctx, data)
+
+	results := make([]userDomain.BatchResultDTO, len(data.Operations))
+	failed := -1
+
+	var err error
+	if data.IsAtomic() {
+		err = uc.transactor.WithinTx(ctx, nil, func(ctx context.Context) error {
+			for i, op := range data.Operations {
+				body, status := uc.runBatchOperation(ctx, i, op)
+				results[i] = userDomain.BatchResultDTO{Status: status, Body: body}
+				if status >= http.StatusBadRequest {
+					failed = i
+					return errBatchOperationFailed
+				}
+			}
+			return nil
+		})
+	} else {
+		for i, op := range data.Operations {
+			err = uc.transactor.WithinTx(ctx, nil, func(ctx context.Context) error {
+				body, status := uc.runBatchOperation(ctx, i, op)
+				results[i] = userDomain.BatchResultDTO{Status: status, Body: body}
+				if status >= http.StatusBadRequest {
+					return errBatchOperationFailed
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, errBatchOperationFailed) {
+				break
+			}
+		}
+	}
+	if err != nil && !errors.Is(err, errBatchOperationFailed) {
+		logger.ErrorContext(ctx, "transactor.WithinTx error", slog.String("error", err.Error()))
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	if failed >= 0 {
+		for i := failed + 1; i < len(results); i++ {
+			results[i] = userDomain.BatchResultDTO{
+				Status: http.StatusFailedDependency,
+				Body: apperror.AppError{
+					Code:    apperror.AnyIntYouWantErrorCode,
+					Error:   apperror.BadRequestErrorText,
+					Message: fmt.Sprintf("Not executed because operation %d failed.", failed),
+				},
+			}
+		}
+
+		return userDomain.BatchResponseDTO{Committed: false, Results: results}, results[failed].Status
+	}
+
+	return userDomain.BatchResponseDTO{Committed: true, Results: results}, http.StatusOK
+}
+
+func (uc usecase) runBatchOperation(ctx context.Context, i int, op userDomain.BatchOperationDTO) (any, int) {
+	switch op.Op {
+	case userDomain.BatchOpCreate:
+		ctx = context.WithValue(ctx, batchOperationKey{}, i)
+		data, err := op.CreateData()
+		if err != nil {
+			return apperror.AppError{
+				Code:    apperror.AnyIntYouWantErrorCode,
+				Error:   apperror.BadRequestErrorText,
+				Message: "Request body invalid.",
+			}, http.StatusBadRequest
+		}
+		return uc.CreateUser(ctx, data)
+	case userDomain.BatchOpUpdate:
+		data, err := op.UpdateData()
+		if err != nil {
+			return apperror.AppError{
+				Code:    apperror.AnyIntYouWantErrorCode,
+				Error:   apperror.BadRequestErrorText,
+				Message: "Request body invalid.",
+			}, http.StatusBadRequest
+		}
+		return uc.UpdateUser(ctx, op.ID, data)
+	case userDomain.BatchOpDelete:
+		return uc.DeleteUser(ctx, op.ID)
+	}
+
+	return apperror.AppError{
+		Code:    apperror.AnyIntYouWantErrorCode,
+		Error:   apperror.BadRequestErrorText,
+		Message: "Invalid op.",
+	}, http.StatusBadRequest
+}
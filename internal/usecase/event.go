@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/srgklmv/comfortel/internal/domain/event"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	"github.com/srgklmv/comfortel/internal/metrics"
+)
+
+// publishUserEvent records user event to outbox and webhook deliveries in current transaction,
+// so it is published if and only if mutation is committed.
+func (uc usecase) publishUserEvent(ctx context.Context, eventType string, user userDomain.User) error {
+	e := event.New(eventType, user.ID, userDomain.GetUserDTO{}.FromDomain(user))
+
+	if err := uc.eventRepository.AddToOutbox(ctx, e); err != nil {
+		return fmt.Errorf("eventRepository.AddToOutbox: %w", err)
+	}
+
+	if err := uc.webhookRepository.EnqueueDeliveries(ctx, e); err != nil {
+		return fmt.Errorf("webhookRepository.EnqueueDeliveries: %w", err)
+	}
+	metrics.UserEvent(eventType)
+
+	return nil
+}
@@ -2,30 +2,73 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/srgklmv/comfortel/internal/domain/event"
 	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	webhookDomain "github.com/srgklmv/comfortel/internal/domain/webhook"
+	"go.opentelemetry.io/otel"
 )
 
 type repository interface {
 	userRepository
+	webhookRepository
+	eventRepository
 }
 
 type userRepository interface {
 	GetUserByLogin(ctx context.Context, login string) (userDomain.User, error)
+	GetUserByEmail(ctx context.Context, email string) (userDomain.User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (userDomain.User, error)
 	GetUsers(ctx context.Context) ([]userDomain.User, error)
+	GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]userDomain.User, error)
+	GetUsersByLogins(ctx context.Context, logins []string) ([]userDomain.User, error)
+	FindUsers(ctx context.Context, filter userDomain.Filter) ([]userDomain.User, int, error)
 	CreateUser(ctx context.Context, data userDomain.User, hashedPassword string) (uuid.UUID, error)
 	UpdateUser(ctx context.Context, data userDomain.User) (userDomain.User, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
 }
 
+type webhookRepository interface {
+	CreateSubscription(ctx context.Context, s webhookDomain.Subscription) (uuid.UUID, error)
+	GetSubscriptions(ctx context.Context) ([]webhookDomain.Subscription, error)
+	GetSubscriptionByID(ctx context.Context, id uuid.UUID) (webhookDomain.Subscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
+	EnqueueDeliveries(ctx context.Context, e event.Event) error
+	GetDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string) ([]webhookDomain.Delivery, error)
+	GetDelivery(ctx context.Context, id uuid.UUID) (webhookDomain.Delivery, error)
+	GetDeliveryAttempts(ctx context.Context, deliveryID uuid.UUID) ([]webhookDomain.Attempt, error)
+	RedeliverDelivery(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
+}
+
+type eventRepository interface {
+	AddToOutbox(ctx context.Context, e event.Event) error
+}
+
+// transactor runs fn in transaction, or in savepoint if ctx already carries one.
+type transactor interface {
+	WithinTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error
+}
+
+// tracer starts span of each usecase method, so time spent between repository queries is visible.
+var tracer = otel.Tracer("github.com/srgklmv/comfortel/internal/usecase")
+
 type usecase struct {
-	userRepository userRepository
+	userRepository    userRepository
+	webhookRepository webhookRepository
+	eventRepository   eventRepository
+	transactor        transactor
+	// hash hashes password, it is slow by design.
+	hash func(password string) (string, error)
 }
 
-func New(repository repository) *usecase {
+func New(repository repository, transactor transactor) *usecase {
 	return &usecase{
-		userRepository: repository,
+		userRepository:    repository,
+		webhookRepository: repository,
+		eventRepository:   repository,
+		transactor:        transactor,
+		hash:              userDomain.HashPassword,
 	}
 }
@@ -4,25 +4,32 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/srgklmv/comfortel/internal/domain/apperror"
 	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	"github.com/srgklmv/comfortel/internal/metrics"
 	"github.com/srgklmv/comfortel/pkg/logger"
 )
 
 func (uc usecase) CreateUser(ctx context.Context, data userDomain.CreateUserRequestDTO) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.CreateUser")
+	defer span.End()
+
 	validationErr, err := data.Validate()
 	if err != nil {
-		logger.Error("data.Validate error", slog.String("error", err.Error()))
+		logger.ErrorContext(ctx, "data.Validate error", slog.String("error", err.Error()))
 		return apperror.AppError{
 			Code:  apperror.AnyIntYouWantErrorCode,
 			Error: apperror.InternalErrorText,
 		}, http.StatusInternalServerError
 	}
 	if validationErr != nil {
+		metrics.ValidationFailed(validationErr)
 		return apperror.AppError{
 			Code:    apperror.AnyIntYouWantErrorCode,
 			Error:   apperror.BadRequestErrorText,
@@ -32,7 +39,7 @@ func (uc usecase) CreateUser(ctx context.Context, data userDomain.CreateUserRequ
 
 	user, err := uc.userRepository.GetUserByLogin(ctx, data.Login)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		logger.Error("userRepository.GetUserByLogin error", slog.String("error", err.Error()))
+		logger.ErrorContext(ctx, "userRepository.GetUserByLogin error", slog.String("error", err.Error()))
 		return apperror.AppError{
 			Code:  apperror.AnyIntYouWantErrorCode,
 			Error: apperror.InternalErrorText,
@@ -42,12 +49,29 @@ func (uc usecase) CreateUser(ctx context.Context, data userDomain.CreateUserRequ
 		return apperror.AppError{
 			Code:  apperror.AnyIntYouWantErrorCode,
 			Error: apperror.LoginTakenErrorText,
-		}, http.StatusBadRequest
+		}, http.StatusConflict
+	}
+
+	if data.Email != "" {
+		user, err = uc.userRepository.GetUserByEmail(ctx, data.Email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.ErrorContext(ctx, "userRepository.GetUserByEmail error", slog.String("error", err.Error()))
+			return apperror.AppError{
+				Code:  apperror.AnyIntYouWantErrorCode,
+				Error: apperror.InternalErrorText,
+			}, http.StatusInternalServerError
+		}
+		if user.ID != uuid.Nil {
+			return apperror.AppError{
+				Code:  apperror.AnyIntYouWantErrorCode,
+				Error: apperror.EmailTakenErrorText,
+			}, http.StatusConflict
+		}
 	}
 
-	hashedPassword, err := userDomain.HashPassword(data.Password)
+	hashedPassword, err := uc.hashPassword(ctx, data.Password)
 	if err != nil {
-		logger.Error("user.HashPassword error", slog.String("error", err.Error()))
+		logger.ErrorContext(ctx, "hashPassword error", slog.String("error", err.Error()))
 		return apperror.AppError{
 			Code:  apperror.AnyIntYouWantErrorCode,
 			Error: apperror.InternalErrorText,
@@ -55,8 +79,28 @@ func (uc usecase) CreateUser(ctx context.Context, data userDomain.CreateUserRequ
 	}
 
 	id, err := uc.userRepository.CreateUser(ctx, data.ToDomain(), hashedPassword)
+	if appErr, ok := conflictError(err); ok {
+		return appErr, http.StatusConflict
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "userRepository.CreateUser error", slog.String("error", err.Error()))
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	created, err := uc.userRepository.GetUserByID(ctx, id)
 	if err != nil {
-		logger.Error("userRepository.CreateUser error", slog.String("error", err.Error()))
+		logger.ErrorContext(ctx, "userRepository.GetUserByID error", slog.String("error", err.Error()))
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	if err = uc.publishUserEvent(ctx, userDomain.EventCreated, created); err != nil {
+		logger.ErrorContext(ctx, "publishUserEvent error", slog.String("error", err.Error()))
 		return apperror.AppError{
 			Code:  apperror.AnyIntYouWantErrorCode,
 			Error: apperror.InternalErrorText,
@@ -67,6 +111,9 @@ func (uc usecase) CreateUser(ctx context.Context, data userDomain.CreateUserRequ
 }
 
 func (uc usecase) UpdateUser(ctx context.Context, id string, data userDomain.UpdateUserRequestDTO) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.UpdateUser")
+	defer span.End()
+
 	uid, err := uuid.Parse(id)
 	if err != nil {
 		return apperror.AppError{
@@ -78,13 +125,14 @@ func (uc usecase) UpdateUser(ctx context.Context, id string, data userDomain.Upd
 
 	validationErr, err := data.Validate()
 	if err != nil {
-		logger.Error("data.Validate error", slog.String("error", err.Error()))
+		logger.ErrorContext(ctx, "data.Validate error", slog.String("error", err.Error()))
 		return apperror.AppError{
 			Code:  apperror.AnyIntYouWantErrorCode,
 			Error: apperror.InternalErrorText,
 		}, http.StatusInternalServerError
 	}
 	if validationErr != nil {
+		metrics.ValidationFailed(validationErr)
 		return apperror.AppError{
 			Code:    apperror.AnyIntYouWantErrorCode,
 			Error:   apperror.BadRequestErrorText,
@@ -94,7 +142,7 @@ func (uc usecase) UpdateUser(ctx context.Context, id string, data userDomain.Upd
 
 	user, err := uc.userRepository.GetUserByID(ctx, uid)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		logger.Error("userRepository.GetUserByID error", slog.String("error", err.Error()))
+		logger.ErrorContext(ctx, "userRepository.GetUserByID error", slog.String("error", err.Error()))
 		return apperror.AppError{
 			Code:  apperror.AnyIntYouWantErrorCode,
 			Error: apperror.InternalErrorText,
@@ -108,21 +156,59 @@ func (uc usecase) UpdateUser(ctx context.Context, id string, data userDomain.Upd
 		}, http.StatusNotFound
 	}
 
+	if data.Email != "" {
+		owner, err := uc.userRepository.GetUserByEmail(ctx, data.Email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.ErrorContext(ctx, "userRepository.GetUserByEmail error", slog.String("error", err.Error()))
+			return apperror.AppError{
+				Code:  apperror.AnyIntYouWantErrorCode,
+				Error: apperror.InternalErrorText,
+			}, http.StatusInternalServerError
+		}
+		if owner.ID != uuid.Nil && owner.ID != user.ID {
+			return apperror.AppError{
+				Code:  apperror.AnyIntYouWantErrorCode,
+				Error: apperror.EmailTakenErrorText,
+			}, http.StatusConflict
+		}
+	}
+
+	wasActive := user.IsActive
 	user.Update(data)
 
 	user, err = uc.userRepository.UpdateUser(ctx, user)
+	if appErr, ok := conflictError(err); ok {
+		return appErr, http.StatusConflict
+	}
 	if err != nil {
-		logger.Error("userRepository.UpdateUser error", slog.String("error", err.Error()))
+		logger.ErrorContext(ctx, "userRepository.UpdateUser error", slog.String("error", err.Error()))
 		return apperror.AppError{
 			Code:  apperror.AnyIntYouWantErrorCode,
 			Error: apperror.InternalErrorText,
 		}, http.StatusInternalServerError
 	}
 
+	events := []string{userDomain.EventUpdated}
+	if !wasActive && user.IsActive {
+		events = append(events, userDomain.EventActivated)
+	}
+	for _, eventType := range events {
+		if err = uc.publishUserEvent(ctx, eventType, user); err != nil {
+			logger.ErrorContext(ctx, "publishUserEvent error", slog.String("error", err.Error()))
+			return apperror.AppError{
+				Code:  apperror.AnyIntYouWantErrorCode,
+				Error: apperror.InternalErrorText,
+			}, http.StatusInternalServerError
+		}
+	}
+
 	return userDomain.GetUserDTO{}.FromDomain(user), http.StatusOK
 }
 
 func (uc usecase) DeleteUser(ctx context.Context, id string) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.DeleteUser")
+	defer span.End()
+
 	uid, err := uuid.Parse(id)
 	if err != nil {
 		return apperror.AppError{
@@ -134,7 +220,7 @@ func (uc usecase) DeleteUser(ctx context.Context, id string) (any, int) {
 
 	user, err := uc.userRepository.GetUserByID(ctx, uid)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		logger.Error("userRepository.GetUserByID error", slog.String("error", err.Error()))
+		logger.ErrorContext(ctx, "userRepository.GetUserByID error", slog.String("error", err.Error()))
 		return apperror.AppError{
 			Code:  apperror.AnyIntYouWantErrorCode,
 			Error: apperror.InternalErrorText,
@@ -150,7 +236,15 @@ func (uc usecase) DeleteUser(ctx context.Context, id string) (any, int) {
 
 	uid, err = uc.userRepository.DeleteUser(ctx, user.ID)
 	if err != nil {
-		logger.Error("userRepository.DeleteUser error", slog.String("error", err.Error()))
+		logger.ErrorContext(ctx, "userRepository.DeleteUser error", slog.String("error", err.Error()))
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	if err = uc.publishUserEvent(ctx, userDomain.EventDeleted, user); err != nil {
+		logger.ErrorContext(ctx, "publishUserEvent error", slog.String("error", err.Error()))
 		return apperror.AppError{
 			Code:  apperror.AnyIntYouWantErrorCode,
 			Error: apperror.InternalErrorText,
@@ -161,9 +255,12 @@ func (uc usecase) DeleteUser(ctx context.Context, id string) (any, int) {
 }
 
 func (uc usecase) GetUsers(ctx context.Context) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.GetUsers")
+	defer span.End()
+
 	users, err := uc.userRepository.GetUsers(ctx)
 	if err != nil {
-		logger.Error("userRepository.GetUserByID error", slog.String("error", err.Error()))
+		logger.ErrorContext(ctx, "userRepository.GetUserByID error", slog.String("error", err.Error()))
 		return apperror.AppError{
 			Code:  apperror.AnyIntYouWantErrorCode,
 			Error: apperror.InternalErrorText,
@@ -179,6 +276,9 @@ func (uc usecase) GetUsers(ctx context.Context) (any, int) {
 }
 
 func (uc usecase) GetUserByID(ctx context.Context, id string) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.GetUserByID")
+	defer span.End()
+
 	uid, err := uuid.Parse(id)
 	if err != nil {
 		return apperror.AppError{
@@ -190,7 +290,49 @@ func (uc usecase) GetUserByID(ctx context.Context, id string) (any, int) {
 
 	user, err := uc.userRepository.GetUserByID(ctx, uid)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		logger.Error("userRepository.GetUserByID error", slog.String("error", err.Error()))
+		logger.ErrorContext(ctx, "userRepository.GetUserByID error", slog.String("error", err.Error()))
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+	if user.ID == uuid.Nil {
+		return apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.BadRequestErrorText,
+			Message: "User not found.",
+		}, http.StatusNotFound
+	}
+
+	return userDomain.GetUserDTO{}.FromDomain(user), http.StatusOK
+}
+
+// conflictError maps uniqueness errors from repository to response.
+// Checks before insert are not enough under concurrent requests, so constraint is the last word.
+func conflictError(err error) (apperror.AppError, bool) {
+	switch {
+	case errors.Is(err, userDomain.ErrLoginTaken):
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.LoginTakenErrorText,
+		}, true
+	case errors.Is(err, userDomain.ErrEmailTaken):
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.EmailTakenErrorText,
+		}, true
+	}
+
+	return apperror.AppError{}, false
+}
+
+func (uc usecase) GetUserByLogin(ctx context.Context, login string) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.GetUserByLogin")
+	defer span.End()
+
+	user, err := uc.userRepository.GetUserByLogin(ctx, login)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.ErrorContext(ctx, "userRepository.GetUserByLogin error", slog.String("error", err.Error()))
 		return apperror.AppError{
 			Code:  apperror.AnyIntYouWantErrorCode,
 			Error: apperror.InternalErrorText,
@@ -206,3 +348,113 @@ func (uc usecase) GetUserByID(ctx context.Context, id string) (any, int) {
 
 	return userDomain.GetUserDTO{}.FromDomain(user), http.StatusOK
 }
+
+// GetUsersByIDs returns found users in no particular order. Missing ids are skipped.
+func (uc usecase) GetUsersByIDs(ctx context.Context, ids []string) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.GetUsersByIDs")
+	defer span.End()
+
+	uids := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		uid, err := uuid.Parse(id)
+		if err != nil {
+			return apperror.AppError{
+				Code:    apperror.AnyIntYouWantErrorCode,
+				Error:   apperror.BadRequestErrorText,
+				Message: "Invalid user id.",
+			}, http.StatusBadRequest
+		}
+		uids = append(uids, uid)
+	}
+
+	users, err := uc.userRepository.GetUsersByIDs(ctx, uids)
+	if err != nil {
+		logger.ErrorContext(ctx, "userRepository.GetUsersByIDs error", slog.String("error", err.Error()))
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	dtos := make([]userDomain.GetUserDTO, 0, len(users))
+	for _, user := range users {
+		dtos = append(dtos, userDomain.GetUserDTO{}.FromDomain(user))
+	}
+
+	return dtos, http.StatusOK
+}
+
+// GetUsersByLogins returns found users in no particular order. Missing logins are skipped.
+func (uc usecase) GetUsersByLogins(ctx context.Context, logins []string) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.GetUsersByLogins")
+	defer span.End()
+
+	users, err := uc.userRepository.GetUsersByLogins(ctx, logins)
+	if err != nil {
+		logger.ErrorContext(ctx, "userRepository.GetUsersByLogins error", slog.String("error", err.Error()))
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	dtos := make([]userDomain.GetUserDTO, 0, len(users))
+	for _, user := range users {
+		dtos = append(dtos, userDomain.GetUserDTO{}.FromDomain(user))
+	}
+
+	return dtos, http.StatusOK
+}
+
+func (uc usecase) SearchUsers(ctx context.Context, data userDomain.SearchUsersRequestDTO) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.SearchUsers")
+	defer span.End()
+
+	if validationErr := data.Validate(); validationErr != nil {
+		metrics.ValidationFailed(validationErr)
+		return apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.BadRequestErrorText,
+			Message: validationErr.Error(),
+		}, http.StatusBadRequest
+	}
+
+	users, total, err := uc.userRepository.FindUsers(ctx, data.ToDomain())
+	if err != nil {
+		logger.ErrorContext(ctx, "userRepository.FindUsers error", slog.String("error", err.Error()))
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	page := userDomain.UsersPageDTO{
+		Users: make([]userDomain.GetUserDTO, 0, len(users)),
+		Total: total,
+	}
+	for _, user := range users {
+		page.Users = append(page.Users, userDomain.GetUserDTO{}.FromDomain(user))
+	}
+
+	return page, http.StatusOK
+}
+
+// hashPassword returns hash of password of batch operation prepared by HashBatchPasswords or hashes it.
+func (uc usecase) hashPassword(ctx context.Context, password string) (string, error) {
+	if i, ok := ctx.Value(batchOperationKey{}).(int); ok {
+		if hashed, ok := passwordHashes(ctx)[i]; ok {
+			return hashed, nil
+		}
+	}
+
+	start := time.Now()
+	_, span := tracer.Start(ctx, "user.HashPassword")
+	hashed, err := uc.hash(password)
+	span.End()
+	metrics.ObservePasswordHash(time.Since(start))
+	if err != nil {
+		return "", fmt.Errorf("user.HashPassword: %w", err)
+	}
+
+	return hashed, nil
+}
@@ -0,0 +1,232 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/srgklmv/comfortel/internal/domain/event"
+	userDomain "github.com/srgklmv/comfortel/internal/domain/user"
+	"github.com/srgklmv/comfortel/internal/repository/memory"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+const testPassword = "password1"
+
+// errFailed rolls back unit of work of failed call, as Transaction middleware does.
+var errFailed = errors.New("failed")
+
+// recordingRepository remembers types of events added to outbox.
+type recordingRepository struct {
+	repository
+	events []string
+}
+
+func (r *recordingRepository) AddToOutbox(ctx context.Context, e event.Event) error {
+	if err := r.repository.AddToOutbox(ctx, e); err != nil {
+		return err
+	}
+	r.events = append(r.events, e.Type)
+
+	return nil
+}
+
+type fixture struct {
+	uc   *usecase
+	repo *recordingRepository
+	ctx  context.Context
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	logger.SetDefault(slog.New(slog.DiscardHandler))
+
+	mem := memory.New()
+	repo := &recordingRepository{repository: mem}
+
+	// Hashing takes about a second, so passwords are "hashed" instantly.
+	uc := New(repo, mem)
+	uc.hash = func(password string) (string, error) { return "hashed:" + password, nil }
+
+	return &fixture{uc: uc, repo: repo, ctx: context.Background()}
+}
+
+// call runs fn in unit of work, which is rolled back if fn fails.
+func (f *fixture) call(fn func(ctx context.Context) (any, int)) (any, int) {
+	var (
+		body   any
+		status int
+	)
+	_ = f.uc.transactor.WithinTx(f.ctx, nil, func(ctx context.Context) error {
+		body, status = fn(ctx)
+		if status >= http.StatusBadRequest {
+			return errFailed
+		}
+		return nil
+	})
+
+	return body, status
+}
+
+func (f *fixture) create(t *testing.T, login string) uuid.UUID {
+	t.Helper()
+
+	body, status := f.call(func(ctx context.Context) (any, int) {
+		return f.uc.CreateUser(ctx, userDomain.CreateUserRequestDTO{Login: login, Password: testPassword})
+	})
+	if status != http.StatusOK {
+		t.Fatalf("CreateUser: status %d, body %+v", status, body)
+	}
+
+	return uuid.MustParse(body.(userDomain.CreateUserResponseDTO).Created)
+}
+
+func createOp(login string) userDomain.BatchOperationDTO {
+	return userDomain.BatchOperationDTO{
+		Op:   userDomain.BatchOpCreate,
+		Data: []byte(`{"login":"` + login + `","password":"` + testPassword + `"}`),
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	f := newFixture(t)
+
+	id := f.create(t, "alice")
+
+	body, status := f.uc.GetUserByID(f.ctx, id.String())
+	if status != http.StatusOK {
+		t.Fatalf("GetUserByID: status %d, body %+v", status, body)
+	}
+	if got := body.(userDomain.GetUserDTO); got.Login != "alice" || !got.IsActive {
+		t.Errorf("GetUserByID: got %+v", got)
+	}
+	if want := []string{userDomain.EventCreated}; !slices.Equal(f.repo.events, want) {
+		t.Errorf("events %v, want %v", f.repo.events, want)
+	}
+}
+
+func TestCreateUserRejectsInvalidAndTaken(t *testing.T) {
+	f := newFixture(t)
+	f.create(t, "alice")
+
+	tests := []struct {
+		name   string
+		data   userDomain.CreateUserRequestDTO
+		status int
+	}{
+		{name: "invalid login", data: userDomain.CreateUserRequestDTO{Login: "a", Password: testPassword}, status: http.StatusBadRequest},
+		{name: "invalid password", data: userDomain.CreateUserRequestDTO{Login: "bobby", Password: "short"}, status: http.StatusBadRequest},
+		{name: "taken login", data: userDomain.CreateUserRequestDTO{Login: "ALICE", Password: testPassword}, status: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, status := f.call(func(ctx context.Context) (any, int) {
+				return f.uc.CreateUser(ctx, tt.data)
+			})
+			if status != tt.status {
+				t.Errorf("status %d, want %d, body %+v", status, tt.status, body)
+			}
+		})
+	}
+}
+
+func TestUpdateUserActivates(t *testing.T) {
+	f := newFixture(t)
+	id := f.create(t, "alice")
+
+	for _, active := range []bool{false, true} {
+		body, status := f.call(func(ctx context.Context) (any, int) {
+			return f.uc.UpdateUser(ctx, id.String(), userDomain.UpdateUserRequestDTO{IsActive: &active})
+		})
+		if status != http.StatusOK {
+			t.Fatalf("UpdateUser: status %d, body %+v", status, body)
+		}
+		if got := body.(userDomain.GetUserDTO); got.IsActive != active {
+			t.Fatalf("UpdateUser: active %t, want %t", got.IsActive, active)
+		}
+	}
+
+	want := []string{userDomain.EventCreated, userDomain.EventUpdated, userDomain.EventUpdated, userDomain.EventActivated}
+	if !slices.Equal(f.repo.events, want) {
+		t.Errorf("events %v, want %v", f.repo.events, want)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	f := newFixture(t)
+	id := f.create(t, "alice")
+
+	if _, status := f.call(func(ctx context.Context) (any, int) { return f.uc.DeleteUser(ctx, id.String()) }); status != http.StatusOK {
+		t.Fatalf("DeleteUser: status %d", status)
+	}
+	if _, status := f.uc.GetUserByID(f.ctx, id.String()); status != http.StatusNotFound {
+		t.Errorf("GetUserByID of deleted user: status %d, want %d", status, http.StatusNotFound)
+	}
+	if _, status := f.call(func(ctx context.Context) (any, int) { return f.uc.DeleteUser(ctx, id.String()) }); status != http.StatusNotFound {
+		t.Errorf("DeleteUser again: status %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestBatchUsersAtomicRollsBack(t *testing.T) {
+	f := newFixture(t)
+
+	body, status := f.call(func(ctx context.Context) (any, int) {
+		return f.uc.BatchUsers(ctx, userDomain.BatchRequestDTO{
+			Operations: []userDomain.BatchOperationDTO{createOp("alice"), createOp("alice"), createOp("carol")},
+		})
+	})
+	if status != http.StatusConflict {
+		t.Fatalf("status %d, want %d, body %+v", status, http.StatusConflict, body)
+	}
+
+	got := body.(userDomain.BatchResponseDTO)
+	statuses := []int{got.Results[0].Status, got.Results[1].Status, got.Results[2].Status}
+	if want := []int{http.StatusOK, http.StatusConflict, http.StatusFailedDependency}; got.Committed || !slices.Equal(statuses, want) {
+		t.Errorf("committed %t, statuses %v, want false and %v", got.Committed, statuses, want)
+	}
+	if _, status = f.uc.GetUserByLogin(f.ctx, "alice"); status != http.StatusNotFound {
+		t.Errorf("user of rolled back batch: status %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestBatchUsersBestEffortKeepsSucceeded(t *testing.T) {
+	f := newFixture(t)
+
+	body, status := f.call(func(ctx context.Context) (any, int) {
+		return f.uc.BatchUsers(ctx, userDomain.BatchRequestDTO{
+			Mode:       userDomain.BatchModeBestEffort,
+			Operations: []userDomain.BatchOperationDTO{createOp("alice"), createOp("alice"), createOp("carol")},
+		})
+	})
+	if status != http.StatusOK {
+		t.Fatalf("status %d, want %d, body %+v", status, http.StatusOK, body)
+	}
+
+	got := body.(userDomain.BatchResponseDTO)
+	statuses := []int{got.Results[0].Status, got.Results[1].Status, got.Results[2].Status}
+	if want := []int{http.StatusOK, http.StatusConflict, http.StatusOK}; !got.Committed || !slices.Equal(statuses, want) {
+		t.Errorf("committed %t, statuses %v, want true and %v", got.Committed, statuses, want)
+	}
+	for _, login := range []string{"alice", "carol"} {
+		if _, status = f.uc.GetUserByLogin(f.ctx, login); status != http.StatusOK {
+			t.Errorf("GetUserByLogin %s: status %d, want %d", login, status, http.StatusOK)
+		}
+	}
+}
+
+func TestHashBatchPasswordsKeysHashesByOperation(t *testing.T) {
+	f := newFixture(t)
+	ops := []userDomain.BatchOperationDTO{createOp("alice"), {Op: userDomain.BatchOpDelete, ID: uuid.NewString()}, createOp("carol")}
+
+	ctx := f.uc.HashBatchPasswords(f.ctx, userDomain.BatchRequestDTO{Operations: ops})
+
+	want := map[int]string{0: "hashed:" + testPassword, 2: "hashed:" + testPassword}
+	if got := passwordHashes(ctx); !maps.Equal(got, want) {
+		t.Errorf("hashes %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,251 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/srgklmv/comfortel/internal/domain/apperror"
+	webhookDomain "github.com/srgklmv/comfortel/internal/domain/webhook"
+	"github.com/srgklmv/comfortel/internal/metrics"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+func (uc usecase) CreateSubscription(ctx context.Context, data webhookDomain.CreateSubscriptionRequestDTO) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.CreateSubscription")
+	defer span.End()
+
+	if validationErr := data.Validate(); validationErr != nil {
+		metrics.ValidationFailed(validationErr)
+		return apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.BadRequestErrorText,
+			Message: validationErr.Error(),
+		}, http.StatusBadRequest
+	}
+
+	subscription := data.ToDomain()
+	if subscription.Secret == "" {
+		secret, err := webhookDomain.GenerateSecret()
+		if err != nil {
+			logger.ErrorContext(ctx, "webhook.GenerateSecret error", slog.String("error", err.Error()))
+			return apperror.AppError{
+				Code:  apperror.AnyIntYouWantErrorCode,
+				Error: apperror.InternalErrorText,
+			}, http.StatusInternalServerError
+		}
+		subscription.Secret = secret
+	}
+
+	id, err := uc.webhookRepository.CreateSubscription(ctx, subscription)
+	if err != nil {
+		logger.ErrorContext(ctx, "webhookRepository.CreateSubscription error", slog.String("error", err.Error()))
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	return webhookDomain.CreateSubscriptionResponseDTO{ID: id.String(), Secret: subscription.Secret}, http.StatusOK
+}
+
+func (uc usecase) GetSubscriptions(ctx context.Context) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.GetSubscriptions")
+	defer span.End()
+
+	subscriptions, err := uc.webhookRepository.GetSubscriptions(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "webhookRepository.GetSubscriptions error", slog.String("error", err.Error()))
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	dtos := make([]webhookDomain.GetSubscriptionDTO, 0, len(subscriptions))
+	for _, s := range subscriptions {
+		dtos = append(dtos, webhookDomain.GetSubscriptionDTO{}.FromDomain(s))
+	}
+
+	return dtos, http.StatusOK
+}
+
+func (uc usecase) GetSubscription(ctx context.Context, id string) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.GetSubscription")
+	defer span.End()
+
+	subscription, response, status := uc.getSubscription(ctx, id)
+	if response != nil {
+		return response, status
+	}
+
+	return webhookDomain.GetSubscriptionDTO{}.FromDomain(subscription), http.StatusOK
+}
+
+func (uc usecase) DeleteSubscription(ctx context.Context, id string) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.DeleteSubscription")
+	defer span.End()
+
+	subscription, response, status := uc.getSubscription(ctx, id)
+	if response != nil {
+		return response, status
+	}
+
+	uid, err := uc.webhookRepository.DeleteSubscription(ctx, subscription.ID)
+	if err != nil {
+		logger.ErrorContext(ctx, "webhookRepository.DeleteSubscription error", slog.String("error", err.Error()))
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	return webhookDomain.DeleteSubscriptionResponseDTO{Deleted: uid.String()}, http.StatusOK
+}
+
+// GetDeliveries returns latest deliveries of subscription, optionally filtered by status.
+// Dead deliveries make up dead-letter list.
+func (uc usecase) GetDeliveries(ctx context.Context, subscriptionID string, status string) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.GetDeliveries")
+	defer span.End()
+
+	if status != "" && status != webhookDomain.StatusPending && status != webhookDomain.StatusDelivered && status != webhookDomain.StatusDead {
+		return apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.BadRequestErrorText,
+			Message: "Invalid delivery status.",
+		}, http.StatusBadRequest
+	}
+
+	subscription, response, code := uc.getSubscription(ctx, subscriptionID)
+	if response != nil {
+		return response, code
+	}
+
+	deliveries, err := uc.webhookRepository.GetDeliveries(ctx, subscription.ID, status)
+	if err != nil {
+		logger.ErrorContext(ctx, "webhookRepository.GetDeliveries error", slog.String("error", err.Error()))
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	dtos := make([]webhookDomain.GetDeliveryDTO, 0, len(deliveries))
+	for _, d := range deliveries {
+		dtos = append(dtos, webhookDomain.GetDeliveryDTO{}.FromDomain(d, nil))
+	}
+
+	return dtos, http.StatusOK
+}
+
+// GetDelivery returns delivery with log of its attempts.
+func (uc usecase) GetDelivery(ctx context.Context, subscriptionID, deliveryID string) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.GetDelivery")
+	defer span.End()
+
+	delivery, response, status := uc.getDelivery(ctx, subscriptionID, deliveryID)
+	if response != nil {
+		return response, status
+	}
+
+	attempts, err := uc.webhookRepository.GetDeliveryAttempts(ctx, delivery.ID)
+	if err != nil {
+		logger.ErrorContext(ctx, "webhookRepository.GetDeliveryAttempts error", slog.String("error", err.Error()))
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	return webhookDomain.GetDeliveryDTO{}.FromDomain(delivery, attempts), http.StatusOK
+}
+
+func (uc usecase) RedeliverDelivery(ctx context.Context, subscriptionID, deliveryID string) (any, int) {
+	ctx, span := tracer.Start(ctx, "usecase.RedeliverDelivery")
+	defer span.End()
+
+	delivery, response, status := uc.getDelivery(ctx, subscriptionID, deliveryID)
+	if response != nil {
+		return response, status
+	}
+
+	id, err := uc.webhookRepository.RedeliverDelivery(ctx, delivery.ID)
+	if err != nil {
+		logger.ErrorContext(ctx, "webhookRepository.RedeliverDelivery error", slog.String("error", err.Error()))
+		return apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	return webhookDomain.RedeliverResponseDTO{Redelivered: id.String()}, http.StatusOK
+}
+
+// getSubscription returns subscription or error response with status if it can not be found.
+func (uc usecase) getSubscription(ctx context.Context, id string) (webhookDomain.Subscription, any, int) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return webhookDomain.Subscription{}, apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.BadRequestErrorText,
+			Message: "Invalid subscription id.",
+		}, http.StatusBadRequest
+	}
+
+	subscription, err := uc.webhookRepository.GetSubscriptionByID(ctx, uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhookDomain.Subscription{}, apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.BadRequestErrorText,
+			Message: "Subscription not found.",
+		}, http.StatusNotFound
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "webhookRepository.GetSubscriptionByID error", slog.String("error", err.Error()))
+		return webhookDomain.Subscription{}, apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	return subscription, nil, http.StatusOK
+}
+
+// getDelivery returns delivery of subscription or error response with status if it can not be found.
+func (uc usecase) getDelivery(ctx context.Context, subscriptionID, deliveryID string) (webhookDomain.Delivery, any, int) {
+	subscription, response, status := uc.getSubscription(ctx, subscriptionID)
+	if response != nil {
+		return webhookDomain.Delivery{}, response, status
+	}
+
+	uid, err := uuid.Parse(deliveryID)
+	if err != nil {
+		return webhookDomain.Delivery{}, apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.BadRequestErrorText,
+			Message: "Invalid delivery id.",
+		}, http.StatusBadRequest
+	}
+
+	delivery, err := uc.webhookRepository.GetDelivery(ctx, uid)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && delivery.SubscriptionID != subscription.ID) {
+		return webhookDomain.Delivery{}, apperror.AppError{
+			Code:    apperror.AnyIntYouWantErrorCode,
+			Error:   apperror.BadRequestErrorText,
+			Message: "Delivery not found.",
+		}, http.StatusNotFound
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "webhookRepository.GetDelivery error", slog.String("error", err.Error()))
+		return webhookDomain.Delivery{}, apperror.AppError{
+			Code:  apperror.AnyIntYouWantErrorCode,
+			Error: apperror.InternalErrorText,
+		}, http.StatusInternalServerError
+	}
+
+	return delivery, nil, http.StatusOK
+}
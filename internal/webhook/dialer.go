@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// blockedNetworks are not reported by netip.Addr methods but must not be reached either: "this network"
+// and shared address space of carrier-grade NAT, which some clouds use for metadata service.
+var blockedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// ErrAddressNotAllowed is returned when webhook URL resolves to address of internal network.
+var ErrAddressNotAllowed = errors.New("address is not allowed")
+
+// newDialer returns dialer which refuses to connect to loopback, link-local, private and other non-public
+// addresses unless they are in allowed. Address is checked after resolution, right before connect,
+// so hostname resolving to internal address or changing its address after subscription is caught too.
+func newDialer(allowed []netip.Prefix) *net.Dialer {
+	return &net.Dialer{
+		Timeout: requestTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("netip.ParseAddrPort: %w", err)
+			}
+			if !allowedAddr(addrPort.Addr(), allowed) {
+				return fmt.Errorf("%w: %s", ErrAddressNotAllowed, addrPort.Addr())
+			}
+
+			return nil
+		},
+	}
+}
+
+func allowedAddr(addr netip.Addr, allowed []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range blockedNetworks {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
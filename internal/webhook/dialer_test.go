@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestWorkerRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	_, err := NewWorker(nil, nil).client.Get(server.URL)
+	if !errors.Is(err, ErrAddressNotAllowed) {
+		t.Fatalf("request to %s: got error %v, want %v", server.URL, err, ErrAddressNotAllowed)
+	}
+
+	allowed := []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	resp, err := NewWorker(nil, nil, WithAllowedNetworks(allowed)).client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request to allowed %s: %v", server.URL, err)
+	}
+	resp.Body.Close()
+}
+
+func TestAllowedAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00:ec2::254", false},
+		{"100.100.100.200", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := allowedAddr(netip.MustParseAddr(tt.addr), nil); got != tt.want {
+			t.Errorf("allowedAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
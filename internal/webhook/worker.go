@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+
+	webhookDomain "github.com/srgklmv/comfortel/internal/domain/webhook"
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+const (
+	pollInterval   = 2 * time.Second
+	batchSize      = 20
+	requestTimeout = 10 * time.Second
+	// lease must be longer than request timeout, otherwise delivery may be claimed twice.
+	lease = time.Minute
+
+	maxAttempts = 8
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+)
+
+type transactor interface {
+	WithinTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error
+}
+
+type deliveryRepository interface {
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]webhookDomain.Job, error)
+	RecordAttempt(ctx context.Context, attempt webhookDomain.Attempt, status string, retryIn time.Duration) error
+}
+
+// worker sends pending deliveries and reschedules failed ones with exponential backoff.
+// Deliveries which run out of attempts are marked dead.
+type worker struct {
+	transactor transactor
+	repo       deliveryRepository
+	client     *http.Client
+}
+
+type Option func(*options)
+
+type options struct {
+	allowedNetworks []netip.Prefix
+}
+
+// WithAllowedNetworks lets webhooks be sent to addresses of networks, which are refused otherwise
+// if they are loopback, link-local or private.
+func WithAllowedNetworks(networks []netip.Prefix) Option {
+	return func(o *options) {
+		o.allowedNetworks = networks
+	}
+}
+
+// NewWorker returns worker which sends webhooks to public addresses only, see WithAllowedNetworks.
+// Proxy from environment is not used, because it would connect to any address on behalf of worker.
+func NewWorker(transactor transactor, repo deliveryRepository, opts ...Option) *worker {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = newDialer(o.allowedNetworks).DialContext
+
+	return &worker{
+		transactor: transactor,
+		repo:       repo,
+		client:     &http.Client{Timeout: requestTimeout, Transport: transport},
+	}
+}
+
+// Run processes deliveries until ctx is cancelled.
+func (w *worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Full batch means there may be more due deliveries, so don't wait for next tick.
+		if w.processBatch(ctx) == batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processBatch sends one batch of deliveries and returns its size.
+func (w *worker) processBatch(ctx context.Context) int {
+	if ctx.Err() != nil {
+		return 0
+	}
+
+	var jobs []webhookDomain.Job
+	err := w.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		jobs, err = w.repo.ClaimDeliveries(ctx, batchSize, lease)
+		return err
+	})
+	if err != nil {
+		logger.Error("webhook deliveries claim error", slog.String("error", err.Error()))
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.deliver(ctx, job)
+		}()
+	}
+	wg.Wait()
+
+	return len(jobs)
+}
+
+func (w *worker) deliver(ctx context.Context, job webhookDomain.Job) {
+	start := time.Now()
+	statusCode, sendErr := w.send(ctx, job)
+	attempt := webhookDomain.Attempt{
+		DeliveryID: job.Delivery.ID,
+		StatusCode: statusCode,
+		Duration:   time.Since(start),
+	}
+
+	status := webhookDomain.StatusDelivered
+	var retryIn time.Duration
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+		status = webhookDomain.StatusPending
+		retryIn = backoff(job.Delivery.Attempts)
+		if job.Delivery.Attempts+1 >= maxAttempts {
+			status = webhookDomain.StatusDead
+		}
+	}
+
+	// Outcome is saved even if worker is stopping, otherwise delivery is sent again after lease.
+	err := w.inTx(context.WithoutCancel(ctx), func(ctx context.Context) error {
+		return w.repo.RecordAttempt(ctx, attempt, status, retryIn)
+	})
+	if err != nil {
+		logger.Error("webhook attempt record error", slog.String("delivery_id", job.Delivery.ID.String()), slog.String("error", err.Error()))
+	}
+	if status == webhookDomain.StatusDead {
+		logger.Error("webhook delivery is dead", slog.String("delivery_id", job.Delivery.ID.String()), slog.String("error", attempt.Error))
+	}
+}
+
+// send posts signed payload and returns response status code. Non-2xx responses are errors.
+func (w *worker) send(ctx context.Context, job webhookDomain.Job) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(job.Delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("http.NewRequestWithContext: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Comfortel-Webhook/1.0")
+	req.Header.Set(webhookDomain.HeaderID, job.Delivery.ID.String())
+	req.Header.Set(webhookDomain.HeaderEvent, job.Delivery.EventType)
+	req.Header.Set(webhookDomain.HeaderTimestamp, fmt.Sprint(timestamp))
+	req.Header.Set(webhookDomain.HeaderSignature, webhookDomain.Sign(job.Secret, timestamp, job.Delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (w *worker) inTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return w.transactor.WithinTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted}, fn)
+}
+
+// backoff returns delay before next attempt after given number of failed ones, with 20% jitter.
+func backoff(attempts int) time.Duration {
+	delay := maxBackoff
+	if attempts < 20 {
+		delay = min(baseBackoff<<attempts, maxBackoff)
+	}
+
+	jitter := time.Duration(rand.Int64N(int64(delay) / 5))
+	return delay - delay/10 + jitter
+}
@@ -0,0 +1,8 @@
+// Package migrations embeds schema migrations, so binary doesn't depend on files next to it.
+// Migrations of every database driver are in directory named after it.
+package migrations
+
+import "embed"
+
+//go:embed postgres/*.sql sqlite/*.sql
+var FS embed.FS
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS user_email_lower_key;
+DROP INDEX IF EXISTS user_login_lower_key;
+
+ALTER TABLE "user" ADD CONSTRAINT user_login_key UNIQUE (login);
+ALTER TABLE "user" ADD CONSTRAINT user_email_key UNIQUE (email);
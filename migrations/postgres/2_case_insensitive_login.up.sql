@@ -0,0 +1,24 @@
+-- Logins and emails which differ only in case can not be normalized, so migration stops before any change
+-- and they have to be renamed or merged by hand first.
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(value, ', ') INTO duplicates FROM (
+        SELECT 'login ' || lower(login) AS value FROM "user" GROUP BY lower(login) HAVING count(*) > 1
+        UNION ALL
+        SELECT 'email ' || lower(email) FROM "user" WHERE email IS NOT NULL GROUP BY lower(email) HAVING count(*) > 1
+    ) d;
+
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'users differ only in case of %, rename or merge them before migrating', duplicates;
+    END IF;
+END $$;
+
+ALTER TABLE "user" DROP CONSTRAINT IF EXISTS user_login_key;
+ALTER TABLE "user" DROP CONSTRAINT IF EXISTS user_email_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_login_lower_key ON "user" (lower(login));
+CREATE UNIQUE INDEX IF NOT EXISTS user_email_lower_key ON "user" (lower(email));
+
+UPDATE "user" SET login = lower(login), email = lower(email);
@@ -0,0 +1,7 @@
+-- tat is theoretical arrival time of next request in unix seconds, bucket is full when it is in the past.
+CREATE TABLE IF NOT EXISTS rate_limit (
+    key VARCHAR(255) PRIMARY KEY,
+    tat DOUBLE PRECISION NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rate_limit_tat_idx ON rate_limit (tat);
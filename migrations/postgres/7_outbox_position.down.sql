@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS outbox_unsequenced_idx;
+DROP INDEX IF EXISTS outbox_unpublished_idx;
+CREATE INDEX IF NOT EXISTS outbox_unpublished_idx ON outbox (seq) WHERE published_at IS NULL;
+
+ALTER TABLE outbox DROP COLUMN IF EXISTS claimed_until;
+ALTER TABLE outbox DROP COLUMN IF EXISTS position;
@@ -0,0 +1,17 @@
+-- Ids are random UUIDs in text form and timestamps are UTC text with milliseconds, which drivers
+-- scan as time and which compare in order.
+CREATE TABLE IF NOT EXISTS "user" (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    login VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    first_name VARCHAR(255),
+    last_name VARCHAR(255),
+    middle_name VARCHAR(255),
+    sex VARCHAR(255),
+    age INT,
+    password VARCHAR(255) NOT NULL,
+    avatar_url VARCHAR(255),
+    is_active BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
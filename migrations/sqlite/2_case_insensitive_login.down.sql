@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS user_email_lower_key;
+DROP INDEX IF EXISTS user_login_lower_key;
@@ -0,0 +1,18 @@
+-- Logins and emails which differ only in case can not be normalized, so migration stops before any change
+-- and they have to be renamed or merged by hand first. SQLite raises errors only in triggers, hence the table.
+CREATE TEMP TABLE case_duplicates (duplicates INT);
+CREATE TEMP TRIGGER case_duplicates_check BEFORE INSERT ON case_duplicates WHEN NEW.duplicates > 0
+BEGIN
+    SELECT RAISE(ABORT, 'users differ only in case of login or email, rename or merge them before migrating');
+END;
+INSERT INTO case_duplicates SELECT count(*) FROM (
+    SELECT 1 FROM "user" GROUP BY lower(login) HAVING count(*) > 1
+    UNION ALL
+    SELECT 1 FROM "user" WHERE email IS NOT NULL GROUP BY lower(email) HAVING count(*) > 1
+);
+DROP TABLE case_duplicates;
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_login_lower_key ON "user" (lower(login));
+CREATE UNIQUE INDEX IF NOT EXISTS user_email_lower_key ON "user" (lower(email));
+
+UPDATE "user" SET login = lower(login), email = lower(email);
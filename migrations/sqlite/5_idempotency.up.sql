@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS idempotency_key (
+    caller VARCHAR(64) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INT,
+    content_type VARCHAR(255),
+    body BLOB,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (caller, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_key_expires_at_idx ON idempotency_key (expires_at);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS outbox_unsequenced_idx;
+DROP INDEX IF EXISTS outbox_unpublished_idx;
+DROP INDEX IF EXISTS outbox_position_key;
+CREATE INDEX IF NOT EXISTS outbox_unpublished_idx ON outbox (seq) WHERE published_at IS NULL;
+
+ALTER TABLE outbox DROP COLUMN claimed_until;
+ALTER TABLE outbox DROP COLUMN position;
@@ -0,0 +1,10 @@
+ALTER TABLE outbox ADD COLUMN position INTEGER;
+ALTER TABLE outbox ADD COLUMN claimed_until TIMESTAMP;
+
+-- Recorded events are committed already, so their order of commit is taken as is.
+UPDATE outbox SET position = seq;
+
+CREATE UNIQUE INDEX IF NOT EXISTS outbox_position_key ON outbox (position);
+DROP INDEX IF EXISTS outbox_unpublished_idx;
+CREATE INDEX IF NOT EXISTS outbox_unpublished_idx ON outbox (position) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_unsequenced_idx ON outbox (seq) WHERE position IS NULL;
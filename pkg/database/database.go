@@ -5,13 +5,23 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	_ "github.com/lib/pq"
 	"github.com/srgklmv/comfortel/pkg/logger"
+	_ "modernc.org/sqlite"
+)
+
+// Supported drivers.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
 )
 
 func New(host, port, database, user, password string) (*sql.DB, error) {
@@ -37,20 +47,69 @@ func New(host, port, database, user, password string) (*sql.DB, error) {
 	return db, nil
 }
 
+// NewReplica opens postgres database by DSN without checking connection, because replica may be down
+// at startup and come up later.
+func NewReplica(dsn string) (*sql.DB, error) {
+	db, err := sql.Open(DriverPostgres, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open: %w", err)
+	}
+
+	return db, nil
+}
+
+// NewSQLite opens SQLite database file, creating it if needed. Transactions take write lock at start
+// and wait for each other instead of failing, so concurrent requests are serialized.
+func NewSQLite(path string) (*sql.DB, error) {
+	data := fmt.Sprintf(
+		"file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate",
+		path,
+	)
+
+	db, err := sql.Open(DriverSQLite, data)
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open: %w", err)
+	}
+
+	err = db.QueryRowContext(context.Background(), "select 1;").Err()
+	if err != nil {
+		return nil, fmt.Errorf("db.QueryRowContext: %w", err)
+	}
+
+	return db, nil
+}
+
 func Shutdown(db *sql.DB) error {
 	return db.Close()
 }
 
-func Migrate(db *sql.DB, path string, version int) error {
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
+// Migrate migrates database of driver to version with migrations from fsys. Migrations of every driver
+// are in directory named after it.
+func Migrate(db *sql.DB, driverName string, fsys fs.FS, version int) error {
+	var (
+		driver database.Driver
+		err    error
+	)
+	switch driverName {
+	case DriverPostgres:
+		driver, err = postgres.WithInstance(db, &postgres.Config{})
+	case DriverSQLite:
+		driver, err = sqlite.WithInstance(db, &sqlite.Config{})
+	default:
+		err = fmt.Errorf("unknown driver %q", driverName)
+	}
 	if err != nil {
 		logger.Error("migrations driver set up error", slog.String("error", err.Error()))
 		return err
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		path,
-		"postgres", driver)
+	source, err := iofs.New(fsys, driverName)
+	if err != nil {
+		logger.Error("migrations source set up error", slog.String("error", err.Error()))
+		return err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, driverName, driver)
 	if err != nil {
 		logger.Error("migrate instance creation error", slog.String("error", err.Error()))
 		return err
@@ -64,3 +123,37 @@ func Migrate(db *sql.DB, path string, version int) error {
 
 	return nil
 }
+
+// LatestVersion returns version of last migration of driver in fsys, which is schema version app works with.
+func LatestVersion(fsys fs.FS, driverName string) (int, error) {
+	source, err := iofs.New(fsys, driverName)
+	if err != nil {
+		return 0, fmt.Errorf("iofs.New: %w", err)
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	if err != nil {
+		return 0, fmt.Errorf("source.First: %w", err)
+	}
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return int(version), nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("source.Next: %w", err)
+		}
+		version = next
+	}
+}
+
+// MigrationVersion returns version of schema applied by Migrate and whether last migration failed halfway.
+func MigrationVersion(ctx context.Context, db *sql.DB) (version int, dirty bool, err error) {
+	err = db.QueryRowContext(ctx, `select version, dirty from schema_migrations limit 1;`).Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, fmt.Errorf("queryRowContext: %w", err)
+	}
+
+	return version, dirty, nil
+}
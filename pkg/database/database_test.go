@@ -0,0 +1,33 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLatestVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sqlite/1_init.up.sql":      {},
+		"sqlite/1_init.down.sql":    {},
+		"sqlite/2_users.up.sql":     {},
+		"sqlite/2_users.down.sql":   {},
+		"sqlite/10_events.up.sql":   {},
+		"postgres/1_init.up.sql":    {},
+		"postgres/3_users.up.sql":   {},
+		"postgres/3_users.down.sql": {},
+	}
+
+	for driver, want := range map[string]int{DriverSQLite: 10, DriverPostgres: 3} {
+		got, err := LatestVersion(fsys, driver)
+		if err != nil {
+			t.Fatalf("LatestVersion(%s): %v", driver, err)
+		}
+		if got != want {
+			t.Errorf("LatestVersion(%s) = %d, want %d", driver, got, want)
+		}
+	}
+
+	if _, err := LatestVersion(fsys, "mysql"); err == nil {
+		t.Error("LatestVersion of driver without migrations succeeded")
+	}
+}
@@ -0,0 +1,213 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Formats.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Outputs.
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputFile   = "file"
+	OutputSyslog = "syslog"
+)
+
+type options struct {
+	level            slog.Leveler
+	format           string
+	output           string
+	writer           io.Writer
+	file             string
+	maxSizeMB        int
+	maxBackups       int
+	syslogAddress    string
+	sampleFirst      int
+	sampleThereafter int
+	sampleInterval   time.Duration
+	redact           []string
+}
+
+type Option func(*options)
+
+// WithLevel sets minimal level of records. Default is info.
+func WithLevel(level slog.Leveler) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithFormat sets format of records: "json" (default) or "text".
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithOutput sets where records go: "stdout" (default), "stderr", "file" or "syslog",
+// see WithFile and WithSyslog.
+func WithOutput(output string) Option {
+	return func(o *options) {
+		o.output = output
+	}
+}
+
+// WithWriter makes logger write to w, overriding output.
+func WithWriter(w io.Writer) Option {
+	return func(o *options) {
+		o.writer = w
+	}
+}
+
+// WithFile sets log file of "file" output. File is rotated when it grows over maxSizeMB, which is not limited
+// if zero, and maxBackups previous files are kept as path.1, path.2 and so on.
+func WithFile(path string, maxSizeMB, maxBackups int) Option {
+	return func(o *options) {
+		o.file = path
+		o.maxSizeMB = maxSizeMB
+		o.maxBackups = maxBackups
+	}
+}
+
+// WithSyslog sets server of "syslog" output, e.g. "udp://localhost:514". Local syslog is used if address is empty.
+func WithSyslog(address string) Option {
+	return func(o *options) {
+		o.syslogAddress = address
+	}
+}
+
+// WithSampling limits repetitive records: of records with same level and message within interval first are
+// logged, then every thereafter-th one. Errors are never dropped. Zero first disables sampling.
+func WithSampling(first, thereafter int, interval time.Duration) Option {
+	return func(o *options) {
+		o.sampleFirst = first
+		o.sampleThereafter = thereafter
+		o.sampleInterval = interval
+	}
+}
+
+// WithRedaction replaces values of attributes with REDACTED if their key or name of their group contains
+// one of keys, case-insensitively, so "token" redacts "auth_token" too. Fields of structs and maps logged
+// with slog.Any are redacted the same way. In messages and string values bearer and basic credentials
+// and pairs like "password=..." are redacted.
+func WithRedaction(keys ...string) Option {
+	return func(o *options) {
+		o.redact = keys
+	}
+}
+
+// New returns logger built by opts and closer which releases its output, e.g. log file.
+// Records logged with context carry ids of its trace and span.
+func New(opts ...Option) (*slog.Logger, io.Closer, error) {
+	o := options{
+		level:  slog.LevelInfo,
+		format: FormatJSON,
+		output: OutputStdout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var (
+		w      = o.writer
+		closer io.Closer
+		sink   *syslogSink
+	)
+	if w == nil {
+		switch o.output {
+		case OutputStdout:
+			w = os.Stdout
+		case OutputStderr:
+			w = os.Stderr
+		case OutputFile:
+			if o.file == "" {
+				return nil, nil, fmt.Errorf("file is not set for file output")
+			}
+			file, err := newRotatingFile(o.file, int64(o.maxSizeMB)<<20, o.maxBackups)
+			if err != nil {
+				return nil, nil, fmt.Errorf("newRotatingFile: %w", err)
+			}
+			w, closer = file, file
+		case OutputSyslog:
+			var err error
+			sink, err = newSyslogSink(o.syslogAddress)
+			if err != nil {
+				return nil, nil, fmt.Errorf("newSyslogSink: %w", err)
+			}
+			w, closer = sink, sink
+		default:
+			return nil, nil, fmt.Errorf("unknown log output %q", o.output)
+		}
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: o.level}
+	if len(o.redact) > 0 {
+		handlerOpts.ReplaceAttr = redactor(o.redact)
+	}
+
+	var handler slog.Handler
+	switch o.format {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	case FormatText:
+		handler = slog.NewTextHandler(w, handlerOpts)
+	default:
+		if closer != nil {
+			_ = closer.Close()
+		}
+		return nil, nil, fmt.Errorf("unknown log format %q", o.format)
+	}
+
+	if sink != nil {
+		handler = severityHandler{Handler: handler, sink: sink}
+	}
+	handler = traceHandler{handler}
+	if o.sampleFirst > 0 {
+		handler = samplingHandler{Handler: handler, sampler: newSampler(o.sampleFirst, o.sampleThereafter, o.sampleInterval)}
+	}
+
+	if closer == nil {
+		closer = nopCloser{}
+	}
+
+	return slog.New(handler), closer, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// traceHandler adds trace_id and span_id of span in context of record.
+type traceHandler struct {
+	slog.Handler
+}
+
+func (h traceHandler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceHandler) WithGroup(name string) slog.Handler {
+	return traceHandler{h.Handler.WithGroup(name)}
+}
@@ -1,31 +1,164 @@
+// Package logger writes structured logs with log/slog. Package functions log with default logger, which is set
+// by Configure or SetDefault, while New builds standalone logger to be injected where it is needed.
 package logger
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"runtime"
+	"sync"
+	"sync/atomic"
 )
 
-var logger *slog.Logger
+var (
+	logger atomic.Pointer[slog.Logger]
+	level  = new(slog.LevelVar)
+
+	// closer releases output of default logger, e.g. log file, when it is replaced.
+	closerMu sync.Mutex
+	closer   io.Closer
+)
 
 func Init() {
-	logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	level.Set(slog.LevelDebug)
+	logger.Store(slog.New(traceHandler{slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})}))
+}
+
+// Configure replaces default logger with one built by New with opts, which logs records of levelName and above.
+// Previous logger keeps working if opts are invalid.
+func Configure(levelName string, opts ...Option) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(levelName)); err != nil {
+		return fmt.Errorf("l.UnmarshalText: %w", err)
+	}
+
+	newLogger, newCloser, err := New(append(opts, WithLevel(level))...)
+	if err != nil {
+		return fmt.Errorf("New: %w", err)
+	}
+
+	level.Set(l)
+	logger.Store(newLogger)
+
+	closerMu.Lock()
+	defer closerMu.Unlock()
+	var closeErr error
+	if closer != nil {
+		closeErr = closer.Close()
+	}
+	closer = newCloser
+	if closeErr != nil {
+		return fmt.Errorf("closer.Close: %w", closeErr)
+	}
+
+	return nil
+}
+
+// Close releases output of default logger, it should be called last on exit.
+func Close() error {
+	closerMu.Lock()
+	defer closerMu.Unlock()
+
+	if closer == nil {
+		return nil
+	}
+	err := closer.Close()
+	closer = nil
+
+	return err
+}
+
+// SetDefault replaces default logger with l, e.g. built by New.
+func SetDefault(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// Default returns default logger to be injected into code which takes *slog.Logger.
+func Default() *slog.Logger {
+	return logger.Load()
+}
+
+// SetLevel changes level of default logger at runtime: "debug", "info", "warn" or "error".
+func SetLevel(levelName string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(levelName)); err != nil {
+		return fmt.Errorf("l.UnmarshalText: %w", err)
+	}
+	level.Set(l)
+
+	return nil
+}
+
+// Level returns level of default logger.
+func Level() slog.Level {
+	return level.Level()
 }
 
 func Info(msg string, args ...any) {
 	args = append(args, slog.String("caller", caller()))
-	logger.Info(msg, args...)
+	logger.Load().Info(msg, args...)
+}
+
+func Warn(msg string, args ...any) {
+	args = append(args, slog.String("caller", caller()))
+	logger.Load().Warn(msg, args...)
 }
 
 func Error(msg string, args ...any) {
 	args = append(args, slog.String("caller", caller()))
-	logger.Error(msg, args...)
+	logger.Load().Error(msg, args...)
 }
 
 func Debug(msg string, args ...any) {
 	args = append(args, slog.String("caller", caller()))
-	logger.Debug(msg, args...)
+	logger.Load().Debug(msg, args...)
+}
+
+// InfoContext, WarnContext, ErrorContext and DebugContext log with logger of ctx, see NewContext, and add ids
+// of trace and span of ctx to record.
+
+func InfoContext(ctx context.Context, msg string, args ...any) {
+	args = append(args, slog.String("caller", caller()))
+	FromContext(ctx).InfoContext(ctx, msg, args...)
+}
+
+func WarnContext(ctx context.Context, msg string, args ...any) {
+	args = append(args, slog.String("caller", caller()))
+	FromContext(ctx).WarnContext(ctx, msg, args...)
+}
+
+func ErrorContext(ctx context.Context, msg string, args ...any) {
+	args = append(args, slog.String("caller", caller()))
+	FromContext(ctx).ErrorContext(ctx, msg, args...)
+}
+
+func DebugContext(ctx context.Context, msg string, args ...any) {
+	args = append(args, slog.String("caller", caller()))
+	FromContext(ctx).DebugContext(ctx, msg, args...)
+}
+
+type ctxKey struct{}
+
+// With returns logger which adds args to each record, e.g. to be passed to NewContext.
+func With(args ...any) *slog.Logger {
+	return logger.Load().With(args...)
+}
+
+// NewContext returns ctx carrying l, so records logged with ctx have attributes of l, like request id.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns logger of ctx, or default logger if ctx carries none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+
+	return logger.Load()
 }
 
 func caller() string {
@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// credentialPattern matches credentials of Authorization header, e.g. "Bearer eyJhbGciOi...".
+var credentialPattern = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[a-z0-9\-._~+/]+=*`)
+
+type redaction struct {
+	keys []string
+	// pairPattern matches pairs like "password=secret" or `"token": "abc"` whose key contains one of keys.
+	pairPattern *regexp.Regexp
+}
+
+// redactor returns slog.HandlerOptions.ReplaceAttr which redacts attributes with keys or groups containing
+// one of keys and credentials in message and values. Handler resolves LogValuers and calls it for members
+// of groups, so only values of kind any are walked here.
+func redactor(keys []string) func(groups []string, a slog.Attr) slog.Attr {
+	r := redaction{}
+	quoted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		r.keys = append(r.keys, strings.ToLower(key))
+		quoted = append(quoted, regexp.QuoteMeta(key))
+	}
+	if len(quoted) > 0 {
+		r.pairPattern = regexp.MustCompile(`(?i)([\w-]*(?:` + strings.Join(quoted, "|") + `)[\w-]*"?\s*[=:]\s*)("[^"]*"|[^\s&,;"]+)`)
+	}
+
+	return func(groups []string, a slog.Attr) slog.Attr {
+		for _, group := range groups {
+			if r.matches(group) {
+				return slog.String(a.Key, redacted)
+			}
+		}
+		if r.matches(a.Key) {
+			return slog.String(a.Key, redacted)
+		}
+
+		switch a.Value.Kind() {
+		case slog.KindString:
+			return slog.String(a.Key, r.text(a.Value.String()))
+		case slog.KindAny:
+			return slog.Any(a.Key, r.any(a.Value.Any()))
+		}
+		return a
+	}
+}
+
+func (r redaction) matches(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range r.keys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// text redacts credentials in s.
+func (r redaction) text(s string) string {
+	s = credentialPattern.ReplaceAllString(s, "$1 "+redacted)
+	if r.pairPattern != nil {
+		s = r.pairPattern.ReplaceAllString(s, "${1}"+redacted)
+	}
+
+	return s
+}
+
+// any returns v with fields redacted. Structs and maps are converted to their JSON form to be walked,
+// values which can't be are logged as text.
+func (r redaction) any(v any) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case error:
+		return r.text(v.Error())
+	case fmt.Stringer:
+		return r.text(v.String())
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return r.text(fmt.Sprintf("%+v", v))
+	}
+	var tree any
+	if err = json.Unmarshal(raw, &tree); err != nil {
+		return r.text(string(raw))
+	}
+
+	return r.walk(tree)
+}
+
+func (r redaction) walk(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if r.matches(key) {
+				v[key] = redacted
+				continue
+			}
+			v[key] = r.walk(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = r.walk(value)
+		}
+	case string:
+		return r.text(v)
+	}
+
+	return v
+}
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type credentials struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+type request struct {
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+	Auth    credentials       `json:"auth"`
+}
+
+// tokenValuer logs itself as group with token.
+type tokenValuer struct{}
+
+func (tokenValuer) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("kind", "api"), slog.String("token", "s3cr3t-valuer"))
+}
+
+func TestRedaction(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatText} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			log, _, err := New(WithWriter(&buf), WithFormat(format), WithRedaction("password", "token", "authorization"))
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			log.Info("login with password=s3cr3t-message by Bearer s3cr3t-bearer",
+				slog.String("api_token", "s3cr3t-key"),
+				slog.String("header", "Bearer s3cr3t-header"),
+				slog.Group("auth", slog.String("password", "s3cr3t-group")),
+				slog.Group("token", slog.String("value", "s3cr3t-group-name")),
+				slog.Any("request", request{
+					Path:    "/api/user",
+					Headers: map[string]string{"Authorization": "Basic s3cr3t-map", "Accept": "application/json"},
+					Auth:    credentials{User: "alice", Password: "s3cr3t-struct"},
+				}),
+				slog.Any("valuer", tokenValuer{}),
+				slog.Any("error", errors.New("dial: url=postgres://u@h?password=s3cr3t-error")),
+			)
+			log.WithGroup("password").Info("grouped", slog.String("value", "s3cr3t-with-group"))
+
+			out := buf.String()
+			if strings.Contains(out, "s3cr3t") {
+				t.Errorf("secret is logged:\n%s", out)
+			}
+			for _, kept := range []string{"/api/user", "alice", "application/json", "api", "login with"} {
+				if !strings.Contains(out, kept) {
+					t.Errorf("%q is lost:\n%s", kept, out)
+				}
+			}
+		})
+	}
+}
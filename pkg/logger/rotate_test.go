@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile: %v", err)
+	}
+
+	return string(data)
+}
+
+func TestRotatingFileKeepsBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := newRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+
+	// Each line fills file, so every next one rotates it.
+	for _, line := range []string{"first...\n", "second..\n", "third...\n", "fourth..\n"} {
+		if _, err = f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	want := map[string]string{path: "fourth..\n", path + ".1": "third...\n", path + ".2": "second..\n"}
+	for p, content := range want {
+		if got := readFile(t, p); got != content {
+			t.Errorf("%s: got %q, want %q", filepath.Base(p), got, content)
+		}
+	}
+	if _, err = os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("backup over maxBackups is kept: %v", err)
+	}
+}
+
+func TestRotatingFileTruncatesWithoutBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("old.....\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+
+	// Size of existing file counts, so first write rotates it.
+	f, err := newRotatingFile(path, 10, 0)
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	if _, err = f.Write([]byte("new.....\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if got := readFile(t, path); got != "new.....\n" {
+		t.Errorf("got %q, want only new line", got)
+	}
+	if matches, _ := filepath.Glob(path + ".*"); len(matches) != 0 {
+		t.Errorf("backups are kept: %v", matches)
+	}
+}
+
+func TestRotatingFileUnlimited(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := newRotatingFile(path, 0, 2)
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+
+	line := strings.Repeat("x", 100) + "\n"
+	for range 3 {
+		if _, err = f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	if got := readFile(t, path); got != strings.Repeat(line, 3) {
+		t.Errorf("file is rotated without max size: %d bytes", len(got))
+	}
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// sampler counts records by level and message within interval, counts are reset when it passes.
+type sampler struct {
+	first      int
+	thereafter int
+	interval   time.Duration
+
+	mu     sync.Mutex
+	reset  time.Time
+	counts map[sampleKey]int
+}
+
+type sampleKey struct {
+	level   slog.Level
+	message string
+}
+
+func newSampler(first, thereafter int, interval time.Duration) *sampler {
+	return &sampler{
+		first:      first,
+		thereafter: thereafter,
+		interval:   interval,
+		counts:     make(map[sampleKey]int),
+	}
+}
+
+// allow reports whether record is logged.
+func (s *sampler) allow(r slog.Record) bool {
+	if r.Level >= slog.LevelError {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := time.Now(); now.After(s.reset) {
+		clear(s.counts)
+		s.reset = now.Add(s.interval)
+	}
+
+	key := sampleKey{level: r.Level, message: r.Message}
+	s.counts[key]++
+	n := s.counts[key]
+
+	if n <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.first)%s.thereafter == 0
+}
+
+// samplingHandler drops records which sampler does not allow.
+type samplingHandler struct {
+	slog.Handler
+	sampler *sampler
+}
+
+func (h samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.sampler.allow(r) {
+		return nil
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return samplingHandler{Handler: h.Handler.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h samplingHandler) WithGroup(name string) slog.Handler {
+	return samplingHandler{Handler: h.Handler.WithGroup(name), sampler: h.sampler}
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	log, _, err := New(WithWriter(&buf), WithFormat(FormatText), WithSampling(2, 3, time.Hour))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	for range 10 {
+		log.Info("repeated")
+		log.Error("failed")
+	}
+	log.Info("other")
+
+	out := buf.String()
+	// First 2, then every 3rd of the rest: 5th and 8th.
+	if n := strings.Count(out, "msg=repeated"); n != 4 {
+		t.Errorf("logged %d repeated records, want 4", n)
+	}
+	if n := strings.Count(out, "msg=failed"); n != 10 {
+		t.Errorf("logged %d errors, want all 10", n)
+	}
+	if n := strings.Count(out, "msg=other"); n != 1 {
+		t.Errorf("logged %d other records, want 1", n)
+	}
+}
+
+func TestSamplerResetsAfterInterval(t *testing.T) {
+	s := newSampler(1, 0, 20*time.Millisecond)
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "repeated", 0)
+
+	if !s.allow(r) {
+		t.Fatal("first record is dropped")
+	}
+	if s.allow(r) {
+		t.Fatal("second record is logged, thereafter is 0")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !s.allow(r) {
+		t.Error("first record of next interval is dropped")
+	}
+}
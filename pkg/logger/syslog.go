@@ -0,0 +1,91 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"log/syslog"
+	"net/url"
+	"sync"
+)
+
+// syslogSink writes records to syslog with severity of level of record being written, which is set
+// by severityHandler.
+type syslogSink struct {
+	mu     sync.Mutex
+	writer *syslog.Writer
+	level  slog.Level
+}
+
+// newSyslogSink connects to syslog at address like "udp://localhost:514" or "unix:///dev/log",
+// or to local syslog if it is empty.
+func newSyslogSink(address string) (*syslogSink, error) {
+	var network, raddr string
+	if address != "" {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, fmt.Errorf("url.Parse: %w", err)
+		}
+		network, raddr = u.Scheme, u.Host
+		if network == "unix" || network == "unixgram" {
+			raddr = u.Path
+		}
+	}
+
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_DAEMON, "comfortel")
+	if err != nil {
+		return nil, fmt.Errorf("syslog.Dial: %w", err)
+	}
+
+	return &syslogSink{writer: w}, nil
+}
+
+// Write must be called with mu held by severityHandler.
+func (s *syslogSink) Write(p []byte) (int, error) {
+	msg := string(p)
+
+	var err error
+	switch {
+	case s.level >= slog.LevelError:
+		err = s.writer.Err(msg)
+	case s.level >= slog.LevelWarn:
+		err = s.writer.Warning(msg)
+	case s.level >= slog.LevelInfo:
+		err = s.writer.Info(msg)
+	default:
+		err = s.writer.Debug(msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (s *syslogSink) Close() error {
+	return s.writer.Close()
+}
+
+// severityHandler passes level of record to sink, so it is written with matching syslog severity.
+type severityHandler struct {
+	slog.Handler
+	sink *syslogSink
+}
+
+func (h severityHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.sink.mu.Lock()
+	defer h.sink.mu.Unlock()
+
+	h.sink.level = r.Level
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h severityHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return severityHandler{Handler: h.Handler.WithAttrs(attrs), sink: h.sink}
+}
+
+func (h severityHandler) WithGroup(name string) slog.Handler {
+	return severityHandler{Handler: h.Handler.WithGroup(name), sink: h.sink}
+}
@@ -0,0 +1,33 @@
+//go:build windows || plan9
+
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime"
+)
+
+type syslogSink struct{}
+
+func newSyslogSink(string) (*syslogSink, error) {
+	return nil, errors.New("syslog is not supported on " + runtime.GOOS)
+}
+
+func (s *syslogSink) Write(p []byte) (int, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func (s *syslogSink) Close() error {
+	return nil
+}
+
+type severityHandler struct {
+	slog.Handler
+	sink *syslogSink
+}
+
+func (h severityHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.Handler.Handle(ctx, r)
+}
@@ -0,0 +1,342 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// PathItem maps lowercase http method to operation.
+type PathItem map[string]*OperationObject
+
+type OperationObject struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema  *Schema `json:"schema"`
+	Example any     `json:"example,omitempty"`
+}
+
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []any              `json:"enum,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Examples    []any              `json:"examples,omitempty"`
+}
+
+// Operation describes single route. Path is in gin format, e.g. "/api/user/:id".
+// Request and Body fields of responses are zero values of DTO types, schemas are built from them by reflection.
+type Operation struct {
+	Method         string
+	Path           string
+	ID             string
+	Summary        string
+	Tags           []string
+	Params         []Parameter
+	Request        any
+	RequestExample any
+	Responses      []ResponseSpec
+}
+
+// ResponseSpec describes response. ContentType defaults to "application/json".
+type ResponseSpec struct {
+	Status      int
+	Description string
+	ContentType string
+	Body        any
+	Example     any
+}
+
+type Spec struct {
+	doc Document
+	// missing are components and properties asked which are not in document, they are reported by Verify.
+	missing []string
+
+	once sync.Once
+	raw  []byte
+	err  error
+}
+
+func New(title, version, description string) *Spec {
+	return &Spec{
+		doc: Document{
+			OpenAPI: Version,
+			Info: Info{
+				Title:       title,
+				Version:     version,
+				Description: description,
+			},
+			Paths:      map[string]PathItem{},
+			Components: Components{Schemas: map[string]*Schema{}},
+		},
+	}
+}
+
+// Add registers operation in document. Path parameters are generated from gin path
+// unless they are passed in Params.
+func (s *Spec) Add(op Operation) {
+	path, pathParams := convertPath(op.Path)
+
+	o := &OperationObject{
+		OperationID: op.ID,
+		Summary:     op.Summary,
+		Tags:        op.Tags,
+		Responses:   map[string]Response{},
+	}
+
+	for _, name := range pathParams {
+		param := Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}}
+		for _, p := range op.Params {
+			if p.In == "path" && p.Name == name {
+				param = p
+			}
+		}
+		o.Parameters = append(o.Parameters, param)
+	}
+	for _, p := range op.Params {
+		if p.In != "path" {
+			o.Parameters = append(o.Parameters, p)
+		}
+	}
+
+	if op.Request != nil {
+		o.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				"application/json": {Schema: s.schemaOf(reflect.TypeOf(op.Request)), Example: op.RequestExample},
+			},
+		}
+	}
+
+	for _, r := range op.Responses {
+		response := Response{Description: r.Description}
+		if response.Description == "" {
+			response.Description = http.StatusText(r.Status)
+		}
+		if r.Body != nil {
+			contentType := r.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			response.Content = map[string]MediaType{
+				contentType: {Schema: s.schemaOf(reflect.TypeOf(r.Body)), Example: r.Example},
+			}
+		}
+		o.Responses[fmt.Sprint(r.Status)] = response
+	}
+
+	item, ok := s.doc.Paths[path]
+	if !ok {
+		item = PathItem{}
+		s.doc.Paths[path] = item
+	}
+	item[strings.ToLower(op.Method)] = o
+}
+
+// Component returns schema of named component to adjust it, e.g. add constraints and examples.
+// If there is no such component, detached schema is returned and Verify reports it.
+func (s *Spec) Component(name string) *Schema {
+	if c, ok := s.doc.Components.Schemas[name]; ok {
+		return c
+	}
+
+	s.missing = append(s.missing, name)
+	return &Schema{}
+}
+
+// Property returns schema of component property to adjust it. If component or property is not found,
+// e.g. field of DTO is renamed, detached schema is returned and Verify reports it.
+func (s *Spec) Property(component, property string) *Schema {
+	if c, ok := s.doc.Components.Schemas[component]; ok {
+		if p, ok := c.Properties[property]; ok {
+			return p
+		}
+	}
+
+	s.missing = append(s.missing, component+"."+property)
+	return &Schema{}
+}
+
+// Verify checks that every route has its operation in document and vice versa, and that every component
+// and property adjusted with Component and Property exists.
+func (s *Spec) Verify(routes gin.RoutesInfo) error {
+	registered := map[string]bool{}
+	for _, r := range routes {
+		path, _ := convertPath(r.Path)
+		registered[r.Method+" "+path] = true
+	}
+
+	var errs []error
+	documented := map[string]bool{}
+	for path, item := range s.doc.Paths {
+		for method := range item {
+			key := strings.ToUpper(method) + " " + path
+			documented[key] = true
+			if !registered[key] {
+				errs = append(errs, fmt.Errorf("operation %s has no route", key))
+			}
+		}
+	}
+	for key := range registered {
+		if !documented[key] {
+			errs = append(errs, fmt.Errorf("route %s is not documented", key))
+		}
+	}
+	for _, name := range s.missing {
+		errs = append(errs, fmt.Errorf("schema %s is not in document", name))
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+
+	return errors.Join(errs...)
+}
+
+// JSON returns marshalled document. Document must not be changed after first call.
+func (s *Spec) JSON() ([]byte, error) {
+	s.once.Do(func() {
+		s.raw, s.err = json.Marshal(s.doc)
+	})
+
+	return s.raw, s.err
+}
+
+func (s *Spec) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, err := s.JSON()
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		c.Data(http.StatusOK, "application/json", raw)
+	}
+}
+
+func (s *Spec) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := s.doc.Components.Schemas[name]; !ok {
+			s.doc.Components.Schemas[name] = s.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	return &Schema{}
+}
+
+func (s *Spec) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		schema.Properties[name] = s.schemaOf(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+// convertPath converts gin path to OpenAPI one and returns names of path parameters.
+func convertPath(path string) (string, []string) {
+	var params []string
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			params = append(params, part[1:])
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+
+	return strings.Join(parts, "/"), params
+}
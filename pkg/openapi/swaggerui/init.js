@@ -0,0 +1,7 @@
+window.onload = () => {
+    const root = document.getElementById("swagger-ui");
+    window.ui = SwaggerUIBundle({
+        url: root.dataset.specUrl,
+        dom_id: "#swagger-ui",
+    });
+};
@@ -0,0 +1,57 @@
+package openapi
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed ui.html
+var uiPage string
+
+// uiAssets are Swagger UI files, served by app itself so docs work offline and under strict CSP.
+//
+//go:embed swaggerui/swagger-ui-bundle.js swaggerui/swagger-ui.css swaggerui/init.js
+var uiAssets embed.FS
+
+var uiTemplate = template.Must(template.New("ui").Parse(uiPage))
+
+// UIHandler serves Swagger UI page rendering document from specURL. Page loads its scripts and styles
+// from assetsURL, see UIAssetsHandler.
+func UIHandler(title, specURL, assetsURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", "text/html; charset=utf-8")
+
+		err := uiTemplate.Execute(c.Writer, struct {
+			Title     string
+			SpecURL   string
+			AssetsURL string
+		}{
+			Title:     title,
+			SpecURL:   specURL,
+			AssetsURL: assetsURL,
+		})
+		if err != nil {
+			c.Error(err)
+		}
+	}
+}
+
+// UIAssetsHandler serves Swagger UI files. Its route must have wildcard parameter "file",
+// e.g. "/docs/assets/*file".
+func UIAssetsHandler() gin.HandlerFunc {
+	assets, err := fs.Sub(uiAssets, "swaggerui")
+	if err != nil {
+		panic(err)
+	}
+	files := http.FS(assets)
+
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=86400")
+		c.FileFromFS(c.Param("file"), files)
+	}
+}
@@ -0,0 +1,59 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Headers of HTTP publisher requests. Receivers should deduplicate messages by HeaderEventID.
+const (
+	HeaderEventID   = "Event-Id"
+	HeaderEventType = "Event-Type"
+	HeaderEventKey  = "Event-Key"
+)
+
+type httpPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTP returns publisher which posts every message to url. Any 2xx response means message is accepted.
+func NewHTTP(url string) *httpPublisher {
+	return &httpPublisher{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *httpPublisher) Publish(ctx context.Context, msg Message) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(msg.Payload))
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, msg.ID)
+	req.Header.Set(HeaderEventType, msg.Type)
+	req.Header.Set(HeaderEventKey, msg.Key)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (p *httpPublisher) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,31 @@
+package publisher
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/srgklmv/comfortel/pkg/logger"
+)
+
+type logPublisher struct{}
+
+// NewLog returns publisher which writes messages to log. Useful for development.
+func NewLog() *logPublisher {
+	return &logPublisher{}
+}
+
+func (p *logPublisher) Publish(_ context.Context, msg Message) error {
+	logger.Info(
+		"event published",
+		slog.String("id", msg.ID),
+		slog.String("type", msg.Type),
+		slog.String("key", msg.Key),
+		slog.String("payload", string(msg.Payload)),
+	)
+
+	return nil
+}
+
+func (p *logPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,81 @@
+package publisher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/srgklmv/comfortel/pkg/publisher/natstest"
+)
+
+func newNATS(t *testing.T) (*natstest.Server, *natsPublisher) {
+	t.Helper()
+
+	server, err := natstest.NewServer()
+	if err != nil {
+		t.Fatalf("natstest.NewServer: %v", err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+
+	p, err := NewNATS(server.URL(), "comfortel.")
+	if err != nil {
+		t.Fatalf("NewNATS: %v", err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+
+	return server, p
+}
+
+func TestNATSPublishWaitsForStreamAck(t *testing.T) {
+	server, p := newNATS(t)
+	ctx := context.Background()
+
+	if err := p.Check(ctx); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	msg := Message{ID: "event-1", Type: "user.created", Key: "user-1", Payload: []byte(`{"id":"event-1"}`)}
+	if err := p.Publish(ctx, msg); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	// Stream drops message with known id, so publishing again after lost ack delivers it once.
+	if err := p.Publish(ctx, msg); err != nil {
+		t.Fatalf("Publish again: %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("stored %d messages, want 1", len(messages))
+	}
+	got := messages[0]
+	if got.Subject != "comfortel.user.created" {
+		t.Errorf("subject %q, want %q", got.Subject, "comfortel.user.created")
+	}
+	if id := got.Header.Get(jetstream.MsgIDHeader); id != msg.ID {
+		t.Errorf("%s header %q, want %q", jetstream.MsgIDHeader, id, msg.ID)
+	}
+	if string(got.Data) != string(msg.Payload) {
+		t.Errorf("payload %q, want %q", got.Data, msg.Payload)
+	}
+}
+
+func TestNATSPublishFailsWhenStreamRejects(t *testing.T) {
+	server, p := newNATS(t)
+	ctx := context.Background()
+
+	server.FailNext(1)
+	msg := Message{ID: "event-1", Type: "user.created", Payload: []byte(`{}`)}
+	if err := p.Publish(ctx, msg); err == nil {
+		t.Fatal("Publish succeeded, want error of rejected publish")
+	}
+	if n := len(server.Messages()); n != 0 {
+		t.Fatalf("stored %d messages, want 0", n)
+	}
+
+	if err := p.Publish(ctx, msg); err != nil {
+		t.Fatalf("Publish after rejection: %v", err)
+	}
+	if n := len(server.Messages()); n != 1 {
+		t.Fatalf("stored %d messages, want 1", n)
+	}
+}
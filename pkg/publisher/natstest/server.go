@@ -0,0 +1,269 @@
+// Package natstest provides minimal NATS server stand-in which records published messages.
+// It answers publishes with reply subject like JetStream stream, so publishers waiting for acknowledgement
+// can be checked without running real broker.
+package natstest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Stream is name of stream in acknowledgements.
+const Stream = "natstest"
+
+const headerMsgID = "Nats-Msg-Id"
+
+// Msg is message received by Server.
+type Msg struct {
+	Subject string
+	Reply   string
+	Header  textproto.MIMEHeader
+	Data    []byte
+}
+
+type Server struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []Msg
+	// ids are message ids of stored messages, message with known id is acknowledged as duplicate.
+	ids map[string]struct{}
+	// failNext makes server reject given number of next publishes.
+	failNext int
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewServer starts server on random local port.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("net.Listen: %w", err)
+	}
+
+	s := &Server{
+		listener: listener,
+		ids:      make(map[string]struct{}),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.accept()
+
+	return s, nil
+}
+
+// URL returns address to pass to publisher.
+func (s *Server) URL() string {
+	return "nats://" + s.listener.Addr().String()
+}
+
+// Messages returns copy of stored messages in order of arrival, duplicates are not stored.
+func (s *Server) Messages() []Msg {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Msg(nil), s.messages...)
+}
+
+// FailNext makes server reject next n publishes with error.
+func (s *Server) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failNext = n
+}
+
+func (s *Server) Close() error {
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serve(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			_ = conn.Close()
+		}()
+	}
+}
+
+// serve handles connection of one client. Acknowledgements are delivered to subscriptions of
+// publishing connection, which is where clients wait for them.
+func (s *Server) serve(conn net.Conn) {
+	if _, err := io.WriteString(conn, `INFO {"server_id":"natstest","version":"2.10.0","headers":true,"jetstream":true,"max_payload":1048576}`+"\r\n"); err != nil {
+		return
+	}
+
+	subs := make(map[string]string)
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		op, args, _ := strings.Cut(line, " ")
+
+		var reply string
+		switch strings.ToUpper(op) {
+		case "CONNECT":
+		case "PING":
+			reply = "PONG\r\n"
+		case "PONG":
+		case "SUB":
+			fields := strings.Fields(args)
+			if len(fields) < 2 {
+				reply = "-ERR 'Invalid Subscription'\r\n"
+				break
+			}
+			subs[fields[len(fields)-1]] = fields[0]
+		case "UNSUB":
+			fields := strings.Fields(args)
+			if len(fields) > 0 {
+				delete(subs, fields[0])
+			}
+		case "PUB", "HPUB":
+			msg, err := readMsg(reader, strings.ToUpper(op) == "HPUB", strings.Fields(args))
+			if err != nil {
+				reply = fmt.Sprintf("-ERR '%s'\r\n", err)
+				break
+			}
+			ack := s.store(msg)
+			if msg.Reply == "" {
+				break
+			}
+			for sid, subject := range subs {
+				if match(subject, msg.Reply) {
+					reply += fmt.Sprintf("MSG %s %s %d\r\n%s\r\n", msg.Reply, sid, len(ack), ack)
+				}
+			}
+		default:
+			reply = "-ERR 'Unknown Protocol Operation'\r\n"
+		}
+
+		if reply != "" {
+			if _, err = io.WriteString(conn, reply); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// store saves message unless it is rejected or duplicate and returns JetStream acknowledgement.
+func (s *Server) store(msg Msg) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ack any
+	id := msg.Header.Get(headerMsgID)
+	_, duplicate := s.ids[id]
+	switch {
+	case s.failNext > 0:
+		s.failNext--
+		ack = map[string]any{"error": map[string]any{"code": 503, "description": "publish rejected"}}
+	case id != "" && duplicate:
+		ack = map[string]any{"stream": Stream, "seq": len(s.messages), "duplicate": true}
+	default:
+		s.messages = append(s.messages, msg)
+		if id != "" {
+			s.ids[id] = struct{}{}
+		}
+		ack = map[string]any{"stream": Stream, "seq": len(s.messages)}
+	}
+
+	b, _ := json.Marshal(ack)
+	return b
+}
+
+// match reports whether subject matches subscription subject with * and > wildcards.
+func match(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+	for i, token := range patternTokens {
+		if token == ">" {
+			return len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) || (token != "*" && token != subjectTokens[i]) {
+			return false
+		}
+	}
+
+	return len(patternTokens) == len(subjectTokens)
+}
+
+// readMsg reads payload of PUB or HPUB.
+func readMsg(reader *bufio.Reader, withHeaders bool, args []string) (Msg, error) {
+	minArgs := 2
+	if withHeaders {
+		minArgs = 3
+	}
+	if len(args) < minArgs || len(args) > minArgs+1 {
+		return Msg{}, fmt.Errorf("invalid arguments")
+	}
+
+	total, err := strconv.Atoi(args[len(args)-1])
+	if err != nil || total < 0 {
+		return Msg{}, fmt.Errorf("invalid size")
+	}
+	headerSize := 0
+	if withHeaders {
+		headerSize, err = strconv.Atoi(args[len(args)-2])
+		if err != nil || headerSize < 0 || headerSize > total {
+			return Msg{}, fmt.Errorf("invalid header size")
+		}
+	}
+
+	buf := make([]byte, total+2)
+	if _, err = io.ReadFull(reader, buf); err != nil {
+		return Msg{}, err
+	}
+
+	msg := Msg{Subject: args[0], Data: buf[headerSize:total]}
+	if len(args) > minArgs {
+		msg.Reply = args[1]
+	}
+	if withHeaders {
+		headerReader := textproto.NewReader(bufio.NewReader(strings.NewReader(string(buf[:headerSize]))))
+		if _, err = headerReader.ReadLine(); err != nil {
+			return Msg{}, fmt.Errorf("invalid headers")
+		}
+		msg.Header, err = headerReader.ReadMIMEHeader()
+		if err != nil && err != io.EOF {
+			return Msg{}, fmt.Errorf("invalid headers")
+		}
+	}
+
+	return msg, nil
+}
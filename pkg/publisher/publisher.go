@@ -0,0 +1,49 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+)
+
+// Message is published event. Key groups related messages, e.g. events of single entity.
+type Message struct {
+	ID      string
+	Type    string
+	Key     string
+	Payload []byte
+}
+
+// Publisher delivers messages to external system. Publish returns nil only when message is accepted,
+// so callers can retry failed messages for at-least-once delivery.
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// Kinds of publishers supported by New.
+const (
+	KindLog  = "log"
+	KindHTTP = "http"
+	KindNATS = "nats"
+)
+
+// New creates publisher of given kind. URL is endpoint for HTTP publisher or server for NATS one,
+// subjectPrefix is prepended to message type to get NATS subject.
+func New(kind, url, subjectPrefix string) (Publisher, error) {
+	switch kind {
+	case KindLog, "":
+		return NewLog(), nil
+	case KindHTTP:
+		if url == "" {
+			return nil, fmt.Errorf("url is required for %s publisher", kind)
+		}
+		return NewHTTP(url), nil
+	case KindNATS:
+		if url == "" {
+			return nil, fmt.Errorf("url is required for %s publisher", kind)
+		}
+		return NewNATS(url, subjectPrefix)
+	}
+
+	return nil, fmt.Errorf("unknown publisher %q", kind)
+}
@@ -0,0 +1,148 @@
+// Package otlptest provides minimal OTLP/HTTP collector stand-in which records received spans.
+// It lets tracing be checked without running real collector.
+package otlptest
+
+import (
+	"compress/gzip"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Span is span received by Server. Attributes are converted to strings.
+type Span struct {
+	Service      string
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	Attributes   map[string]string
+}
+
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+
+	mu    sync.Mutex
+	spans []Span
+}
+
+// NewServer starts server on random local port.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("net.Listen: %w", err)
+	}
+
+	s := &Server{listener: listener}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/traces", s.handle)
+	s.server = &http.Server{Handler: mux}
+	go func() {
+		_ = s.server.Serve(listener)
+	}()
+
+	return s, nil
+}
+
+// URL returns endpoint to pass to tracing.New.
+func (s *Server) URL() string {
+	return "http://" + s.listener.Addr().String()
+}
+
+// Spans returns copy of received spans in order of arrival.
+func (s *Server) Spans() []Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	spans := make([]Span, len(s.spans))
+	copy(spans, s.spans)
+
+	return spans
+}
+
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req collectortrace.ExportTraceServiceRequest
+	if err = proto.Unmarshal(data, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var spans []Span
+	for _, rs := range req.GetResourceSpans() {
+		service := attributes(rs.GetResource().GetAttributes())["service.name"]
+		for _, ss := range rs.GetScopeSpans() {
+			for _, span := range ss.GetSpans() {
+				spans = append(spans, Span{
+					Service:      service,
+					TraceID:      hex.EncodeToString(span.GetTraceId()),
+					SpanID:       hex.EncodeToString(span.GetSpanId()),
+					ParentSpanID: hex.EncodeToString(span.GetParentSpanId()),
+					Name:         span.GetName(),
+					Attributes:   attributes(span.GetAttributes()),
+				})
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.spans = append(s.spans, spans...)
+	s.mu.Unlock()
+
+	resp, err := proto.Marshal(&collectortrace.ExportTraceServiceResponse{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(resp)
+}
+
+func attributes(kvs []*commonpb.KeyValue) map[string]string {
+	attrs := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		switch v := kv.GetValue().GetValue().(type) {
+		case *commonpb.AnyValue_StringValue:
+			attrs[kv.GetKey()] = v.StringValue
+		case *commonpb.AnyValue_IntValue:
+			attrs[kv.GetKey()] = strconv.FormatInt(v.IntValue, 10)
+		case *commonpb.AnyValue_BoolValue:
+			attrs[kv.GetKey()] = strconv.FormatBool(v.BoolValue)
+		case *commonpb.AnyValue_DoubleValue:
+			attrs[kv.GetKey()] = strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+		default:
+			attrs[kv.GetKey()] = kv.GetValue().String()
+		}
+	}
+
+	return attrs
+}
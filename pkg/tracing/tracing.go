@@ -0,0 +1,63 @@
+// Package tracing sets up OpenTelemetry tracing of app. Packages create spans with global tracer provider,
+// which does nothing until New is called.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/srgklmv/comfortel/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Exporters.
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+)
+
+// New sets global tracer provider, which exports spans of exporter kind: "none" or "otlp", which sends
+// spans over OTLP/HTTP to endpoint, e.g. "http://localhost:4318". Ratio of traces started by app is sampled,
+// traces of callers are sampled as caller decided. W3C trace context of incoming requests is propagated anyway.
+// Returned shutdown flushes spans.
+func New(ctx context.Context, exporter, endpoint, serviceName string, sampleRatio float64) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Error("tracing error", slog.String("error", err.Error()))
+	}))
+
+	var spanExporter sdktrace.SpanExporter
+	switch exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"))
+		if err != nil {
+			return nil, fmt.Errorf("otlptracehttp.New: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown exporter %q", exporter)
+	}
+
+	provider := NewProvider(sdktrace.NewBatchSpanProcessor(spanExporter), serviceName, sampleRatio)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// NewProvider returns tracer provider passing spans to processor, e.g. sdktrace.NewSimpleSpanProcessor
+// of tracetest.NewInMemoryExporter to check spans in place.
+func NewProvider(processor sdktrace.SpanProcessor, serviceName string, sampleRatio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+}
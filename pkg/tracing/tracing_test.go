@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/srgklmv/comfortel/pkg/tracing/otlptest"
+	"go.opentelemetry.io/otel"
+)
+
+func TestNewExportsOverOTLP(t *testing.T) {
+	collector, err := otlptest.NewServer()
+	if err != nil {
+		t.Fatalf("otlptest.NewServer: %v", err)
+	}
+	t.Cleanup(func() { _ = collector.Close() })
+
+	ctx := context.Background()
+	shutdown, err := New(ctx, ExporterOTLP, collector.URL(), "comfortel-test", 1)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	ctx, parent := otel.Tracer("test").Start(ctx, "parent")
+	_, child := otel.Tracer("test").Start(ctx, "child")
+	child.End()
+	parent.End()
+
+	// Shutdown flushes batch of spans.
+	if err = shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	spans := collector.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("collector got %d spans, want 2: %+v", len(spans), spans)
+	}
+	byName := map[string]otlptest.Span{}
+	for _, s := range spans {
+		if s.Service != "comfortel-test" {
+			t.Errorf("span %s: service %q, want %q", s.Name, s.Service, "comfortel-test")
+		}
+		byName[s.Name] = s
+	}
+	if c, p := byName["child"], byName["parent"]; c.ParentSpanID != p.SpanID || c.TraceID != p.TraceID {
+		t.Errorf("child %+v is not in trace of parent %+v", c, p)
+	}
+}